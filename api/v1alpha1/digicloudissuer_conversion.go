@@ -0,0 +1,292 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+)
+
+// ConvertTo converts this DigicloudIssuer to the hub version
+func (src *DigicloudIssuer) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.DigicloudIssuer)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 DigicloudIssuer but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = convertProvisionerTo(&src.Spec.Provisioner)
+	dst.Status = v1beta1.DigicloudIssuerStatus{
+		Conditions:           src.Status.Conditions,
+		ObservedGeneration:   src.Status.ObservedGeneration,
+		ChallengeDelegations: convertChallengeDelegationsTo(src.Status.ChallengeDelegations),
+		DigicloudNamespace:   src.Status.DigicloudNamespace,
+		Zones:                convertZonesTo(src.Status.Zones),
+		LastAPICheckTime:     src.Status.LastAPICheckTime,
+		ACMEAccount:          (*v1beta1.ACMEAccountStatus)(src.Status.ACMEAccount),
+		InFlightChallenges:   src.Status.InFlightChallenges,
+		ChallengeRecords:     convertChallengeRecordsTo(src.Status.ChallengeRecords),
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version to this DigicloudIssuer
+func (dst *DigicloudIssuer) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.DigicloudIssuer)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 DigicloudIssuer but got %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec.Provisioner = convertProvisionerFrom(&src.Spec)
+	dst.Status = DigicloudIssuerStatus{
+		Conditions:           src.Status.Conditions,
+		ObservedGeneration:   src.Status.ObservedGeneration,
+		ChallengeDelegations: convertChallengeDelegationsFrom(src.Status.ChallengeDelegations),
+		DigicloudNamespace:   src.Status.DigicloudNamespace,
+		Zones:                convertZonesFrom(src.Status.Zones),
+		LastAPICheckTime:     src.Status.LastAPICheckTime,
+		ACMEAccount:          (*ACMEAccountStatus)(src.Status.ACMEAccount),
+		InFlightChallenges:   src.Status.InFlightChallenges,
+		ChallengeRecords:     convertChallengeRecordsFrom(src.Status.ChallengeRecords),
+	}
+	return nil
+}
+
+// ConvertTo converts this DigicloudClusterIssuer to the hub version
+func (src *DigicloudClusterIssuer) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.DigicloudClusterIssuer)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 DigicloudClusterIssuer but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = v1beta1.DigicloudClusterIssuerSpec{
+		DigicloudIssuerSpec: convertProvisionerTo(&src.Spec.Provisioner),
+		NamespaceSelector:   src.Spec.NamespaceSelector,
+	}
+	dst.Status = v1beta1.DigicloudIssuerStatus{
+		Conditions:           src.Status.Conditions,
+		ObservedGeneration:   src.Status.ObservedGeneration,
+		ChallengeDelegations: convertChallengeDelegationsTo(src.Status.ChallengeDelegations),
+		DigicloudNamespace:   src.Status.DigicloudNamespace,
+		Zones:                convertZonesTo(src.Status.Zones),
+		LastAPICheckTime:     src.Status.LastAPICheckTime,
+		ACMEAccount:          (*v1beta1.ACMEAccountStatus)(src.Status.ACMEAccount),
+		InFlightChallenges:   src.Status.InFlightChallenges,
+		ChallengeRecords:     convertChallengeRecordsTo(src.Status.ChallengeRecords),
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version to this DigicloudClusterIssuer
+func (dst *DigicloudClusterIssuer) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.DigicloudClusterIssuer)
+	if !ok {
+		return fmt.Errorf("expected a v1beta1 DigicloudClusterIssuer but got %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = DigicloudClusterIssuerSpec{
+		Provisioner:       convertProvisionerFrom(&src.Spec.DigicloudIssuerSpec),
+		NamespaceSelector: src.Spec.NamespaceSelector,
+	}
+	dst.Status = DigicloudClusterIssuerStatus{
+		Conditions:           src.Status.Conditions,
+		ObservedGeneration:   src.Status.ObservedGeneration,
+		ChallengeDelegations: convertChallengeDelegationsFrom(src.Status.ChallengeDelegations),
+		DigicloudNamespace:   src.Status.DigicloudNamespace,
+		Zones:                convertZonesFrom(src.Status.Zones),
+		LastAPICheckTime:     src.Status.LastAPICheckTime,
+		ACMEAccount:          (*ACMEAccountStatus)(src.Status.ACMEAccount),
+		InFlightChallenges:   src.Status.InFlightChallenges,
+		ChallengeRecords:     convertChallengeRecordsFrom(src.Status.ChallengeRecords),
+	}
+	return nil
+}
+
+// convertProvisionerTo converts a provisioner to a v1beta1 spec. The provisioner's
+// own account fields move into digicloud and the propagation fields into
+// propagation; either is left nil if none of its fields are set.
+func convertProvisionerTo(src *DigicloudIssuerProvisioner) v1beta1.DigicloudIssuerSpec {
+	dst := v1beta1.DigicloudIssuerSpec{
+		CNAMEStrategy:  v1beta1.CNAMEStrategy(src.CNAMEStrategy),
+		AllowedDomains: src.AllowedDomains,
+	}
+
+	account := v1beta1.DigicloudAccount{
+		APIBaseURL:        src.APIBaseURL,
+		APITokenSecretRef: v1beta1.SecretKeySelector(src.APITokenSecretRef),
+		Namespace:         src.Namespace,
+		TTL:               src.TTL,
+	}
+	if account != (v1beta1.DigicloudAccount{}) {
+		dst.Digicloud = &account
+	}
+
+	if src.PropagationTimeout != nil || src.PollingInterval != nil {
+		dst.Propagation = &v1beta1.PropagationCheck{
+			Timeout:         src.PropagationTimeout,
+			PollingInterval: src.PollingInterval,
+		}
+	}
+
+	if src.ACME != nil {
+		dst.ACME = &v1beta1.ACMEIssuer{
+			Server:                    src.ACME.Server,
+			Email:                     src.ACME.Email,
+			TermsOfServiceAgreed:      src.ACME.TermsOfServiceAgreed,
+			PrivateKeySecretRef:       (*v1beta1.SecretKeySelector)(src.ACME.PrivateKeySecretRef),
+			DeactivateAccountOnDelete: src.ACME.DeactivateAccountOnDelete,
+		}
+	}
+
+	if src.Solvers != nil {
+		dst.Solvers = make([]v1beta1.DigicloudSolver, len(src.Solvers))
+		for i, solver := range src.Solvers {
+			dst.Solvers[i] = v1beta1.DigicloudSolver{
+				Selector: (*v1beta1.SolverSelector)(solver.Selector),
+				Digicloud: v1beta1.DigicloudAccount{
+					APIBaseURL:        solver.APIBaseURL,
+					APITokenSecretRef: v1beta1.SecretKeySelector(solver.APITokenSecretRef),
+					Namespace:         solver.Namespace,
+					TTL:               solver.TTL,
+				},
+			}
+		}
+	}
+
+	return dst
+}
+
+// convertProvisionerFrom converts a v1beta1 spec to a provisioner, flattening
+// digicloud and propagation into it. Every v1beta1 field has a v1alpha1 equivalent,
+// but an empty digicloud or propagation struct cannot be told apart from an absent
+// one and converts back as nil. Neither is stored: an empty account fails
+// validation, and propagation is defaulted.
+func convertProvisionerFrom(src *v1beta1.DigicloudIssuerSpec) DigicloudIssuerProvisioner {
+	dst := DigicloudIssuerProvisioner{
+		CNAMEStrategy:  CNAMEStrategy(src.CNAMEStrategy),
+		AllowedDomains: src.AllowedDomains,
+	}
+
+	if src.Digicloud != nil {
+		dst.APIBaseURL = src.Digicloud.APIBaseURL
+		dst.APITokenSecretRef = SecretKeySelector(src.Digicloud.APITokenSecretRef)
+		dst.Namespace = src.Digicloud.Namespace
+		dst.TTL = src.Digicloud.TTL
+	}
+
+	if src.Propagation != nil {
+		dst.PropagationTimeout = src.Propagation.Timeout
+		dst.PollingInterval = src.Propagation.PollingInterval
+	}
+
+	if src.ACME != nil {
+		dst.ACME = &DigicloudIssuerACME{
+			Server:                    src.ACME.Server,
+			Email:                     src.ACME.Email,
+			TermsOfServiceAgreed:      src.ACME.TermsOfServiceAgreed,
+			PrivateKeySecretRef:       (*SecretKeySelector)(src.ACME.PrivateKeySecretRef),
+			DeactivateAccountOnDelete: src.ACME.DeactivateAccountOnDelete,
+		}
+	}
+
+	if src.Solvers != nil {
+		dst.Solvers = make([]DigicloudSolver, len(src.Solvers))
+		for i, solver := range src.Solvers {
+			dst.Solvers[i] = DigicloudSolver{
+				Selector:          (*SolverSelector)(solver.Selector),
+				APIBaseURL:        solver.Digicloud.APIBaseURL,
+				APITokenSecretRef: SecretKeySelector(solver.Digicloud.APITokenSecretRef),
+				Namespace:         solver.Digicloud.Namespace,
+				TTL:               solver.Digicloud.TTL,
+			}
+		}
+	}
+
+	return dst
+}
+
+func convertChallengeDelegationsTo(src []ChallengeDelegation) []v1beta1.ChallengeDelegation {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1beta1.ChallengeDelegation, len(src))
+	for i := range src {
+		dst[i] = v1beta1.ChallengeDelegation(src[i])
+	}
+	return dst
+}
+
+func convertChallengeDelegationsFrom(src []v1beta1.ChallengeDelegation) []ChallengeDelegation {
+	if src == nil {
+		return nil
+	}
+	dst := make([]ChallengeDelegation, len(src))
+	for i := range src {
+		dst[i] = ChallengeDelegation(src[i])
+	}
+	return dst
+}
+
+func convertZonesTo(src []ZoneStatus) []v1beta1.ZoneStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1beta1.ZoneStatus, len(src))
+	for i := range src {
+		dst[i] = v1beta1.ZoneStatus(src[i])
+	}
+	return dst
+}
+
+func convertZonesFrom(src []v1beta1.ZoneStatus) []ZoneStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]ZoneStatus, len(src))
+	for i := range src {
+		dst[i] = ZoneStatus(src[i])
+	}
+	return dst
+}
+
+func convertChallengeRecordsTo(src []ChallengeRecord) []v1beta1.ChallengeRecord {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1beta1.ChallengeRecord, len(src))
+	for i := range src {
+		dst[i] = v1beta1.ChallengeRecord(*src[i].DeepCopy())
+	}
+	return dst
+}
+
+func convertChallengeRecordsFrom(src []v1beta1.ChallengeRecord) []ChallengeRecord {
+	if src == nil {
+		return nil
+	}
+	dst := make([]ChallengeRecord, len(src))
+	for i := range src {
+		dst[i] = ChallengeRecord(*src[i].DeepCopy())
+	}
+	return dst
+}
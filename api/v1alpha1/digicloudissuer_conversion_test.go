@@ -0,0 +1,194 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/randfill"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+)
+
+const fuzzIterations = 1000
+
+// newFiller returns a filler for issuer objects
+func newFiller(f *randfill.Filler) *randfill.Filler {
+	return f.NilChance(0.3).NumElements(0, 3).Funcs(
+		func(tm *metav1.TypeMeta, _ randfill.Continue) {
+			*tm = metav1.TypeMeta{}
+		},
+	)
+}
+
+func TestDigicloudIssuer_FuzzyConversion(t *testing.T) {
+	filler := newFiller(randfill.New())
+
+	for i := 0; i < fuzzIterations; i++ {
+		spoke := &DigicloudIssuer{}
+		filler.Fill(spoke)
+		assertSpokeRoundTrip(t, spoke, &v1beta1.DigicloudIssuer{}, &DigicloudIssuer{})
+
+		hub := &v1beta1.DigicloudIssuer{}
+		filler.Fill(hub)
+		assertHubRoundTrip(t, hub, &DigicloudIssuer{}, &v1beta1.DigicloudIssuer{})
+	}
+}
+
+func TestDigicloudClusterIssuer_FuzzyConversion(t *testing.T) {
+	filler := newFiller(randfill.New())
+
+	for i := 0; i < fuzzIterations; i++ {
+		spoke := &DigicloudClusterIssuer{}
+		filler.Fill(spoke)
+		assertSpokeRoundTrip(t, spoke, &v1beta1.DigicloudClusterIssuer{}, &DigicloudClusterIssuer{})
+
+		hub := &v1beta1.DigicloudClusterIssuer{}
+		filler.Fill(hub)
+		assertHubRoundTrip(t, hub, &DigicloudClusterIssuer{}, &v1beta1.DigicloudClusterIssuer{})
+	}
+}
+
+func FuzzDigicloudIssuerConversion(f *testing.F) {
+	f.Add([]byte("digicloud"))
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		spoke := &DigicloudIssuer{}
+		newFiller(randfill.NewFromGoFuzz(data)).Fill(spoke)
+		assertSpokeRoundTrip(t, spoke, &v1beta1.DigicloudIssuer{}, &DigicloudIssuer{})
+	})
+}
+
+type convertible interface {
+	ConvertTo(dst conversion.Hub) error
+	ConvertFrom(src conversion.Hub) error
+}
+
+func assertSpokeRoundTrip(t *testing.T, spoke convertible, hub conversion.Hub, result convertible) {
+	t.Helper()
+
+	original := spoke.(runtime.Object).DeepCopyObject()
+	require.NoError(t, spoke.ConvertTo(hub))
+	require.NoError(t, result.ConvertFrom(hub))
+	if !apiequality.Semantic.DeepEqual(original, result) {
+		t.Fatalf("v1alpha1 -> v1beta1 -> v1alpha1 is not lossless:\n%s", diff.ObjectReflectDiff(original, result))
+	}
+}
+
+// assertHubRoundTrip asserts that hub survives conversion through v1alpha1, up to
+// the normalization of empty structs by normalizedHub
+func assertHubRoundTrip(t *testing.T, hub conversion.Hub, spoke convertible, result conversion.Hub) {
+	t.Helper()
+
+	expected := normalizedHub(hub)
+	require.NoError(t, spoke.ConvertFrom(hub))
+	require.NoError(t, spoke.ConvertTo(result))
+	if !apiequality.Semantic.DeepEqual(expected, result) {
+		t.Fatalf("v1beta1 -> v1alpha1 -> v1beta1 is not lossless:\n%s", diff.ObjectReflectDiff(expected, result))
+	}
+}
+
+// normalizedHub returns a copy of hub as conversion through v1alpha1 leaves it: an
+// empty digicloud or propagation struct becomes nil, see convertProvisionerFrom
+func normalizedHub(hub conversion.Hub) runtime.Object {
+	normalized := hub.DeepCopyObject()
+	var spec *v1beta1.DigicloudIssuerSpec
+	switch normalized := normalized.(type) {
+	case *v1beta1.DigicloudIssuer:
+		spec = &normalized.Spec
+	case *v1beta1.DigicloudClusterIssuer:
+		spec = &normalized.Spec.DigicloudIssuerSpec
+	}
+	if spec.Digicloud != nil && *spec.Digicloud == (v1beta1.DigicloudAccount{}) {
+		spec.Digicloud = nil
+	}
+	if spec.Propagation != nil && *spec.Propagation == (v1beta1.PropagationCheck{}) {
+		spec.Propagation = nil
+	}
+	return normalized
+}
+
+func TestDigicloudIssuer_ConvertFrom_EmptyStructs(t *testing.T) {
+	hub := &v1beta1.DigicloudIssuer{
+		Spec: v1beta1.DigicloudIssuerSpec{
+			Digicloud:   &v1beta1.DigicloudAccount{},
+			Propagation: &v1beta1.PropagationCheck{},
+		},
+	}
+
+	spoke := &DigicloudIssuer{}
+	require.NoError(t, spoke.ConvertFrom(hub))
+	result := &v1beta1.DigicloudIssuer{}
+	require.NoError(t, spoke.ConvertTo(result))
+
+	assert.Nil(t, result.Spec.Digicloud)
+	assert.Nil(t, result.Spec.Propagation)
+}
+
+func TestDigicloudIssuer_ConvertTo(t *testing.T) {
+	ttl := 120
+	issuer := &DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "digicloud",
+			Namespace: "default",
+		},
+		Spec: DigicloudIssuerSpec{
+			Provisioner: DigicloudIssuerProvisioner{
+				APIBaseURL:         "https://api.digicloud.ir",
+				APITokenSecretRef:  SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				Namespace:          "media",
+				TTL:                &ttl,
+				PropagationTimeout: &metav1.Duration{Duration: 5 * time.Minute},
+				CNAMEStrategy:      FollowStrategy,
+				Solvers: []DigicloudSolver{{
+					Selector:          &SolverSelector{DNSZones: []string{"example.com"}},
+					APITokenSecretRef: SecretKeySelector{Name: "example-credentials", Key: "token"},
+					Namespace:         "example",
+				}},
+			},
+		},
+	}
+
+	hub := &v1beta1.DigicloudIssuer{}
+	require.NoError(t, issuer.ConvertTo(hub))
+
+	assert.Equal(t, &v1beta1.DigicloudAccount{
+		APIBaseURL:        "https://api.digicloud.ir",
+		APITokenSecretRef: v1beta1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+		Namespace:         "media",
+		TTL:               &ttl,
+	}, hub.Spec.Digicloud)
+	assert.Equal(t, &v1beta1.PropagationCheck{Timeout: &metav1.Duration{Duration: 5 * time.Minute}}, hub.Spec.Propagation)
+	assert.Equal(t, v1beta1.FollowStrategy, hub.Spec.CNAMEStrategy)
+	assert.Equal(t, []v1beta1.DigicloudSolver{{
+		Selector: &v1beta1.SolverSelector{DNSZones: []string{"example.com"}},
+		Digicloud: v1beta1.DigicloudAccount{
+			APITokenSecretRef: v1beta1.SecretKeySelector{Name: "example-credentials", Key: "token"},
+			Namespace:         "example",
+		},
+	}}, hub.Spec.Solvers)
+	assert.Nil(t, hub.Spec.ACME)
+}
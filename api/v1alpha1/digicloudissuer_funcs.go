@@ -0,0 +1,171 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	issuerlibv1alpha1 "github.com/cert-manager/issuer-lib/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetProvisioner returns the provisioner configuration of the issuer
+func (i *DigicloudIssuer) GetProvisioner() *DigicloudIssuerProvisioner {
+	return &i.Spec.Provisioner
+}
+
+// GetConditions returns the status conditions of the issuer
+func (i *DigicloudIssuer) GetConditions() []cmapi.IssuerCondition {
+	return i.Status.Conditions
+}
+
+// SetConditions replaces the status conditions of the issuer
+func (i *DigicloudIssuer) SetConditions(conditions []cmapi.IssuerCondition) {
+	i.Status.Conditions = conditions
+}
+
+// GetObservedGeneration returns the generation the issuer status was last computed for
+func (i *DigicloudIssuer) GetObservedGeneration() int64 {
+	return i.Status.ObservedGeneration
+}
+
+// SetObservedGeneration records the generation the issuer status was computed for
+func (i *DigicloudIssuer) SetObservedGeneration(generation int64) {
+	i.Status.ObservedGeneration = generation
+}
+
+// GetChallengeDelegations returns the CNAME-delegated challenges in the issuer status
+func (i *DigicloudIssuer) GetChallengeDelegations() []ChallengeDelegation {
+	return i.Status.ChallengeDelegations
+}
+
+// SetChallengeDelegations replaces the CNAME-delegated challenges in the issuer status
+func (i *DigicloudIssuer) SetChallengeDelegations(delegations []ChallengeDelegation) {
+	i.Status.ChallengeDelegations = delegations
+}
+
+// GetProvisioner returns the provisioner configuration of the cluster issuer
+func (i *DigicloudClusterIssuer) GetProvisioner() *DigicloudIssuerProvisioner {
+	return &i.Spec.Provisioner
+}
+
+// GetConditions returns the status conditions of the cluster issuer
+func (i *DigicloudClusterIssuer) GetConditions() []cmapi.IssuerCondition {
+	return i.Status.Conditions
+}
+
+// SetConditions replaces the status conditions of the cluster issuer
+func (i *DigicloudClusterIssuer) SetConditions(conditions []cmapi.IssuerCondition) {
+	i.Status.Conditions = conditions
+}
+
+// GetObservedGeneration returns the generation the cluster issuer status was last computed for
+func (i *DigicloudClusterIssuer) GetObservedGeneration() int64 {
+	return i.Status.ObservedGeneration
+}
+
+// SetObservedGeneration records the generation the cluster issuer status was computed for
+func (i *DigicloudClusterIssuer) SetObservedGeneration(generation int64) {
+	i.Status.ObservedGeneration = generation
+}
+
+// GetChallengeDelegations returns the CNAME-delegated challenges in the cluster issuer status
+func (i *DigicloudClusterIssuer) GetChallengeDelegations() []ChallengeDelegation {
+	return i.Status.ChallengeDelegations
+}
+
+// SetChallengeDelegations replaces the CNAME-delegated challenges in the cluster issuer status
+func (i *DigicloudClusterIssuer) SetChallengeDelegations(delegations []ChallengeDelegation) {
+	i.Status.ChallengeDelegations = delegations
+}
+
+// SetAPICheck records the result of the latest successful Digicloud API health check
+// of the issuer
+func (i *DigicloudIssuer) SetAPICheck(namespace string, zones []ZoneStatus, checked metav1.Time) {
+	i.Status.DigicloudNamespace = namespace
+	i.Status.Zones = zones
+	i.Status.LastAPICheckTime = &checked
+}
+
+// SetACMEAccount records the ACME account of the issuer
+func (i *DigicloudIssuer) SetACMEAccount(account *ACMEAccountStatus) {
+	i.Status.ACMEAccount = account
+}
+
+// SetInFlightChallenges records the number of challenges the issuer has presented
+func (i *DigicloudIssuer) SetInFlightChallenges(count int32) {
+	i.Status.InFlightChallenges = count
+}
+
+// SetAPICheck records the result of the latest successful Digicloud API health check
+// of the cluster issuer
+func (i *DigicloudClusterIssuer) SetAPICheck(namespace string, zones []ZoneStatus, checked metav1.Time) {
+	i.Status.DigicloudNamespace = namespace
+	i.Status.Zones = zones
+	i.Status.LastAPICheckTime = &checked
+}
+
+// SetACMEAccount records the ACME account of the cluster issuer
+func (i *DigicloudClusterIssuer) SetACMEAccount(account *ACMEAccountStatus) {
+	i.Status.ACMEAccount = account
+}
+
+// SetInFlightChallenges records the number of challenges the cluster issuer has presented
+func (i *DigicloudClusterIssuer) SetInFlightChallenges(count int32) {
+	i.Status.InFlightChallenges = count
+}
+
+// GetChallengeRecords returns the challenge records the issuer has presented
+func (i *DigicloudIssuer) GetChallengeRecords() []ChallengeRecord {
+	return i.Status.ChallengeRecords
+}
+
+// SetChallengeRecords replaces the challenge records the issuer has presented
+func (i *DigicloudIssuer) SetChallengeRecords(records []ChallengeRecord) {
+	i.Status.ChallengeRecords = records
+}
+
+// GetChallengeRecords returns the challenge records the cluster issuer has presented
+func (i *DigicloudClusterIssuer) GetChallengeRecords() []ChallengeRecord {
+	return i.Status.ChallengeRecords
+}
+
+// SetChallengeRecords replaces the challenge records the cluster issuer has presented
+func (i *DigicloudClusterIssuer) SetChallengeRecords(records []ChallengeRecord) {
+	i.Status.ChallengeRecords = records
+}
+
+// GetStatus returns the conditions of the issuer as issuer-lib reads them. The
+// status is a copy, the conditions are owned by the issuer controller.
+func (i *DigicloudIssuer) GetStatus() *issuerlibv1alpha1.IssuerStatus {
+	return &issuerlibv1alpha1.IssuerStatus{Conditions: i.Status.Conditions}
+}
+
+// GetIssuerTypeIdentifier returns the identifier issuer-lib uses for DigicloudIssuers
+func (i *DigicloudIssuer) GetIssuerTypeIdentifier() string {
+	return "digicloudissuers." + GroupVersion.Group
+}
+
+// GetStatus returns the conditions of the cluster issuer as issuer-lib reads them.
+// The status is a copy, the conditions are owned by the issuer controller.
+func (i *DigicloudClusterIssuer) GetStatus() *issuerlibv1alpha1.IssuerStatus {
+	return &issuerlibv1alpha1.IssuerStatus{Conditions: i.Status.Conditions}
+}
+
+// GetIssuerTypeIdentifier returns the identifier issuer-lib uses for DigicloudClusterIssuers
+func (i *DigicloudClusterIssuer) GetIssuerTypeIdentifier() string {
+	return "digicloudclusterissuers." + GroupVersion.Group
+}
@@ -39,8 +39,15 @@ type DigicloudIssuerProvisioner struct {
 	// +kubebuilder:default="https://api.digicloud.ir"
 	APIBaseURL string `json:"apiBaseUrl,omitempty"`
 
-	// APITokenSecretRef is a reference to a secret containing the Digicloud API token
-	APITokenSecretRef SecretKeySelector `json:"apiTokenSecretRef"`
+	// APITokenSecretRef is a reference to a secret containing the Digicloud API token.
+	// It is required unless solvers are configured.
+	// +optional
+	APITokenSecretRef SecretKeySelector `json:"apiTokenSecretRef,omitempty"`
+
+	// Namespace is the Digicloud namespace the zones belong to. Defaults to the
+	// "namespace" key of the API token secret.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 
 	// TTL is the time-to-live for DNS records in seconds
 	// +kubebuilder:default=300
@@ -55,6 +62,115 @@ type DigicloudIssuerProvisioner struct {
 	// PollingInterval is the interval between DNS propagation checks
 	// +kubebuilder:default="10s"
 	PollingInterval *metav1.Duration `json:"pollingInterval,omitempty"`
+
+	// ACME contains the configuration for the ACME server certificates are ordered from
+	// +optional
+	ACME *DigicloudIssuerACME `json:"acme,omitempty"`
+
+	// CNAMEStrategy configures how CNAME records at the challenge FQDN are handled.
+	// Follow writes the TXT record into the Digicloud zone the CNAME chain ends in,
+	// for domains hosted elsewhere that delegate _acme-challenge to a Digicloud zone.
+	// +kubebuilder:default=None
+	// +optional
+	CNAMEStrategy CNAMEStrategy `json:"cnameStrategy,omitempty"`
+
+	// Solvers configure Digicloud credentials and zones for subsets of DNS names.
+	// The most specific solver matching a DNS name is used for its challenge; names
+	// no solver matches use the settings of the provisioner itself.
+	// +optional
+	Solvers []DigicloudSolver `json:"solvers,omitempty"`
+
+	// AllowedDomains restricts the DNS names certificates may be requested for. Each
+	// entry is either a glob, in which "*" matches within a single label and "**"
+	// across labels, or a regular expression enclosed in slashes matching the whole name ("/.*\.example\.com/").
+	// Every requested name must match at least one entry. All names are allowed if empty.
+	// +optional
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+}
+
+// DigicloudSolver configures the Digicloud account used for the DNS names it selects
+type DigicloudSolver struct {
+	// Selector selects the DNS names this solver is used for. A solver without a
+	// selector matches every DNS name with the lowest priority.
+	// +optional
+	Selector *SolverSelector `json:"selector,omitempty"`
+
+	// APIBaseURL is the base URL for the Digicloud API
+	// +kubebuilder:default="https://api.digicloud.ir"
+	APIBaseURL string `json:"apiBaseUrl,omitempty"`
+
+	// APITokenSecretRef is a reference to a secret containing the Digicloud API token
+	APITokenSecretRef SecretKeySelector `json:"apiTokenSecretRef"`
+
+	// Namespace is the Digicloud namespace the zones belong to. Defaults to the
+	// "namespace" key of the API token secret.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// TTL is the time-to-live for DNS records in seconds
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	TTL *int `json:"ttl,omitempty"`
+}
+
+// SolverSelector selects DNS names by name, zone or CertificateRequest labels,
+// following cert-manager's ACME solver selectors. All specified criteria must
+// match. A dnsNames match takes precedence over a dnsZones match, longer zones
+// take precedence over shorter ones and more matchLabels over fewer.
+type SolverSelector struct {
+	// MatchLabels must all be present on the CertificateRequest
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// DNSNames is a list of DNS names, including wildcards, the solver is used for
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// DNSZones is a list of zones whose names and subdomains the solver is used for
+	// +optional
+	DNSZones []string `json:"dnsZones,omitempty"`
+}
+
+// CNAMEStrategy configures how CNAME records at the challenge FQDN are handled
+// +kubebuilder:validation:Enum=None;Follow
+type CNAMEStrategy string
+
+const (
+	// NoneStrategy writes the TXT record at _acme-challenge.<domain> in the zone of the domain
+	NoneStrategy CNAMEStrategy = "None"
+
+	// FollowStrategy follows CNAMEs from _acme-challenge.<domain> and writes the TXT
+	// record at the final target
+	FollowStrategy CNAMEStrategy = "Follow"
+)
+
+// DigicloudIssuerACME contains the configuration for the ACME server
+type DigicloudIssuerACME struct {
+	// Server is the URL of the ACME server's directory endpoint
+	// +kubebuilder:default="https://acme-v02.api.letsencrypt.org/directory"
+	Server string `json:"server,omitempty"`
+
+	// Email is the contact email address registered with the ACME account
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// TermsOfServiceAgreed agrees to the terms of service of the ACME server, which
+	// is required to register a new account. An existing account of the private key
+	// is used without it.
+	// +optional
+	TermsOfServiceAgreed bool `json:"termsOfServiceAgreed,omitempty"`
+
+	// PrivateKeySecretRef is a reference to a secret containing the ACME account private key.
+	// A new key is generated and stored in the secret if it does not exist.
+	// Defaults to the key "tls.key" of the secret "<issuer name>-acme-account-key".
+	// +optional
+	PrivateKeySecretRef *SecretKeySelector `json:"privateKeySecretRef,omitempty"`
+
+	// DeactivateAccountOnDelete deactivates the ACME account at the ACME server when
+	// the issuer is deleted. The account key secret is kept.
+	// +optional
+	DeactivateAccountOnDelete bool `json:"deactivateAccountOnDelete,omitempty"`
 }
 
 // SecretKeySelector is a reference to a secret key
@@ -73,6 +189,105 @@ type DigicloudIssuerStatus struct {
 
 	// Conditions represent the latest available observations of the issuer's state
 	Conditions []cmapi.IssuerCondition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the issuer's spec the conditions were
+	// last computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ChallengeDelegations lists the CNAME-delegated challenges detected for DNS names
+	// this issuer has signed for
+	// +optional
+	ChallengeDelegations []ChallengeDelegation `json:"challengeDelegations,omitempty"`
+
+	// DigicloudNamespace is the Digicloud namespace of the issuer's own account
+	// +optional
+	DigicloudNamespace string `json:"digicloudNamespace,omitempty"`
+
+	// Zones lists the Digicloud zones visible to the API tokens of the issuer
+	// +optional
+	Zones []ZoneStatus `json:"zones,omitempty"`
+
+	// LastAPICheckTime is when the Digicloud API last answered the issuer's health check
+	// +optional
+	LastAPICheckTime *metav1.Time `json:"lastAPICheckTime,omitempty"`
+
+	// ACMEAccount reports the ACME account the issuer orders certificates with
+	// +optional
+	ACMEAccount *ACMEAccountStatus `json:"acmeAccount,omitempty"`
+
+	// InFlightChallenges is the number of challenge records the issuer has presented
+	// and not yet cleaned up
+	// +optional
+	InFlightChallenges int32 `json:"inFlightChallenges,omitempty"`
+
+	// ChallengeRecords lists the challenge TXT records the issuer has presented and not
+	// yet cleaned up, so that they can be removed when the issuer or the request they
+	// were presented for is deleted
+	// +optional
+	ChallengeRecords []ChallengeRecord `json:"challengeRecords,omitempty"`
+}
+
+// ZoneStatus reports a Digicloud zone visible to an API token of the issuer
+type ZoneStatus struct {
+	// Name is the name of the zone
+	Name string `json:"name"`
+
+	// ID is the Digicloud ID of the zone
+	ID string `json:"id"`
+
+	// Namespace is the Digicloud namespace the zone belongs to
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// NSVerification is Digicloud's verification state of the zone's NS delegation
+	// +optional
+	NSVerification string `json:"nsVerification,omitempty"`
+}
+
+// ACMEAccountStatus reports the ACME account of an issuer
+type ACMEAccountStatus struct {
+	// URI is the URL of the account at the ACME server
+	URI string `json:"uri"`
+
+	// Status is the status of the account reported by the ACME server
+	// +optional
+	Status string `json:"status,omitempty"`
+}
+
+// ChallengeRecord is a challenge TXT record presented by an issuer
+type ChallengeRecord struct {
+	// Request is the UID of the CertificateRequest the record was presented for
+	Request string `json:"request"`
+
+	// FQDN is the FQDN of the TXT record
+	FQDN string `json:"fqdn"`
+
+	// Zone is the Digicloud zone holding the record
+	Zone string `json:"zone"`
+
+	// Value is the content of the TXT record
+	Value string `json:"value"`
+
+	// Solver is the index of the solver whose account wrote the record. The issuer's
+	// own account wrote it if unset.
+	// +optional
+	Solver *int32 `json:"solver,omitempty"`
+}
+
+// ChallengeDelegation records that the dns-01 challenge for a DNS name is CNAME-delegated
+type ChallengeDelegation struct {
+	// DNSName is the requested DNS name, without a wildcard prefix
+	DNSName string `json:"dnsName"`
+
+	// ChallengeFQDN is the FQDN the ACME server queries, _acme-challenge.<dnsName>.
+	ChallengeFQDN string `json:"challengeFQDN"`
+
+	// Target is the FQDN the CNAME chain ends in, where the TXT record is written
+	Target string `json:"target"`
+
+	// Zone is the Digicloud zone holding Target
+	Zone string `json:"zone"`
 }
 
 //+kubebuilder:object:root=true
@@ -80,6 +295,11 @@ type DigicloudIssuerStatus struct {
 //+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 //+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
 //+kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message"
+//+kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".status.digicloudNamespace",priority=1
+//+kubebuilder:printcolumn:name="Account",type="string",JSONPath=".status.acmeAccount.status",priority=1
+//+kubebuilder:printcolumn:name="Challenges",type="integer",JSONPath=".status.inFlightChallenges",priority=1
+//+kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastAPICheckTime"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // DigicloudIssuer is the Schema for the digicloudissuers API
 type DigicloudIssuer struct {
@@ -103,12 +323,54 @@ type DigicloudIssuerList struct {
 type DigicloudClusterIssuerSpec struct {
 	// Provisioner contains the provisioner configuration for the cluster issuer
 	Provisioner DigicloudIssuerProvisioner `json:"provisioner"`
+
+	// NamespaceSelector restricts the namespaces whose CertificateRequests the cluster
+	// issuer signs. Requests from all namespaces are signed if unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 }
 
 // DigicloudClusterIssuerStatus defines the observed state of DigicloudClusterIssuer
 type DigicloudClusterIssuerStatus struct {
 	// Conditions represent the latest available observations of the cluster issuer's state
 	Conditions []cmapi.IssuerCondition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of the issuer's spec the conditions were
+	// last computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ChallengeDelegations lists the CNAME-delegated challenges detected for DNS names
+	// this cluster issuer has signed for
+	// +optional
+	ChallengeDelegations []ChallengeDelegation `json:"challengeDelegations,omitempty"`
+
+	// DigicloudNamespace is the Digicloud namespace of the issuer's own account
+	// +optional
+	DigicloudNamespace string `json:"digicloudNamespace,omitempty"`
+
+	// Zones lists the Digicloud zones visible to the API tokens of the issuer
+	// +optional
+	Zones []ZoneStatus `json:"zones,omitempty"`
+
+	// LastAPICheckTime is when the Digicloud API last answered the issuer's health check
+	// +optional
+	LastAPICheckTime *metav1.Time `json:"lastAPICheckTime,omitempty"`
+
+	// ACMEAccount reports the ACME account the issuer orders certificates with
+	// +optional
+	ACMEAccount *ACMEAccountStatus `json:"acmeAccount,omitempty"`
+
+	// InFlightChallenges is the number of challenge records the issuer has presented
+	// and not yet cleaned up
+	// +optional
+	InFlightChallenges int32 `json:"inFlightChallenges,omitempty"`
+
+	// ChallengeRecords lists the challenge TXT records the issuer has presented and not
+	// yet cleaned up, so that they can be removed when the issuer or the request they
+	// were presented for is deleted
+	// +optional
+	ChallengeRecords []ChallengeRecord `json:"challengeRecords,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -116,6 +378,11 @@ type DigicloudClusterIssuerStatus struct {
 //+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 //+kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
 //+kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].message"
+//+kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".status.digicloudNamespace",priority=1
+//+kubebuilder:printcolumn:name="Account",type="string",JSONPath=".status.acmeAccount.status",priority=1
+//+kubebuilder:printcolumn:name="Challenges",type="integer",JSONPath=".status.inFlightChallenges",priority=1
+//+kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastAPICheckTime"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // DigicloudClusterIssuer is the Schema for the digicloudclusterissuers API
 type DigicloudClusterIssuer struct {
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/vamirreza/digicloud-issuer/internal/standalone"
+)
+
+// stringsFlag is a flag that can be repeated to collect several values
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// standaloneFlags are the flags shared by certonly and renew
+type standaloneFlags struct {
+	configPath string
+	outDir     string
+	domains    stringsFlag
+	keyType    string
+	logOpts    zap.Options
+}
+
+func (f *standaloneFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.configPath, "config", "digicloud-issuer.yaml", "The YAML file configuring the issuer, mirroring an issuer's spec.provisioner.")
+	fs.StringVar(&f.outDir, "out", "certs", "The directory cert.pem, chain.pem, fullchain.pem and privkey.pem are written to.")
+	fs.Var(&f.domains, "d", "A DNS name of the certificate. Can be repeated.")
+	fs.StringVar(&f.keyType, "key-type", standalone.KeyTypeECDSA, "The type of the private key, ecdsa or rsa.")
+	f.logOpts.BindFlags(fs)
+}
+
+// runCertonly implements the certonly command, which obtains a certificate outside
+// Kubernetes and writes it to files
+func runCertonly(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("certonly", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var flags standaloneFlags
+	flags.bind(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(flags.domains) == 0 {
+		fmt.Fprintln(stderr, "at least one -d is required")
+		return 2
+	}
+
+	if err := obtain(&flags, flags.domains, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runRenew implements the renew command, which obtains a new certificate for the
+// names of the certificate written by certonly once it is close to expiry
+func runRenew(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("renew", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var flags standaloneFlags
+	flags.bind(fs)
+	var renewBefore time.Duration
+	var force bool
+	fs.DurationVar(&renewBefore, "renew-before", 30*24*time.Hour, "Renew once the certificate expires within this duration.")
+	fs.BoolVar(&force, "force", false, "Renew even if the certificate is not close to expiry.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	due, dnsNames, err := standalone.RenewalDue(flags.outDir, renewBefore)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !due && !force {
+		fmt.Fprintf(stdout, "Certificate in %s is not due for renewal\n", flags.outDir)
+		return 0
+	}
+	if len(flags.domains) > 0 {
+		dnsNames = flags.domains
+	}
+	if len(dnsNames) == 0 {
+		fmt.Fprintf(stderr, "no certificate found in %s, -d is required\n", flags.outDir)
+		return 2
+	}
+
+	if err := obtain(&flags, dnsNames, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// obtain obtains a certificate for dnsNames and writes it to the output directory
+func obtain(flags *standaloneFlags, dnsNames []string, stdout, stderr io.Writer) error {
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&flags.logOpts), zap.WriteTo(stderr)))
+
+	config, err := standalone.LoadConfig(flags.configPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cert, err := standalone.Obtain(ctx, config, standalone.Request{DNSNames: dnsNames, KeyType: flags.keyType}, stdout)
+	if err != nil {
+		return err
+	}
+	if err := standalone.WriteCertificate(flags.outDir, cert); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Certificate for %s written to %s\n", strings.Join(dnsNames, ", "), flags.outDir)
+	return nil
+}
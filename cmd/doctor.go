@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+)
+
+// runDoctor implements the doctor command, which runs every check issuance depends
+// on against an issuer in the cluster and prints a report
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var issuerRef, kubeconfig, kubeContext, clusterResourceNamespace string
+	var dnsNames stringsFlag
+	var timeout time.Duration
+	fs.StringVar(&issuerRef, "issuer", "", "The issuer to diagnose: <namespace>/<name> for a DigicloudIssuer, <name> for a DigicloudClusterIssuer.")
+	fs.Var(&dnsNames, "d", "A DNS name to check. Can be repeated. Defaults to the zones of the issuer's Digicloud accounts.")
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file. Defaults to $KUBECONFIG, ~/.kube/config or the in-cluster config.")
+	fs.StringVar(&kubeContext, "context", "", "The kubeconfig context to use.")
+	fs.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "",
+		"The namespace secrets referenced by cluster issuers are read from, as configured for the manager.")
+	fs.DurationVar(&timeout, "timeout", 10*time.Minute, "How long all checks may take.")
+	var logOpts zap.Options
+	logOpts.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if issuerRef == "" {
+		fmt.Fprintln(stderr, "--issuer is required")
+		return 2
+	}
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&logOpts), zap.WriteTo(stderr)))
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to load kubeconfig: %v\n", err)
+		return 1
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	issuer, key := controllers.IssuerForRef(issuerRef)
+	if err := c.Get(ctx, key, issuer); err != nil {
+		fmt.Fprintf(stderr, "Error: failed to get issuer %s: %v\n", issuerRef, err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Diagnosing %s, this creates and deletes a TXT record per DNS name...\n\n", issuerRef)
+	s := controllers.NewDigicloudSigner(c, *issuer.GetProvisioner(), clusterResourceNamespace)
+	results := s.Diagnose(ctx, issuer, dnsNames)
+
+	if !printReport(stdout, results) {
+		return 1
+	}
+	return 0
+}
+
+// printReport prints a line per check, with the hint of each failed check, and
+// reports whether all checks passed
+func printReport(w io.Writer, results []controllers.DiagnosticResult) bool {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	failed := 0
+	for _, result := range results {
+		if result.Passed() {
+			fmt.Fprintf(tw, "PASS\t%s\t%s\t%s\n", result.Check, result.Subject, result.Message)
+			continue
+		}
+		failed++
+		fmt.Fprintf(tw, "FAIL\t%s\t%s\t%v\n", result.Check, result.Subject, result.Err)
+		if result.Hint != "" {
+			fmt.Fprintf(tw, "\t\t\thint: %s\n", result.Hint)
+		}
+	}
+	_ = tw.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d checks failed\n", failed, len(results))
+		return false
+	}
+	fmt.Fprintf(w, "\nAll %d checks passed\n", len(results))
+	return true
+}
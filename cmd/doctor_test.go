@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+)
+
+func TestPrintReport(t *testing.T) {
+	var out bytes.Buffer
+	passed := printReport(&out, []controllers.DiagnosticResult{
+		{Check: controllers.CheckAPI, Subject: "issuer", Message: "Token accepted"},
+		{Check: controllers.CheckDelegation, Subject: "example.com", Err: errors.New("zone example.com is not delegated"), Hint: "Set the NS records"},
+	})
+
+	assert.False(t, passed)
+	assert.Contains(t, out.String(), "PASS  Digicloud API")
+	assert.Contains(t, out.String(), "FAIL  NS delegation")
+	assert.Contains(t, out.String(), "hint: Set the NS records")
+	assert.Contains(t, out.String(), "1 of 2 checks failed")
+}
@@ -0,0 +1,175 @@
+// Command kubectl-digicloud is a kubectl plugin for operating Digicloud issuers.
+// Installed on the PATH, it is run as "kubectl digicloud".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	// Import all Kubernetes client auth plugins, like kubectl does
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/kubectl"
+)
+
+const usage = `Operate Digicloud issuers.
+
+Usage:
+  kubectl digicloud issuers    [-n <namespace> | -A]
+  kubectl digicloud records    [--issuer <issuer>] [-n <namespace> | -A]
+  kubectl digicloud cleanup    [--issuer <issuer>] [-n <namespace> | -A] [--dry-run]
+  kubectl digicloud revalidate <issuer>
+  kubectl digicloud which      <dns-name> [-n <namespace> | -A] [-l <key>=<value>,...]
+
+Issuers are given as <namespace>/<name> for a DigicloudIssuer and <name> for a
+DigicloudClusterIssuer. Cluster issuers are included in every listing.
+
+Commands:
+  issuers     List issuers with their status and the zones of their accounts
+  records     List the challenge records the issuers created in Digicloud
+  cleanup     Delete challenge records no issuer tracks as an in-flight challenge
+  revalidate  Make the controller re-validate an issuer and check the Digicloud API
+  which       Show which account and zone each issuer would solve a DNS name with
+
+Run "kubectl digicloud <command> -h" for the flags of a command.
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(digicloudv1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "issuers", "records", "cleanup", "revalidate", "which":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("kubectl digicloud "+command, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var kubeconfig, kubeContext, namespace, clusterResourceNamespace, clusterID, issuerRef, selector string
+	var allNamespaces, dryRun bool
+	var timeout time.Duration
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&kubeContext, "context", "", "The kubeconfig context to use.")
+	fs.StringVar(&namespace, "n", "", "The namespace of the issuers. Defaults to the namespace of the context.")
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the issuers. Defaults to the namespace of the context.")
+	fs.BoolVar(&allNamespaces, "A", false, "Include the issuers of all namespaces.")
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "Include the issuers of all namespaces.")
+	fs.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "",
+		"The namespace secrets referenced by cluster issuers are read from, as configured for the manager.")
+	fs.DurationVar(&timeout, "timeout", 2*time.Minute, "How long the command may take.")
+	switch command {
+	case "records", "cleanup":
+		fs.StringVar(&issuerRef, "issuer", "", "Only the records in the zones of this issuer.")
+		fs.StringVar(&clusterID, "cluster-id", "",
+			"The cluster ID, as configured for the manager. Defaults to the UID of the kube-system namespace.")
+	case "which":
+		fs.StringVar(&selector, "l", "", "The labels of the CertificateRequest, as key=value pairs separated by commas.")
+	}
+	if command == "cleanup" {
+		fs.BoolVar(&dryRun, "dry-run", false, "Only print the records that would be deleted.")
+	}
+	// Flags may follow the positional arguments, as with kubectl
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if want := map[string]int{"revalidate": 1, "which": 1}[command]; len(positional) != want {
+		fmt.Fprintf(stderr, "%s takes %d arguments, got %d\n\n%s", command, want, len(positional), usage)
+		return 2
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to load kubeconfig: %v\n", err)
+		return 1
+	}
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if allNamespaces {
+		namespace = ""
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if clusterID == "" {
+		if clusterID, err = controllers.ClusterID(ctx, c); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	plugin := &kubectl.Plugin{Client: c, ClusterResourceNamespace: clusterResourceNamespace, ClusterID: clusterID, Out: stdout}
+
+	switch command {
+	case "issuers":
+		err = plugin.Issuers(ctx, namespace)
+	case "records":
+		err = plugin.Records(ctx, issuerRef, namespace)
+	case "cleanup":
+		err = plugin.Cleanup(ctx, issuerRef, namespace, dryRun)
+	case "revalidate":
+		err = plugin.Revalidate(ctx, positional[0])
+	case "which":
+		var set labels.Set
+		if set, err = labels.ConvertSelectorToLabelsMap(selector); err == nil {
+			err = plugin.Which(ctx, positional[0], namespace, set)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
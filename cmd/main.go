@@ -2,19 +2,7 @@
 Copyright 2025 Digicloud.
 
 Licensed under the Apache License, Version 2.0 (the "License");
-you may not use th	if printVersion {
-		return
-	}
-
-	// TODO: Implement cluster resource namespace detection if needed
-	// if err := getInClusterNamespace(&clusterResourceNamespace); err != nil {
-	//	if errors.Is(err, errNotInCluster) {
-	//		setupLog.Error(err, "please supply --cluster-resource-namespace")
-	//	} else {
-	//		setupLog.Error(err, "unexpected error")
-	//	}
-	//	os.Exit(1)
-	// }pt in compliance with the License.
+you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
 
     http://www.apache.org/licenses/LICENSE-2.0
@@ -29,12 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -54,9 +45,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/health"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
 	"github.com/vamirreza/digicloud-issuer/internal/version"
+	webhookv1alpha1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1alpha1"
+	webhookv1beta1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1beta1"
 
 	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	digicloudv1beta1 "github.com/vamirreza/digicloud-issuer/api/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -72,16 +69,27 @@ func init() {
 	utilruntime.Must(cmapi.AddToScheme(scheme))
 
 	utilruntime.Must(digicloudv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(digicloudv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 // nolint:gocyclo
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "certonly":
+			os.Exit(runCertonly(os.Args[2:], os.Stdout, os.Stderr))
+		case "renew":
+			os.Exit(runRenew(os.Args[2:], os.Stdout, os.Stderr))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	var clusterResourceNamespace string
 	var printVersion bool
-	// flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "",
-	//	"The namespace for secrets in which cluster-scoped resources are found.")
-	_ = clusterResourceNamespace // unused for now
+	flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "",
+		"The namespace for secrets in which cluster-scoped resources are found.")
 	flag.BoolVar(&printVersion, "version", false, "Print version to stdout and exit")
 
 	var metricsAddr string
@@ -109,6 +117,38 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers.")
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var traceSampleRatio float64
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector traces are exported to. Tracing is disabled if empty.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"If set, traces are exported to the OTLP collector without TLS.")
+	flag.Float64Var(&traceSampleRatio, "trace-sample-ratio", 1,
+		"The fraction of traces sampled, between 0 and 1.")
+	var dns01RecursiveNameservers string
+	var dns01RecursiveNameserversOnly bool
+	flag.StringVar(&dns01RecursiveNameservers, "dns01-recursive-nameservers", "",
+		"A comma-separated list of host:port recursive nameservers DNS01 challenge propagation is checked with. "+
+			"Defaults to the nameservers in /etc/resolv.conf.")
+	flag.BoolVar(&dns01RecursiveNameserversOnly, "dns01-recursive-nameservers-only", false,
+		"If set, DNS01 challenge propagation is checked with the recursive nameservers only, "+
+			"without querying the authoritative nameservers of the zone.")
+	var clusterID string
+	flag.StringVar(&clusterID, "cluster-id", "",
+		"The ID noted on the challenge records of this cluster. Defaults to the UID of the kube-system namespace.")
+	var maxConcurrentSigns int
+	var maxRetryDuration time.Duration
+	flag.IntVar(&maxConcurrentSigns, "max-concurrent-signs", controllers.DefaultMaxConcurrentSigns,
+		"How many CertificateRequests are signed at once.")
+	flag.DurationVar(&maxRetryDuration, "max-retry-duration", controllers.DefaultMaxRetryDuration,
+		"How long after its creation a CertificateRequest that fails to be signed is retried before it is failed.")
+	var apiGracePeriod, workerStallTimeout time.Duration
+	flag.DurationVar(&apiGracePeriod, "api-readiness-grace-period", 0,
+		"How recent the last successful Digicloud API call must be for the manager to be ready, once any issuer exists. "+
+			"Disabled if 0; "+health.DefaultAPIGracePeriod.String()+" suits the issuers' API checks.")
+	flag.DurationVar(&workerStallTimeout, "worker-stall-timeout", health.DefaultStallTimeout,
+		"How long a worker may be busy with one item before the liveness check fails.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -134,6 +174,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Endpoint:    otlpEndpoint,
+		Insecure:    otlpInsecure,
+		SampleRatio: traceSampleRatio,
+		ServiceName: "digicloud-issuer",
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -228,7 +279,8 @@ func main() {
 		"version", version.Version,
 		"enable-leader-election", enableLeaderElection,
 		"metrics-addr", metricsAddr,
-		// "cluster-resource-namespace", clusterResourceNamespace,
+		"otlp-endpoint", otlpEndpoint,
+		"cluster-resource-namespace", clusterResourceNamespace,
 	)
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -256,23 +308,88 @@ func main() {
 	}
 
 	ctx := ctrl.SetupSignalHandler()
-
-	if err = (&controllers.DigicloudIssuerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	watchdog := health.NewWatchdog(workerStallTimeout)
+
+	if err = (&controllers.IssuerReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ForObject: &digicloudv1alpha1.DigicloudIssuer{},
+		Recorder:  mgr.GetEventRecorderFor("digicloud-issuer"),
+		Watchdog:  watchdog,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create DigicloudIssuer controller")
 		os.Exit(1)
 	}
 
-	if err = (&controllers.DigicloudClusterIssuerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	if err = (&controllers.IssuerReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		ForObject:                &digicloudv1alpha1.DigicloudClusterIssuer{},
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+		Watchdog:                 watchdog,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create DigicloudClusterIssuer controller")
 		os.Exit(1)
 	}
 
+	// The cache is not started yet, so the cluster ID is read from the API server.
+	// Without it the records are noted without a cluster, which only affects the
+	// records the kubectl plugin attributes to this cluster.
+	if clusterID == "" {
+		if clusterID, err = controllers.ClusterID(ctx, mgr.GetAPIReader()); err != nil {
+			setupLog.Error(err, "unable to determine the cluster ID, challenge records are not noted with it")
+		}
+	}
+
+	var nameservers []string
+	if dns01RecursiveNameservers != "" {
+		nameservers = strings.Split(dns01RecursiveNameservers, ",")
+	}
+	if err = (&controllers.SignerController{
+		Client:                   mgr.GetClient(),
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+		Watchdog:                 watchdog,
+		MaxConcurrentSigns:       maxConcurrentSigns,
+		MaxRetryDuration:         maxRetryDuration,
+		Nameservers:              nameservers,
+		RecursiveNameserversOnly: dns01RecursiveNameserversOnly,
+		ClusterID:                clusterID,
+	}).SetupWithManager(ctx, mgr); err != nil {
+		setupLog.Error(err, "unable to create signer controller")
+		os.Exit(1)
+	}
+
+	if err = (&controllers.CertificateRequestReconciler{
+		Client:                   mgr.GetClient(),
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+		Watchdog:                 watchdog,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create CertificateRequest controller")
+		os.Exit(1)
+	}
+
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupDigicloudIssuerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DigicloudIssuer")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupDigicloudClusterIssuerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DigicloudClusterIssuer")
+			os.Exit(1)
+		}
+		if err = webhookv1beta1.SetupDigicloudIssuerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DigicloudIssuer", "version", "v1beta1")
+			os.Exit(1)
+		}
+		if err = webhookv1beta1.SetupDigicloudClusterIssuerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DigicloudClusterIssuer", "version", "v1beta1")
+			os.Exit(1)
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -295,13 +412,28 @@ func main() {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddHealthzCheck("workers", watchdog.Check); err != nil {
+		setupLog.Error(err, "unable to set up worker health check")
+		os.Exit(1)
+	}
+	readiness := health.NewReadiness(mgr.GetCache(), mgr.GetClient(), dnsprovider.LastSuccessfulRequest, apiGracePeriod)
+	readiness.SetElected(mgr.Elected())
+	if err := mgr.AddReadyzCheck("readyz", readiness.Check); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctx); err != nil {
+	err = mgr.Start(ctx)
+
+	// Flush the spans of the last requests before exiting
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "failed to flush traces")
+	}
+	cancel()
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
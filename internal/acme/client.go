@@ -0,0 +1,264 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/registration"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
+)
+
+// DefaultServer is the ACME directory used when an issuer does not configure one
+const DefaultServer = lego.LEDirectoryProduction
+
+// ErrTermsOfServiceNotAgreed is returned when a new account would have to be
+// registered without agreeing to the terms of service of the ACME server
+var ErrTermsOfServiceNotAgreed = errors.New("the ACME account does not exist and its terms of service are not agreed to")
+
+// Options configures an ACME client
+type Options struct {
+	// DirectoryURL is the URL of the ACME server's directory endpoint
+	DirectoryURL string
+
+	// Email is the contact email address registered with the account
+	Email string
+
+	// Key is the account private key
+	Key crypto.PrivateKey
+
+	// TermsOfServiceAgreed agrees to the terms of service of the ACME server when a
+	// new account is registered for Key
+	TermsOfServiceAgreed bool
+
+	// HTTPClient is used for all requests to the ACME server
+	HTTPClient *http.Client
+}
+
+// Client orders certificates from an ACME server, solving DNS01 challenges with a lego DNS provider
+type Client struct {
+	directoryURL         string
+	httpClient           *http.Client
+	transport            *tracingTransport
+	user                 *user
+	client               *lego.Client
+	termsOfServiceAgreed bool
+}
+
+// user implements registration.User for the issuer's ACME account
+type user struct {
+	email        string
+	key          crypto.PrivateKey
+	registration *registration.Resource
+}
+
+func (u *user) GetEmail() string                        { return u.email }
+func (u *user) GetRegistration() *registration.Resource { return u.registration }
+func (u *user) GetPrivateKey() crypto.PrivateKey        { return u.key }
+
+// NewClient creates a new ACME client for the directory in opts. Requests lego sends
+// outside of Obtain are traced in ctx.
+func NewClient(ctx context.Context, opts Options) (*Client, error) {
+	if opts.DirectoryURL == "" {
+		opts.DirectoryURL = DefaultServer
+	}
+	if opts.Key == nil {
+		return nil, fmt.Errorf("an ACME account key is required")
+	}
+
+	u := &user{email: opts.Email, key: opts.Key}
+	config := lego.NewConfig(u)
+	config.CADirURL = opts.DirectoryURL
+	config.Certificate.KeyType = certcrypto.EC256
+	if opts.HTTPClient != nil {
+		config.HTTPClient = opts.HTTPClient
+	}
+	httpClient := *config.HTTPClient
+	transport := &tracingTransport{next: httpClient.Transport, ctx: ctx}
+	if transport.next == nil {
+		transport.next = http.DefaultTransport
+	}
+	httpClient.Transport = transport
+	config.HTTPClient = &httpClient
+
+	client, err := lego.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ACME client for %s: %w", opts.DirectoryURL, err)
+	}
+
+	return &Client{
+		directoryURL:         opts.DirectoryURL,
+		httpClient:           config.HTTPClient,
+		transport:            transport,
+		user:                 u,
+		client:               client,
+		termsOfServiceAgreed: opts.TermsOfServiceAgreed,
+	}, nil
+}
+
+// CAAIdentities returns the CAA identities advertised in the directory of the
+// client's ACME server
+func (c *Client) CAAIdentities(ctx context.Context) ([]string, error) {
+	return CAAIdentities(ctx, c.httpClient, c.directoryURL)
+}
+
+// SetDNS01Provider sets the provider used to solve DNS01 challenges
+func (c *Client) SetDNS01Provider(provider challenge.Provider, opts ...dns01.ChallengeOption) error {
+	return c.client.Challenge.SetDNS01Provider(provider, opts...)
+}
+
+// Obtain registers the account if needed and orders a certificate for csr. It
+// returns the PEM encoded certificate chain and the certificate of the issuing CA.
+func (c *Client) Obtain(ctx context.Context, csr *x509.CertificateRequest) (chainPEM, caPEM []byte, err error) {
+	ctx, span := tracing.Start(ctx, "ACME Obtain", trace.WithAttributes(semconv.URLFull(c.directoryURL)))
+	defer func() { tracing.End(span, err) }()
+	defer c.transport.setContext(ctx)()
+
+	if err := c.register(); err != nil {
+		return nil, nil, err
+	}
+
+	resource, err := c.client.Certificate.ObtainForCSR(certificate.ObtainForCSRRequest{
+		CSR:    csr,
+		Bundle: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resource.Certificate, resource.IssuerCertificate, nil
+}
+
+// Account returns the URI and status of the client's account at the ACME server.
+// Both are empty until Obtain has registered or looked up the account.
+func (c *Client) Account() (uri, status string) {
+	if c.user.registration == nil {
+		return "", ""
+	}
+	return c.user.registration.URI, c.user.registration.Body.Status
+}
+
+// DeactivateAccount deactivates the account of the client's key at the ACME server
+func (c *Client) DeactivateAccount() error {
+	reg, err := c.client.Registration.ResolveAccountByKey()
+	if err != nil {
+		return fmt.Errorf("failed to look up ACME account: %w", err)
+	}
+	c.user.registration = reg
+
+	if err := c.client.Registration.DeleteRegistration(); err != nil {
+		return fmt.Errorf("failed to deactivate ACME account: %w", err)
+	}
+	return nil
+}
+
+// register looks up the account for the client's key and creates it if it does not
+// exist and the terms of service are agreed to
+func (c *Client) register() error {
+	if c.user.registration != nil {
+		return nil
+	}
+
+	reg, err := c.client.Registration.ResolveAccountByKey()
+	if err != nil {
+		if !c.termsOfServiceAgreed {
+			return fmt.Errorf("%w: %w", ErrTermsOfServiceNotAgreed, err)
+		}
+		reg, err = c.client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+		if err != nil {
+			return fmt.Errorf("failed to register ACME account: %w", err)
+		}
+	}
+
+	c.user.registration = reg
+	return nil
+}
+
+// tracingTransport starts a span for each request to the ACME server. lego sends its
+// requests without a context, so their spans are started in the context of the
+// client's current operation instead.
+type tracingTransport struct {
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+// setContext sets the context spans of requests without one are started in, and
+// returns a function restoring the previous context
+func (t *tracingTransport) setContext(ctx context.Context) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous := t.ctx
+	t.ctx = ctx
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.ctx = previous
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tracingTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	ctx := req.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		t.mu.Lock()
+		ctx = t.ctx
+		t.mu.Unlock()
+	}
+
+	_, span := tracing.Start(ctx, "ACME "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+		))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		tracing.End(span, err)
+	}()
+
+	return t.next.RoundTrip(req.WithContext(trace.ContextWithSpan(req.Context(), span)))
+}
+
+// GenerateAccountKey creates a new ECDSA P-256 account key and returns it PEM encoded
+func GenerateAccountKey() (crypto.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ACME account key: %w", err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode ACME account key: %w", err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParseAccountKey parses a PEM encoded account key
+func ParseAccountKey(data []byte) (crypto.PrivateKey, error) {
+	key, err := certcrypto.ParsePEMPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ACME account key: %w", err)
+	}
+	return key, nil
+}
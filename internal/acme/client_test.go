@@ -0,0 +1,163 @@
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/letsencrypt/pebble/v2/ca"
+	"github.com/letsencrypt/pebble/v2/db"
+	"github.com/letsencrypt/pebble/v2/va"
+	"github.com/letsencrypt/pebble/v2/wfe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
+)
+
+func newDirectoryServer(t *testing.T, caaIdentities []string) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"newNonce":   server.URL + "/new-nonce",
+			"newAccount": server.URL + "/new-account",
+			"newOrder":   server.URL + "/new-order",
+			"meta":       map[string]any{"caaIdentities": caaIdentities},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClient_CAAIdentities(t *testing.T) {
+	server := newDirectoryServer(t, []string{"letsencrypt.org"})
+
+	key, _, err := GenerateAccountKey()
+	require.NoError(t, err)
+
+	client, err := NewClient(context.Background(), Options{DirectoryURL: server.URL, Key: key})
+	require.NoError(t, err)
+
+	identities, err := client.CAAIdentities(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"letsencrypt.org"}, identities)
+}
+
+func TestClient_TracesRequests(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := newDirectoryServer(t, nil)
+	key, _, err := GenerateAccountKey()
+	require.NoError(t, err)
+
+	ctx, parent := tracing.Start(tracing.WithRequestUID(context.Background(), "request-uid"), "parent")
+	client, err := NewClient(ctx, Options{DirectoryURL: server.URL, Key: key})
+	require.NoError(t, err)
+	_, err = client.CAAIdentities(ctx)
+	require.NoError(t, err)
+	parent.End()
+
+	// lego fetches the directory without a context in NewClient
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "ACME GET", span.Name)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.Contains(t, span.Attributes, tracing.RequestUIDKey.String("request-uid"))
+	}
+}
+
+func TestNewClient_RequiresKey(t *testing.T) {
+	_, err := NewClient(context.Background(), Options{DirectoryURL: "http://127.0.0.1"})
+	assert.Error(t, err)
+}
+
+func TestParseAccountKey(t *testing.T) {
+	key, keyPEM, err := GenerateAccountKey()
+	require.NoError(t, err)
+
+	parsed, err := ParseAccountKey(keyPEM)
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = ParseAccountKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+// newPebble starts a Pebble ACME server and returns its directory URL and a client
+// trusting its HTTPS certificate
+func newPebble(t *testing.T) (string, *http.Client) {
+	t.Helper()
+
+	logger := log.New(io.Discard, "", 0)
+	store := db.NewMemoryStore()
+	authority := ca.New(logger, store, "", "ecdsa", 0, 1, map[string]ca.Profile{
+		"default": {Description: "The default profile"},
+	})
+	validator := va.New(logger, 0, 0, false, "127.0.0.1:0", store)
+	frontend := wfe.New(logger, store, validator, authority, nil, false, false, 1, 1)
+
+	server := httptest.NewTLSServer(frontend.Handler())
+	t.Cleanup(server.Close)
+	return server.URL + wfe.DirectoryPath, server.Client()
+}
+
+func TestClient_RegisterRequiresTermsOfService(t *testing.T) {
+	t.Setenv("PEBBLE_WFE_NONCEREJECT", "0")
+	directoryURL, httpClient := newPebble(t)
+	key, _, err := GenerateAccountKey()
+	require.NoError(t, err)
+
+	client, err := NewClient(context.Background(), Options{DirectoryURL: directoryURL, Key: key, HTTPClient: httpClient})
+	require.NoError(t, err)
+	_, _, err = client.Obtain(context.Background(), &x509.CertificateRequest{})
+	require.ErrorIs(t, err, ErrTermsOfServiceNotAgreed)
+	uri, _ := client.Account()
+	assert.Empty(t, uri)
+
+	client, err = NewClient(context.Background(), Options{
+		DirectoryURL:         directoryURL,
+		Key:                  key,
+		HTTPClient:           httpClient,
+		TermsOfServiceAgreed: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.register())
+	uri, status := client.Account()
+	assert.NotEmpty(t, uri)
+	assert.Equal(t, "valid", status)
+
+	// The existing account is used without agreeing again
+	client, err = NewClient(context.Background(), Options{DirectoryURL: directoryURL, Key: key, HTTPClient: httpClient})
+	require.NoError(t, err)
+	require.NoError(t, client.register())
+	existing, _ := client.Account()
+	assert.Equal(t, uri, existing)
+}
+
+func TestCAAIdentities(t *testing.T) {
+	server := newDirectoryServer(t, []string{"letsencrypt.org", "example.net"})
+
+	identities, err := CAAIdentities(context.Background(), nil, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"letsencrypt.org", "example.net"}, identities)
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(failing.Close)
+	_, err = CAAIdentities(context.Background(), nil, failing.URL)
+	assert.ErrorContains(t, err, "status 404")
+}
@@ -0,0 +1,44 @@
+package acme
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-acme/lego/v4/acme"
+)
+
+// CAAIdentities returns the issuer domain names the ACME server at directoryURL
+// recognizes in CAA records, as advertised in the meta.caaIdentities field of its
+// directory. No account is needed. The request is traced in ctx if httpClient is nil.
+func CAAIdentities(ctx context.Context, httpClient *http.Client, directoryURL string) ([]string, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &tracingTransport{next: http.DefaultTransport, ctx: ctx}}
+	}
+	if directoryURL == "" {
+		directoryURL = DefaultServer
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", directoryURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ACME directory request failed with status %d", resp.StatusCode)
+	}
+
+	var directory acme.Directory
+	if err := json.NewDecoder(resp.Body).Decode(&directory); err != nil {
+		return nil, fmt.Errorf("failed to decode ACME directory: %w", err)
+	}
+
+	return directory.Meta.CaaIdentities, nil
+}
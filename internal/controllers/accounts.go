@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// Account is a Digicloud account of an issuer, configured by the provisioner itself
+// or by one of its solvers
+type Account struct {
+	// Solver is the index of the solver configuring the account, or -1 for the
+	// provisioner's own account
+	Solver int
+
+	// Provider manages the zones of the account
+	Provider *dnsprovider.DigicloudProvider
+}
+
+// String returns "issuer" for the provisioner's own account, "solver <index>" otherwise
+func (a Account) String() string {
+	return accountName(a.Solver)
+}
+
+// Accounts returns a provider for every Digicloud account of issuerObj, reading the
+// API tokens from the referenced secrets. Accounts whose secret cannot be read are
+// left out and reported in the returned error.
+func (s *DigicloudSigner) Accounts(ctx context.Context, issuerObj client.Object) ([]Account, error) {
+	var accounts []Account
+	var errs []error
+	for _, index := range s.solverIndexes() {
+		provider, err := s.newProvider(ctx, issuerObj, s.solver(index))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", accountName(index), err))
+			continue
+		}
+		provider.SetContext(ctx)
+		accounts = append(accounts, Account{Solver: index, Provider: provider})
+	}
+	return accounts, errors.Join(errs...)
+}
+
+// SelectSolver returns the index of the solver the signer selects for dnsName in a
+// CertificateRequest with labels, or -1 if the provisioner's own account is used
+func (s *DigicloudSigner) SelectSolver(dnsName string, labels map[string]string) int {
+	return selectSolver(s.issuerSpec.Solvers, dnsName, labels)
+}
+
+// DomainAllowed reports whether the allowedDomains of the issuer permit dnsName
+func (s *DigicloudSigner) DomainAllowed(dnsName string) (bool, error) {
+	matchers, err := compileAllowedDomains(s.issuerSpec.AllowedDomains)
+	if err != nil {
+		return false, err
+	}
+	return domainAllowed(matchers, dnsName), nil
+}
+
+// IssuerForRef returns an empty issuer and the key for ref, which is
+// <namespace>/<name> for a DigicloudIssuer and <name> for a DigicloudClusterIssuer
+func IssuerForRef(ref string) (IssuerObject, types.NamespacedName) {
+	if namespace, name, ok := strings.Cut(ref, "/"); ok {
+		return &digicloudv1alpha1.DigicloudIssuer{}, types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return &digicloudv1alpha1.DigicloudClusterIssuer{}, types.NamespacedName{Name: ref}
+}
+
+// solverIndexes returns the index of every solver with an account: the provisioner's
+// own if it references an API token, followed by all solvers
+func (s *DigicloudSigner) solverIndexes() []int {
+	indexes := make([]int, 0, len(s.issuerSpec.Solvers)+1)
+	if s.issuerSpec.APITokenSecretRef.Name != "" {
+		indexes = append(indexes, defaultSolver)
+	}
+	for i := range s.issuerSpec.Solvers {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// accountName names the account of the solver at index in reports
+func accountName(index int) string {
+	if index == defaultSolver {
+		return "issuer"
+	}
+	return fmt.Sprintf("solver %d", index)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/health"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
+)
+
+// CertificateRequestReconciler cleans up the challenge records presented for
+// CertificateRequests that are deleted mid-challenge
+type CertificateRequestReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// ClusterResourceNamespace is the namespace secrets referenced by cluster issuers
+	// are read from. Defaults to DefaultClusterResourceNamespace.
+	ClusterResourceNamespace string
+
+	// Watchdog tracks reconciles so that a wedged worker fails the liveness check.
+	// Optional.
+	Watchdog *health.Watchdog
+}
+
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests/finalizers,verbs=update
+
+// Reconcile removes the challenge records of a deleted CertificateRequest and then
+// its RequestFinalizer
+func (r *CertificateRequestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	defer r.Watchdog.Track()()
+
+	var cr cmapi.CertificateRequest
+	if err := r.Get(ctx, req.NamespacedName, &cr); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if cr.DeletionTimestamp.IsZero() || !controllerutil.ContainsFinalizer(&cr, RequestFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	logger = logger.WithValues("requestUID", cr.UID)
+	ctx = log.IntoContext(tracing.WithRequestUID(ctx, cr.UID), logger)
+
+	issuer, err := r.getIssuer(ctx, &cr)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Without its issuer the records are cleaned up by the issuer's finalizer
+	if issuer != nil {
+		cleanupCtx, cancel := context.WithTimeout(ctx, cleanupTimeout)
+		defer cancel()
+		cleanupCtx, span := tracing.Start(cleanupCtx, "Clean up challenge records")
+		s := NewDigicloudSigner(r.Client, *issuer.GetProvisioner(), r.ClusterResourceNamespace)
+		err := s.cleanUpChallengeRecords(cleanupCtx, issuer, cr.UID)
+		tracing.End(span, err)
+		if err != nil {
+			if cleanupPending(&cr) {
+				logger.Error(err, "Failed to clean up challenge records, retrying")
+				r.Recorder.Eventf(&cr, corev1.EventTypeWarning, EventReasonCleanupFailed, "Failed to clean up challenge records, retrying: %v", err)
+				return ctrl.Result{RequeueAfter: cleanupRetryInterval}, nil
+			}
+			logger.Error(err, "Giving up cleaning up challenge records")
+			r.Recorder.Eventf(&cr, corev1.EventTypeWarning, EventReasonCleanupAbandoned,
+				"Removing finalizer after failing to clean up challenge records for %s: %v", CleanupGracePeriod, err)
+		}
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	controllerutil.RemoveFinalizer(&cr, RequestFinalizer)
+	return ctrl.Result{}, client.IgnoreNotFound(r.Patch(ctx, &cr, patch))
+}
+
+// getIssuer returns the Digicloud issuer referenced by cr, or nil if it no longer exists
+func (r *CertificateRequestReconciler) getIssuer(ctx context.Context, cr *cmapi.CertificateRequest) (IssuerObject, error) {
+	var issuer IssuerObject
+	key := types.NamespacedName{Name: cr.Spec.IssuerRef.Name}
+	switch cr.Spec.IssuerRef.Kind {
+	case "DigicloudIssuer":
+		issuer = &digicloudv1alpha1.DigicloudIssuer{}
+		key.Namespace = cr.Namespace
+	case "DigicloudClusterIssuer":
+		issuer = &digicloudv1alpha1.DigicloudClusterIssuer{}
+	default:
+		return nil, nil
+	}
+
+	if err := r.Get(ctx, key, issuer); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return issuer, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CertificateRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cmapi.CertificateRequest{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return controllerutil.ContainsFinalizer(obj, RequestFinalizer)
+		}))).
+		Complete(r)
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+func TestCertificateRequestReconciler_Reconcile(t *testing.T) {
+	server := newRecordsServer(t, http.StatusOK)
+	issuer, secret := newDeletedIssuer(server.URL, time.Now())
+	issuer.DeletionTimestamp = nil
+	issuer.Finalizers = nil
+
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-request",
+			Namespace:         "default",
+			UID:               "request-2",
+			Finalizers:        []string{RequestFinalizer},
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			IssuerRef: cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group},
+		},
+	}
+
+	scheme := newIssuerTestScheme(t)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer, secret, cr).
+		WithStatusSubresource(issuer).
+		Build()
+
+	reconciler := &CertificateRequestReconciler{
+		Client:   fakeClient,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(cr),
+	})
+	require.NoError(t, err)
+
+	// Only the record presented for the request is removed
+	assert.Equal(t, []string{"/v1/edge/domains/example.com/records/rec-2"}, server.deleted)
+
+	updated := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+	assert.Equal(t, testChallengeRecords[:1], updated.Status.ChallengeRecords)
+	assert.Equal(t, int32(1), updated.Status.InFlightChallenges)
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(cr), &cmapi.CertificateRequest{})
+	assert.True(t, apierrors.IsNotFound(err), "request should be deleted once its finalizer is removed")
+}
+
+func TestCertificateRequestReconciler_Reconcile_IssuerDeleted(t *testing.T) {
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-request",
+			Namespace:         "default",
+			Finalizers:        []string{RequestFinalizer},
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			IssuerRef: cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(newIssuerTestScheme(t)).
+		WithObjects(cr).
+		Build()
+
+	reconciler := &CertificateRequestReconciler{
+		Client:   fakeClient,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(cr),
+	})
+	require.NoError(t, err)
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(cr), &cmapi.CertificateRequest{})
+	assert.True(t, apierrors.IsNotFound(err), "request should be deleted once its finalizer is removed")
+}
+
+func TestCertificateRequestReconciler_Reconcile_NotDeleted(t *testing.T) {
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-request",
+			Namespace:  "default",
+			Finalizers: []string{RequestFinalizer},
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			IssuerRef: cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(newIssuerTestScheme(t)).
+		WithObjects(cr).
+		Build()
+
+	reconciler := &CertificateRequestReconciler{
+		Client:   fakeClient,
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(cr),
+	})
+	require.NoError(t, err)
+	assert.Zero(t, result)
+
+	// The finalizer is kept while the request is being signed
+	updated := &cmapi.CertificateRequest{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(cr), updated))
+	assert.Contains(t, updated.Finalizers, RequestFinalizer)
+}
+
+func TestCertificateRequestReconciler_Reconcile_APIUnreachable(t *testing.T) {
+	tests := []struct {
+		name          string
+		deleted       time.Time
+		wantRequeue   time.Duration
+		wantEvent     string
+		wantFinalizer bool
+	}{
+		{
+			name:          "within grace period",
+			deleted:       time.Now(),
+			wantRequeue:   cleanupRetryInterval,
+			wantEvent:     "Warning " + EventReasonCleanupFailed,
+			wantFinalizer: true,
+		},
+		{
+			name:      "grace period passed",
+			deleted:   time.Now().Add(-CleanupGracePeriod - time.Minute),
+			wantEvent: "Warning " + EventReasonCleanupAbandoned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRecordsServer(t, http.StatusServiceUnavailable)
+			issuer, secret := newDeletedIssuer(server.URL, time.Now())
+			issuer.DeletionTimestamp = nil
+			issuer.Finalizers = nil
+
+			cr := &cmapi.CertificateRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "test-request",
+					Namespace:         "default",
+					UID:               "request-2",
+					Finalizers:        []string{RequestFinalizer},
+					DeletionTimestamp: &metav1.Time{Time: tt.deleted},
+				},
+				Spec: cmapi.CertificateRequestSpec{
+					IssuerRef: cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group},
+				},
+			}
+
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(newIssuerTestScheme(t)).
+				WithObjects(issuer, secret, cr).
+				WithStatusSubresource(issuer).
+				Build()
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &CertificateRequestReconciler{
+				Client:   fakeClient,
+				Recorder: recorder,
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(cr),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRequeue, result.RequeueAfter)
+
+			require.Len(t, recorder.Events, 1)
+			assert.Contains(t, <-recorder.Events, tt.wantEvent)
+
+			// The record stays on the issuer, whose finalizer removes it later
+			updatedIssuer := &v1alpha1.DigicloudIssuer{}
+			require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updatedIssuer))
+			assert.Equal(t, testChallengeRecords, updatedIssuer.Status.ChallengeRecords)
+
+			updated := &cmapi.CertificateRequest{}
+			err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(cr), updated)
+			if !tt.wantFinalizer {
+				assert.True(t, apierrors.IsNotFound(err), "request should be deleted once its finalizer is removed")
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, updated.Finalizers, RequestFinalizer)
+		})
+	}
+}
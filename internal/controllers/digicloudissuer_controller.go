@@ -18,84 +18,66 @@ package controllers
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 	"github.com/cert-manager/issuer-lib/controllers/signer"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
 	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/health"
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
 )
 
-// DigicloudIssuerReconciler reconciles a DigicloudIssuer object
-type DigicloudIssuerReconciler struct {
-	client.Client
-	Scheme *runtime.Scheme
-}
-
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
-
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-func (r *DigicloudIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
-
-	// Fetch the DigicloudIssuer instance
-	var issuer digicloudv1alpha1.DigicloudIssuer
-	if err := r.Get(ctx, req.NamespacedName, &issuer); err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.Info("DigicloudIssuer resource not found, ignoring since object must be deleted")
-			return ctrl.Result{}, nil
-		}
-		logger.Error(err, "Failed to get DigicloudIssuer")
-		return ctrl.Result{}, err
+// validateProvisioner validates the API token secret references of the provisioner
+// and its solvers against the secrets in secretNamespace
+func validateProvisioner(ctx context.Context, c client.Reader, provisioner digicloudv1alpha1.DigicloudIssuerProvisioner, secretNamespace string) error {
+	if _, err := compileAllowedDomains(provisioner.AllowedDomains); err != nil {
+		return err
 	}
 
-	// Initialize status conditions if not set
-	if issuer.Status.Conditions == nil {
-		issuer.Status.Conditions = []cmapi.IssuerCondition{}
-	}
-
-	// Validate the issuer configuration
-	if err := r.validateIssuer(ctx, &issuer); err != nil {
-		logger.Error(err, "Invalid issuer configuration")
-		r.setReadyCondition(&issuer, "Failed", err.Error())
-		if statusErr := r.Status().Update(ctx, &issuer); statusErr != nil {
-			logger.Error(statusErr, "Failed to update status")
-			return ctrl.Result{}, statusErr
+	// The provisioner's own token is only optional when solvers supply the credentials
+	if provisioner.APITokenSecretRef != (digicloudv1alpha1.SecretKeySelector{}) || len(provisioner.Solvers) == 0 {
+		if err := validateSecretRef(ctx, c, provisioner.APITokenSecretRef, secretNamespace); err != nil {
+			return err
 		}
-		return ctrl.Result{}, err
 	}
 
-	// Set ready condition
-	r.setReadyCondition(&issuer, "Checked", "Issuer configuration is valid")
-	if err := r.Status().Update(ctx, &issuer); err != nil {
-		logger.Error(err, "Failed to update status")
-		return ctrl.Result{}, err
+	for i, solver := range provisioner.Solvers {
+		if err := validateSecretRef(ctx, c, solver.APITokenSecretRef, secretNamespace); err != nil {
+			return fmt.Errorf("solver %d: %w", i, err)
+		}
 	}
 
-	logger.Info("DigicloudIssuer reconciled successfully")
-	return ctrl.Result{}, nil
+	return nil
 }
 
-// validateIssuer validates the issuer configuration
-func (r *DigicloudIssuerReconciler) validateIssuer(ctx context.Context, issuer *digicloudv1alpha1.DigicloudIssuer) error {
-	// Validate API token secret reference
-	secretName := issuer.Spec.Provisioner.APITokenSecretRef.Name
-	secretKey := issuer.Spec.Provisioner.APITokenSecretRef.Key
+// validateSecretRef checks that the referenced API token secret exists and contains the key
+func validateSecretRef(ctx context.Context, c client.Reader, ref digicloudv1alpha1.SecretKeySelector, secretNamespace string) error {
+	secretName := ref.Name
+	secretKey := ref.Key
 
 	if secretName == "" || secretKey == "" {
 		return fmt.Errorf("API token secret reference must specify both name and key")
@@ -105,12 +87,12 @@ func (r *DigicloudIssuerReconciler) validateIssuer(ctx context.Context, issuer *
 	var secret corev1.Secret
 	secretNamespacedName := types.NamespacedName{
 		Name:      secretName,
-		Namespace: issuer.Namespace,
+		Namespace: secretNamespace,
 	}
 
-	if err := r.Get(ctx, secretNamespacedName, &secret); err != nil {
+	if err := c.Get(ctx, secretNamespacedName, &secret); err != nil {
 		if apierrors.IsNotFound(err) {
-			return fmt.Errorf("API token secret %s not found in namespace %s", secretName, issuer.Namespace)
+			return fmt.Errorf("API token secret %s not found in namespace %s", secretName, secretNamespace)
 		}
 		return fmt.Errorf("failed to get API token secret: %w", err)
 	}
@@ -123,230 +105,387 @@ func (r *DigicloudIssuerReconciler) validateIssuer(ctx context.Context, issuer *
 	return nil
 }
 
-// setReadyCondition sets the Ready condition on the issuer
-func (r *DigicloudIssuerReconciler) setReadyCondition(issuer *digicloudv1alpha1.DigicloudIssuer, reason, message string) {
-	status := cmmeta.ConditionTrue
-	if reason == "Failed" {
-		status = cmmeta.ConditionFalse
-	}
-
-	// Find existing condition
-	for i, condition := range issuer.Status.Conditions {
-		if condition.Type == cmapi.IssuerConditionReady {
-			issuer.Status.Conditions[i].Status = status
-			issuer.Status.Conditions[i].Reason = reason
-			issuer.Status.Conditions[i].Message = message
-			now := metav1.Now()
-			issuer.Status.Conditions[i].LastTransitionTime = &now
-			return
-		}
+// CertificateRequestConditionSigningFailed is the CertificateRequest condition set
+// to True with one of the reasons below when signing fails permanently. issuer-lib
+// overwrites the reason of the Ready condition with Failed, so the reason is kept
+// on a condition of its own.
+const CertificateRequestConditionSigningFailed cmapi.CertificateRequestConditionType = "SigningFailed"
+
+const (
+	// ReasonZoneNotDelegated is the CertificateRequest condition reason used when a
+	// zone's NS records do not point at Digicloud
+	ReasonZoneNotDelegated = "ZoneNotDelegated"
+
+	// ReasonDNSSECMismatch is the CertificateRequest and issuer DNSSEC condition reason
+	// used when the DS published at a zone's parent does not match the DNSSEC keys
+	// Digicloud signs with
+	ReasonDNSSECMismatch = "DNSSECMismatch"
+
+	// ReasonDNSSECChecked is the issuer DNSSEC condition reason used when the chain of
+	// trust of the zones a request was checked for is intact or DNSSEC is disabled
+	ReasonDNSSECChecked = "Checked"
+
+	// ReasonCAAForbidden is the CertificateRequest condition reason used when CAA
+	// records do not authorize the configured ACME server to issue for a name
+	ReasonCAAForbidden = "CAAForbidden"
+
+	// ReasonTermsOfServiceNotAgreed is the CertificateRequest condition reason used
+	// when an ACME account would have to be registered without termsOfServiceAgreed
+	ReasonTermsOfServiceNotAgreed = "TermsOfServiceNotAgreed"
+)
+
+// DigicloudSigner implements the cert-manager issuer-lib signer interface
+type DigicloudSigner struct {
+	issuerSpec               digicloudv1alpha1.DigicloudIssuerProvisioner
+	clusterResourceNamespace string
+	client                   client.Client
+	nsResolver               dnsprovider.NSResolver
+	nameservers              []string
+	recursiveOnly            bool
+	recorder                 record.EventRecorder
+	watchdog                 *health.Watchdog
+	clusterID                string
+}
+
+// NewDigicloudSigner creates a new Digicloud signer. Secrets referenced by cluster
+// issuers are read from clusterResourceNamespace.
+func NewDigicloudSigner(client client.Client, issuerSpec digicloudv1alpha1.DigicloudIssuerProvisioner, clusterResourceNamespace string) *DigicloudSigner {
+	return &DigicloudSigner{
+		issuerSpec:               issuerSpec,
+		clusterResourceNamespace: clusterResourceNamespace,
+		client:                   client,
+		recorder:                 &record.FakeRecorder{},
 	}
+}
 
-	// Add new condition if not found
-	now := metav1.Now()
-	issuer.Status.Conditions = append(issuer.Status.Conditions, cmapi.IssuerCondition{
-		Type:               cmapi.IssuerConditionReady,
-		Status:             status,
-		Reason:             reason,
-		Message:            message,
-		LastTransitionTime: &now,
-	})
+// SetEventRecorder sets the recorder challenge and signing events are recorded on
+// CertificateRequests with. No events are recorded by default.
+func (s *DigicloudSigner) SetEventRecorder(recorder record.EventRecorder) {
+	if recorder == nil {
+		recorder = &record.FakeRecorder{}
+	}
+	s.recorder = recorder
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *DigicloudIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&digicloudv1alpha1.DigicloudIssuer{}).
-		Complete(r)
+// SetWatchdog sets the watchdog signing is tracked with, so that a signer worker
+// stuck signing fails the liveness check. Nothing is tracked by default.
+func (s *DigicloudSigner) SetWatchdog(watchdog *health.Watchdog) {
+	s.watchdog = watchdog
 }
 
-// DigicloudClusterIssuerReconciler reconciles a DigicloudClusterIssuer object
-type DigicloudClusterIssuerReconciler struct {
-	client.Client
-	Scheme *runtime.Scheme
+// SetNameservers sets the recursive nameservers that challenge propagation, zone
+// delegation and DNSSEC are checked with, instead of the nameservers in
+// /etc/resolv.conf. Live NS records are looked up with the first of them.
+func (s *DigicloudSigner) SetNameservers(nameservers []string) {
+	s.nameservers = nameservers
+	s.nsResolver = nil
+	if len(nameservers) > 0 {
+		s.nsResolver = dnsprovider.NewNSResolver(nameservers[0])
+	}
 }
 
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// SetRecursiveNameserversOnly makes challenge propagation be checked with the
+// recursive nameservers only, without querying the authoritative nameservers of
+// the zone, for clusters that cannot reach them
+func (s *DigicloudSigner) SetRecursiveNameserversOnly(recursiveOnly bool) {
+	s.recursiveOnly = recursiveOnly
+}
 
-// Reconcile is part of the main kubernetes reconciliation loop for cluster issuers
-func (r *DigicloudClusterIssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
+// SetClusterID sets the cluster ID stamped into the note of the challenge records
+// presented, so that only this cluster treats them as its own. The records are
+// noted with dnsprovider.RecordNote by default.
+func (s *DigicloudSigner) SetClusterID(clusterID string) {
+	s.clusterID = clusterID
+}
 
-	// Fetch the DigicloudClusterIssuer instance
-	var issuer digicloudv1alpha1.DigicloudClusterIssuer
-	if err := r.Get(ctx, req.NamespacedName, &issuer); err != nil {
-		if apierrors.IsNotFound(err) {
-			logger.Info("DigicloudClusterIssuer resource not found, ignoring since object must be deleted")
-			return ctrl.Result{}, nil
-		}
-		logger.Error(err, "Failed to get DigicloudClusterIssuer")
-		return ctrl.Result{}, err
-	}
+// Sign signs a certificate request using the Digicloud DNS provider for DNS01 challenges
+func (s *DigicloudSigner) Sign(ctx context.Context, cr signer.CertificateRequestObject, issuerObj client.Object) (signer.PEMBundle, error) {
+	defer s.watchdog.Track()()
 
-	// Initialize status conditions if not set
-	if issuer.Status.Conditions == nil {
-		issuer.Status.Conditions = []cmapi.IssuerCondition{}
+	// The request wrappers of issuer-lib deep copy to the CertificateRequest or
+	// CertificateSigningRequest they wrap, which events are recorded on
+	var object runtime.Object
+	if obj, ok := cr.(runtime.Object); ok {
+		object = obj.DeepCopyObject()
 	}
 
-	// Validate the cluster issuer configuration
-	if err := r.validateClusterIssuer(ctx, &issuer); err != nil {
-		logger.Error(err, "Invalid cluster issuer configuration")
-		r.setClusterReadyCondition(&issuer, "Failed", err.Error())
-		if statusErr := r.Status().Update(ctx, &issuer); statusErr != nil {
-			logger.Error(statusErr, "Failed to update status")
-			return ctrl.Result{}, statusErr
+	ctx = tracing.WithRequestUID(ctx, cr.GetUID())
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("requestUID", cr.GetUID()))
+	ctx, span := tracing.Start(ctx, "DigicloudSigner.Sign", trace.WithAttributes(
+		attribute.String("certificaterequest.namespace", cr.GetNamespace()),
+		attribute.String("certificaterequest.name", cr.GetName()),
+		attribute.String("issuer.kind", issuerKindOf(issuerObj)),
+		attribute.String("issuer.name", issuerObj.GetName()),
+	))
+
+	bundle, err := s.sign(ctx, cr, object, issuerObj)
+	tracing.End(span, err)
+	if err != nil {
+		reason := EventReasonSigningFailed
+		var conditionErr signer.SetCertificateRequestConditionError
+		if errors.As(err, &conditionErr) {
+			reason = conditionErr.Reason
 		}
-		return ctrl.Result{}, err
+		s.recorder.Eventf(object, corev1.EventTypeWarning, reason, "Failed to sign certificate: %v", err)
+		return bundle, err
+	}
+
+	s.recorder.Event(object, corev1.EventTypeNormal, EventReasonIssued, "Certificate issued")
+	return bundle, nil
+}
+
+// sign obtains a certificate for cr, recording challenge events on object
+func (s *DigicloudSigner) sign(ctx context.Context, cr signer.CertificateRequestObject, object runtime.Object, issuerObj client.Object) (signer.PEMBundle, error) {
+	logger := log.FromContext(ctx)
+
+	template, _, csrPEM, err := cr.GetRequest()
+	if err != nil {
+		return signer.PEMBundle{}, signer.PermanentError{Err: fmt.Errorf("failed to parse certificate request: %w", err)}
+	}
+	csr, err := parseCSR(csrPEM)
+	if err != nil {
+		return signer.PEMBundle{}, signer.PermanentError{Err: err}
 	}
 
-	// Set ready condition
-	r.setClusterReadyCondition(&issuer, "Checked", "Cluster issuer configuration is valid")
-	if err := r.Status().Update(ctx, &issuer); err != nil {
-		logger.Error(err, "Failed to update status")
-		return ctrl.Result{}, err
+	dnsNames, err := requestedDNSNames(template)
+	if err != nil {
+		return signer.PEMBundle{}, err
+	}
+	if err := s.checkPolicy(ctx, cr, issuerObj, dnsNames); err != nil {
+		return signer.PEMBundle{}, err
 	}
 
-	logger.Info("DigicloudClusterIssuer reconciled successfully")
-	return ctrl.Result{}, nil
-}
+	// Create a DNS provider for the solver selected for each DNS name
+	providers, err := s.newProviderSet(ctx, issuerObj, dnsNames, cr.GetLabels())
+	if err != nil {
+		return signer.PEMBundle{}, err
+	}
 
-// validateClusterIssuer validates the cluster issuer configuration
-func (r *DigicloudClusterIssuerReconciler) validateClusterIssuer(ctx context.Context, issuer *digicloudv1alpha1.DigicloudClusterIssuer) error {
-	// For cluster issuers, we need to look for secrets in a specific namespace
-	// This is typically controlled by configuration, but for now we'll use a default
-	secretNamespace := "digicloud-issuer-system" // TODO: Make this configurable
+	providers.SetContext(ctx)
+	logger.Info("Digicloud signer created successfully")
 
-	secretName := issuer.Spec.Provisioner.APITokenSecretRef.Name
-	secretKey := issuer.Spec.Provisioner.APITokenSecretRef.Key
+	targets, err := s.preflight(ctx, providers, dnsNames)
+	if recordErr := s.recordDNSSEC(ctx, issuerObj, targets, err); recordErr != nil {
+		logger.Error(recordErr, "Failed to record DNSSEC condition in issuer status")
+	}
+	if err != nil {
+		return signer.PEMBundle{}, err
+	}
+	s.recordZones(object, targets)
+	if err := s.recordChallengeDelegations(ctx, issuerObj, targets); err != nil {
+		logger.Error(err, "Failed to record challenge delegations in issuer status")
+	}
 
-	if secretName == "" || secretKey == "" {
-		return fmt.Errorf("API token secret reference must specify both name and key")
+	if err := s.checkCAA(ctx, providers, dnsNames); err != nil {
+		return signer.PEMBundle{}, err
 	}
 
-	// Check if the secret exists
-	var secret corev1.Secret
-	secretNamespacedName := types.NamespacedName{
-		Name:      secretName,
-		Namespace: secretNamespace,
+	acmeClient, err := s.newACMEClient(ctx, issuerObj)
+	if err != nil {
+		return signer.PEMBundle{}, err
 	}
 
-	if err := r.Get(ctx, secretNamespacedName, &secret); err != nil {
-		if apierrors.IsNotFound(err) {
-			return fmt.Errorf("API token secret %s not found in namespace %s", secretName, secretNamespace)
+	// Challenge records are tracked in the issuer status so that they can be cleaned
+	// up if the issuer or the request is deleted mid-challenge
+	if err := s.addRequestFinalizer(ctx, object); err != nil {
+		return signer.PEMBundle{}, err
+	}
+	solvers := s.challengeSolvers(dnsNames, cr.GetLabels())
+	challenges := newChallengeRecorder(ctx, providers, s.recorder, object)
+	challenges.onPresent = func(domain, value string) {
+		if record, ok := newChallengeRecord(cr.GetUID(), targets, solvers, domain, value); ok {
+			if err := s.addChallengeRecord(ctx, issuerObj, record); err != nil {
+				logger.Error(err, "Failed to record challenge record in issuer status")
+			}
 		}
-		return fmt.Errorf("failed to get API token secret: %w", err)
+	}
+	challenges.onCleanUp = func(domain, value string) {
+		if record, ok := newChallengeRecord(cr.GetUID(), targets, solvers, domain, value); ok {
+			if err := s.removeChallengeRecord(ctx, issuerObj, record); err != nil {
+				logger.Error(err, "Failed to remove challenge record from issuer status")
+			}
+		}
+	}
+	err = acmeClient.SetDNS01Provider(challenges,
+		dns01.WrapPreCheck(challenges.PreCheck),
+		dns01.CondOption(len(s.nameservers) > 0, dns01.AddRecursiveNameservers(s.nameservers)),
+		dns01.CondOption(s.recursiveOnly, dns01.DisableCompletePropagationRequirement()),
+	)
+	if err != nil {
+		return signer.PEMBundle{}, fmt.Errorf("failed to set DNS01 provider: %w", err)
 	}
 
-	// Check if the secret contains the specified key
-	if _, exists := secret.Data[secretKey]; !exists {
-		return fmt.Errorf("API token secret %s does not contain key %s", secretName, secretKey)
+	s.recorder.Eventf(object, corev1.EventTypeNormal, EventReasonOrderCreated, "Ordering certificate from %s", s.getACMEServer())
+	chainPEM, caPEM, err := acmeClient.Obtain(ctx, csr)
+	if err := s.releaseRequest(ctx, issuerObj, object); err != nil {
+		logger.Error(err, "Failed to clean up challenge records of request")
+	}
+	if err := s.recordACMEAccount(ctx, issuerObj, acmeClient); err != nil {
+		logger.Error(err, "Failed to record ACME account in issuer status")
+	}
+	if err != nil {
+		metrics.ACMEOrders.WithLabelValues(metrics.ResultFailed).Inc()
+		challenges.recordTimeouts(err)
+		s.recorder.Eventf(object, corev1.EventTypeWarning, EventReasonOrderFailed, "ACME order failed: %v", err)
+		if errors.Is(err, acme.ErrTermsOfServiceNotAgreed) {
+			return signer.PEMBundle{}, signingFailedError(err, ReasonTermsOfServiceNotAgreed)
+		}
+		return signer.PEMBundle{}, fmt.Errorf("failed to obtain certificate: %w", err)
 	}
 
-	return nil
+	metrics.ACMEOrders.WithLabelValues(metrics.ResultIssued).Inc()
+	logger.Info("Certificate obtained from ACME server", "server", s.getACMEServer())
+	return signer.PEMBundle{ChainPEM: chainPEM, CAPEM: caPEM}, nil
 }
 
-// setClusterReadyCondition sets the Ready condition on the cluster issuer
-func (r *DigicloudClusterIssuerReconciler) setClusterReadyCondition(issuer *digicloudv1alpha1.DigicloudClusterIssuer, reason, message string) {
-	status := cmmeta.ConditionTrue
-	if reason == "Failed" {
-		status = cmmeta.ConditionFalse
-	}
-
-	// Find existing condition
-	for i, condition := range issuer.Status.Conditions {
-		if condition.Type == cmapi.IssuerConditionReady {
-			issuer.Status.Conditions[i].Status = status
-			issuer.Status.Conditions[i].Reason = reason
-			issuer.Status.Conditions[i].Message = message
-			now := metav1.Now()
-			issuer.Status.Conditions[i].LastTransitionTime = &now
-			return
+// recordZones records the Digicloud zone the challenge of each DNS name is solved in
+func (s *DigicloudSigner) recordZones(object runtime.Object, targets map[string]*dnsprovider.ChallengeTarget) {
+	dnsNames := make([]string, 0, len(targets))
+	for dnsName := range targets {
+		dnsNames = append(dnsNames, dnsName)
+	}
+	sort.Strings(dnsNames)
+
+	for _, dnsName := range dnsNames {
+		target := targets[dnsName]
+		if target.Delegated() {
+			s.recorder.Eventf(object, corev1.EventTypeNormal, EventReasonZoneResolved,
+				"Challenge for %s is CNAME-delegated to %s in zone %s", dnsName, target.EffectiveFQDN, target.Zone)
+		} else {
+			s.recorder.Eventf(object, corev1.EventTypeNormal, EventReasonZoneResolved,
+				"Challenge for %s is solved in zone %s", dnsName, target.Zone)
 		}
 	}
-
-	// Add new condition if not found
-	now := metav1.Now()
-	issuer.Status.Conditions = append(issuer.Status.Conditions, cmapi.IssuerCondition{
-		Type:               cmapi.IssuerConditionReady,
-		Status:             status,
-		Reason:             reason,
-		Message:            message,
-		LastTransitionTime: &now,
-	})
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *DigicloudClusterIssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&digicloudv1alpha1.DigicloudClusterIssuer{}).
-		Complete(r)
+// preflight verifies that the zones the challenge records of all requested DNS names
+// are written to are delegated to Digicloud and have an intact DNSSEC chain of trust,
+// so that a misconfigured zone fails the request before any challenge is presented
+// instead of timing out during ACME validation. It returns the challenge target of
+// each requested name, keyed by the name without a wildcard prefix.
+func (s *DigicloudSigner) preflight(ctx context.Context, providers *dnsprovider.ProviderSet, dnsNames []string) (map[string]*dnsprovider.ChallengeTarget, error) {
+	targets := make(map[string]*dnsprovider.ChallengeTarget, len(dnsNames))
+	checked := make(map[string]bool, len(dnsNames))
+	for _, dnsName := range dnsNames {
+		dnsName = dnsname.Key(dnsName)
+		if targets[dnsName] != nil {
+			continue
+		}
+
+		provider, err := providers.For(dnsName)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := provider.ResolveChallenge(ctx, dnsName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve challenge target for %s: %w", dnsName, err)
+		}
+		targets[dnsName] = target
+
+		if checked[target.Zone] {
+			continue
+		}
+		checked[target.Zone] = true
+
+		err = provider.CheckDelegation(ctx, target.Zone)
+		var notDelegated *dnsprovider.ZoneNotDelegatedError
+		if errors.As(err, &notDelegated) {
+			return nil, signingFailedError(err, ReasonZoneNotDelegated)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check NS delegation for %s: %w", dnsName, err)
+		}
+
+		err = provider.CheckDNSSEC(ctx, target.Zone)
+		var dnssecMismatch *dnsprovider.DNSSECMismatchError
+		if errors.As(err, &dnssecMismatch) {
+			return nil, signingFailedError(err, ReasonDNSSECMismatch)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check DNSSEC for %s: %w", dnsName, err)
+		}
+	}
+
+	return targets, nil
 }
 
-// DigicloudSigner implements the cert-manager issuer-lib signer interface
-type DigicloudSigner struct {
-	issuerSpec      digicloudv1alpha1.DigicloudIssuerProvisioner
-	secretNamespace string
-	client          client.Client
+// recordChallengeDelegations updates the CNAME-delegated challenges reported in the
+// issuer status with the challenge targets resolved for a request
+func (s *DigicloudSigner) recordChallengeDelegations(ctx context.Context, issuerObj client.Object, targets map[string]*dnsprovider.ChallengeTarget) error {
+	return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+		issuer.SetChallengeDelegations(mergeChallengeDelegations(issuer.GetChallengeDelegations(), targets))
+	})
 }
 
-// NewDigicloudSigner creates a new Digicloud signer
-func NewDigicloudSigner(client client.Client, issuerSpec digicloudv1alpha1.DigicloudIssuerProvisioner, secretNamespace string) *DigicloudSigner {
-	return &DigicloudSigner{
-		issuerSpec:      issuerSpec,
-		secretNamespace: secretNamespace,
-		client:          client,
+// mergeChallengeDelegations replaces the delegations of the DNS names in targets,
+// dropping names whose challenge is no longer delegated
+func mergeChallengeDelegations(existing []digicloudv1alpha1.ChallengeDelegation, targets map[string]*dnsprovider.ChallengeTarget) []digicloudv1alpha1.ChallengeDelegation {
+	var merged []digicloudv1alpha1.ChallengeDelegation
+	for _, delegation := range existing {
+		if _, updated := targets[delegation.DNSName]; !updated {
+			merged = append(merged, delegation)
+		}
+	}
+	for dnsName, target := range targets {
+		if target.Delegated() {
+			merged = append(merged, digicloudv1alpha1.ChallengeDelegation{
+				DNSName:       dnsName,
+				ChallengeFQDN: target.FQDN,
+				Target:        target.EffectiveFQDN,
+				Zone:          target.Zone,
+			})
+		}
 	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].DNSName < merged[j].DNSName })
+	return merged
 }
 
-// Sign signs a certificate request using the Digicloud DNS provider for DNS01 challenges
-func (s *DigicloudSigner) Sign(ctx context.Context, cr signer.CertificateRequestObject, issuerObj client.Object) (signer.PEMBundle, error) {
-	logger := log.FromContext(ctx)
-
-	// Get the API token from the secret
-	apiToken, namespace, err := s.getAPIToken(ctx, issuerObj)
+// checkCAA verifies that the CAA records of all requested DNS names authorize the
+// ACME server, so that a forbidden name fails the request before any challenge is
+// presented. Names are checked as requested since wildcards are governed by issuewild.
+func (s *DigicloudSigner) checkCAA(ctx context.Context, providers *dnsprovider.ProviderSet, dnsNames []string) error {
+	caaIdentities, err := acme.CAAIdentities(ctx, nil, s.getACMEServer())
 	if err != nil {
-		return signer.PEMBundle{}, fmt.Errorf("failed to get API token: %w", err)
+		return fmt.Errorf("failed to get CAA identities of ACME server: %w", err)
 	}
 
-	// TODO: Get the namespace from the issuer configuration
-	digicloudNamespace := namespace // This should be configured in the issuer spec
-
-	// Create the DNS provider
-	_ = dnsprovider.NewDigicloudProvider(
-		s.issuerSpec.APIBaseURL,
-		apiToken,
-		digicloudNamespace,
-		s.getTTL(),
-	)
+	for _, dnsName := range dnsNames {
+		provider, err := providers.For(dnsName)
+		if err != nil {
+			return err
+		}
 
-	logger.Info("Digicloud signer created successfully")
+		err = provider.CheckCAA(ctx, dnsName, caaIdentities)
+		var caaForbidden *dnsprovider.CAAForbiddenError
+		if errors.As(err, &caaForbidden) {
+			return signingFailedError(err, ReasonCAAForbidden)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check CAA for %s: %w", dnsName, err)
+		}
+	}
 
-	// TODO: Implement actual certificate signing logic using ACME with DNS01 challenges
-	// This would involve:
-	// 1. Creating an ACME client
-	// 2. Registering the Digicloud DNS provider for DNS01 challenges
-	// 3. Requesting a certificate from the ACME server
-	// 4. Returning the signed certificate
+	return nil
+}
 
-	// For now, return an error indicating this is not yet implemented
-	return signer.PEMBundle{}, fmt.Errorf("certificate signing not yet implemented")
+// signingFailedError fails the CertificateRequest permanently, setting its
+// SigningFailed condition with reason
+func signingFailedError(err error, reason string) error {
+	return signer.PermanentError{Err: signer.SetCertificateRequestConditionError{
+		Err:           err,
+		ConditionType: CertificateRequestConditionSigningFailed,
+		Status:        cmmeta.ConditionTrue,
+		Reason:        reason,
+	}}
 }
 
 // getAPIToken retrieves the API token from the Kubernetes secret
-func (s *DigicloudSigner) getAPIToken(ctx context.Context, issuerObj client.Object) (string, string, error) {
-	secretName := s.issuerSpec.APITokenSecretRef.Name
-	secretKey := s.issuerSpec.APITokenSecretRef.Key
+func (s *DigicloudSigner) getAPIToken(ctx context.Context, issuerObj client.Object, ref digicloudv1alpha1.SecretKeySelector) (string, string, error) {
+	secretName := ref.Name
+	secretKey := ref.Key
 
-	var secretNamespace string
-	if s.secretNamespace != "" {
-		secretNamespace = s.secretNamespace
-	} else {
-		// For namespaced issuers, use the issuer's namespace
-		secretNamespace = issuerObj.GetNamespace()
-	}
+	secretNamespace := issuerSecretNamespace(issuerObj, s.clusterResourceNamespace)
 
 	var secret corev1.Secret
 	secretNamespacedName := types.NamespacedName{
@@ -380,3 +519,113 @@ func (s *DigicloudSigner) getTTL() int {
 	}
 	return 300 // Default TTL
 }
+
+// getPropagationTimeout returns how long and how often DNS propagation is checked
+func (s *DigicloudSigner) getPropagationTimeout() (time.Duration, time.Duration) {
+	timeout, interval := 5*time.Minute, 10*time.Second
+	if s.issuerSpec.PropagationTimeout != nil {
+		timeout = s.issuerSpec.PropagationTimeout.Duration
+	}
+	if s.issuerSpec.PollingInterval != nil {
+		interval = s.issuerSpec.PollingInterval.Duration
+	}
+	return timeout, interval
+}
+
+// getACMEServer returns the ACME directory URL certificates are ordered from
+func (s *DigicloudSigner) getACMEServer() string {
+	if s.issuerSpec.ACME != nil && s.issuerSpec.ACME.Server != "" {
+		return s.issuerSpec.ACME.Server
+	}
+	return acme.DefaultServer
+}
+
+// newACMEClient creates an ACME client for the issuer's account
+func (s *DigicloudSigner) newACMEClient(ctx context.Context, issuerObj client.Object) (*acme.Client, error) {
+	key, err := s.getACMEAccountKey(ctx, issuerObj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ACME account key: %w", err)
+	}
+
+	opts := acme.Options{DirectoryURL: s.getACMEServer(), Key: key}
+	if s.issuerSpec.ACME != nil {
+		opts.Email = s.issuerSpec.ACME.Email
+		opts.TermsOfServiceAgreed = s.issuerSpec.ACME.TermsOfServiceAgreed
+	}
+	return acme.NewClient(ctx, opts)
+}
+
+// acmeAccountKeyRef returns the secret and key holding the issuer's ACME account key
+func (s *DigicloudSigner) acmeAccountKeyRef(issuerObj client.Object) (types.NamespacedName, string) {
+	name := types.NamespacedName{
+		Name:      issuerObj.GetName() + "-acme-account-key",
+		Namespace: issuerSecretNamespace(issuerObj, s.clusterResourceNamespace),
+	}
+	key := corev1.TLSPrivateKeyKey
+	if s.issuerSpec.ACME != nil && s.issuerSpec.ACME.PrivateKeySecretRef != nil {
+		name.Name = s.issuerSpec.ACME.PrivateKeySecretRef.Name
+		key = s.issuerSpec.ACME.PrivateKeySecretRef.Key
+	}
+	return name, key
+}
+
+// getACMEAccountKey loads the ACME account key from its secret, generating and
+// storing a new key if the secret does not exist yet
+func (s *DigicloudSigner) getACMEAccountKey(ctx context.Context, issuerObj client.Object) (crypto.PrivateKey, error) {
+	name, secretKey := s.acmeAccountKeyRef(issuerObj)
+	secretName, secretNamespace := name.Name, name.Namespace
+
+	var secret corev1.Secret
+	err := s.client.Get(ctx, name, &secret)
+	if err == nil {
+		return parseACMEAccountKey(&secret, secretKey)
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", secretNamespace, secretName, err)
+	}
+
+	key, keyPEM, err := acme.GenerateAccountKey()
+	if err != nil {
+		return nil, err
+	}
+
+	secret = corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: secretNamespace},
+		Data:       map[string][]byte{secretKey: keyPEM},
+	}
+	if err := s.client.Create(ctx, &secret); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create secret %s/%s: %w", secretNamespace, secretName, err)
+		}
+		// Another request for the issuer stored its key first, which is used instead
+		if err := s.client.Get(ctx, name, &secret); err != nil {
+			return nil, fmt.Errorf("failed to get secret %s/%s: %w", secretNamespace, secretName, err)
+		}
+		return parseACMEAccountKey(&secret, secretKey)
+	}
+
+	return key, nil
+}
+
+// parseACMEAccountKey parses the ACME account key stored in secret under secretKey
+func parseACMEAccountKey(secret *corev1.Secret, secretKey string) (crypto.PrivateKey, error) {
+	keyBytes, exists := secret.Data[secretKey]
+	if !exists {
+		return nil, fmt.Errorf("secret %s/%s does not contain key %s", secret.Namespace, secret.Name, secretKey)
+	}
+	return acme.ParseAccountKey(keyBytes)
+}
+
+// parseCSR decodes a PEM encoded certificate signing request
+func parseCSR(csrPEM []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode certificate request PEM")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate request: %w", err)
+	}
+	return csr, nil
+}
@@ -2,19 +2,47 @@ package controllers
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
 
 	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
 )
 
 func TestDigicloudIssuerReconciler_Reconcile(t *testing.T) {
@@ -35,9 +63,10 @@ func TestDigicloudIssuerReconciler_Reconcile(t *testing.T) {
 		Build()
 
 	// Create reconciler
-	reconciler := &DigicloudIssuerReconciler{
-		Client: fakeClient,
-		Scheme: scheme,
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		ForObject: &v1alpha1.DigicloudIssuer{},
 	}
 
 	// Test reconciliation with non-existent resource
@@ -72,9 +101,10 @@ func TestDigicloudIssuerReconciler_SetupWithManager(t *testing.T) {
 		Build()
 
 	// Create reconciler
-	reconciler := &DigicloudIssuerReconciler{
-		Client: fakeClient,
-		Scheme: scheme,
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		ForObject: &v1alpha1.DigicloudIssuer{},
 	}
 
 	// Test that reconciler was created successfully
@@ -97,9 +127,10 @@ func TestDigicloudClusterIssuerReconciler_Reconcile(t *testing.T) {
 		Build()
 
 	// Create reconciler
-	reconciler := &DigicloudClusterIssuerReconciler{
-		Client: fakeClient,
-		Scheme: scheme,
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		ForObject: &v1alpha1.DigicloudClusterIssuer{},
 	}
 
 	// Test reconciliation with non-existent resource
@@ -117,3 +148,455 @@ func TestDigicloudClusterIssuerReconciler_Reconcile(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, result.Requeue)
 }
+
+type fakeNSResolver struct {
+	hosts []string
+}
+
+func (r *fakeNSResolver) LookupNS(_ context.Context, _ string) ([]*net.NS, error) {
+	var nameservers []*net.NS
+	for _, host := range r.hosts {
+		nameservers = append(nameservers, &net.NS{Host: host})
+	}
+	return nameservers, nil
+}
+
+func newTestCertificateRequest(t *testing.T, dnsNames ...string) *cmapi.CertificateRequest {
+	t.Helper()
+	return newTestCertificateRequestFor(t, &x509.CertificateRequest{DNSNames: dnsNames})
+}
+
+// newTestCertificateRequestFor returns a CertificateRequest with a CSR for template
+func newTestCertificateRequestFor(t *testing.T, template *x509.CertificateRequest) *cmapi.CertificateRequest {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	assert.NoError(t, err)
+
+	return &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-request", Namespace: "default"},
+		Spec: cmapi.CertificateRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		},
+	}
+}
+
+func TestDigicloudSigner_Sign_ZoneNotDelegated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/edge/domains/example.com/verify-ns-records":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/edge/domains/example.com/ns-records":
+			_, _ = w.Write([]byte(`{"digicloud_ns_records":["ns1.digicloud.ir","ns2.digicloud.ir"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:        server.URL,
+				APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+			},
+		},
+	}
+	cr := newTestCertificateRequest(t, "example.com", "*.example.com")
+	cr.Spec.IssuerRef = cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group}
+	fakeClient := newSigningClient(t, issuer, cr, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("test-token")},
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	s.SetEventRecorder(recorder)
+	s.nsResolver = &fakeNSResolver{hosts: []string{"a.iana-servers.net.", "b.iana-servers.net."}}
+
+	signed := reconcileSigning(t, fakeClient, s.Sign, cr)
+
+	ready := certificateRequestCondition(signed, cmapi.CertificateRequestConditionReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, cmmeta.ConditionFalse, ready.Status)
+	assert.Equal(t, cmapi.CertificateRequestReasonFailed, ready.Reason)
+	failed := certificateRequestCondition(signed, CertificateRequestConditionSigningFailed)
+	require.NotNil(t, failed)
+	assert.Equal(t, cmmeta.ConditionTrue, failed.Status)
+	assert.Equal(t, ReasonZoneNotDelegated, failed.Reason)
+	assert.Contains(t, failed.Message, "ns1.digicloud.ir, ns2.digicloud.ir")
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning "+ReasonZoneNotDelegated)
+}
+
+func TestDigicloudSigner_Sign_Traced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/edge/domains/example.com/verify-ns-records":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/edge/domains/example.com/ns-records":
+			_, _ = w.Write([]byte(`{"digicloud_ns_records":["ns1.digicloud.ir","ns2.digicloud.ir"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("test-token")},
+		}).
+		Build()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:        server.URL,
+				APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+			},
+		},
+	}
+
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	s.nsResolver = &fakeNSResolver{hosts: []string{"a.iana-servers.net."}}
+
+	cr := newTestCertificateRequest(t, "example.com")
+	cr.UID = "request-uid"
+	_, err := s.Sign(context.Background(), signer.CertificateRequestObjectFromCertificateRequest(cr), issuer)
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	sign := spans[len(spans)-1]
+	assert.Equal(t, "DigicloudSigner.Sign", sign.Name)
+	assert.Equal(t, codes.Error, sign.Status.Code)
+	assert.Equal(t, "Digicloud API GET /v1/edge/domains/{domain}/verify-ns-records", spans[0].Name)
+	assert.Equal(t, "Digicloud API GET /v1/edge/domains/{domain}/ns-records", spans[1].Name)
+	for _, span := range spans {
+		assert.Equal(t, sign.SpanContext.TraceID(), span.SpanContext.TraceID())
+		assert.Contains(t, span.Attributes, tracing.RequestUIDKey.String("request-uid"))
+	}
+	for _, span := range spans[:2] {
+		assert.Equal(t, sign.SpanContext.SpanID(), span.Parent.SpanID())
+	}
+}
+
+func TestDigicloudSigner_Sign_CAAForbidden(t *testing.T) {
+	var recordsCreated bool
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/edge/domains/example.com/verify-ns-records":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/edge/domains/example.com/ns-records":
+			_, _ = w.Write([]byte(`{"digicloud_ns_records":["ns1.digicloud.ir","ns2.digicloud.ir"]}`))
+		case "/v1/edge/domains/example.com/dnssec":
+			_, _ = w.Write([]byte(`{"dnssec":false}`))
+		case "/v1/edge/domains/example.com/records":
+			recordsCreated = recordsCreated || r.Method == http.MethodPost
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	var directory *httptest.Server
+	directory = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"newNonce":   directory.URL + "/new-nonce",
+			"newAccount": directory.URL + "/new-account",
+			"newOrder":   directory.URL + "/new-order",
+			"meta":       map[string]any{"caaIdentities": []string{"letsencrypt.org"}},
+		})
+	}))
+	defer directory.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	nameserver := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if q := r.Question[0]; q.Qtype == dns.TypeCAA && q.Name == "example.com." {
+			m.Answer = []dns.RR{
+				&dns.CAA{
+					Hdr:   dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 300},
+					Tag:   "issue",
+					Value: "letsencrypt.org",
+				},
+				&dns.CAA{
+					Hdr:   dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 300},
+					Tag:   "issuewild",
+					Value: ";",
+				},
+			}
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = nameserver.ActivateAndServe() }()
+	defer func() { _ = nameserver.Shutdown() }()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:        api.URL,
+				APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				ACME:              &v1alpha1.DigicloudIssuerACME{Server: directory.URL},
+			},
+		},
+	}
+	cr := newTestCertificateRequest(t, "example.com", "*.example.com")
+	cr.Spec.IssuerRef = cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group}
+	fakeClient := newSigningClient(t, issuer, cr, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("test-token")},
+	})
+
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	s.nsResolver = &fakeNSResolver{hosts: []string{"ns1.digicloud.ir.", "ns2.digicloud.ir."}}
+	s.nameservers = []string{conn.LocalAddr().String()}
+
+	signed := reconcileSigning(t, fakeClient, s.Sign, cr)
+
+	ready := certificateRequestCondition(signed, cmapi.CertificateRequestConditionReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, cmmeta.ConditionFalse, ready.Status)
+	failed := certificateRequestCondition(signed, CertificateRequestConditionSigningFailed)
+	require.NotNil(t, failed)
+	assert.Equal(t, cmmeta.ConditionTrue, failed.Status)
+	assert.Equal(t, ReasonCAAForbidden, failed.Reason)
+	assert.Contains(t, failed.Message, "*.example.com")
+	assert.False(t, recordsCreated, "no TXT record may be created when CAA forbids issuance")
+
+	// CAA is checked before the ACME account key is loaded or generated
+	var accountKey corev1.Secret
+	err = fakeClient.Get(context.Background(), types.NamespacedName{Name: "test-issuer-acme-account-key", Namespace: "default"}, &accountKey)
+	assert.True(t, apierrors.IsNotFound(err), "the CAA preflight must not create the ACME account key")
+}
+
+func TestDigicloudSigner_Sign_DNSSECMismatch(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/edge/domains/example.com/verify-ns-records":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/edge/domains/example.com/ns-records":
+			_, _ = w.Write([]byte(`{"digicloud_ns_records":["ns1.digicloud.ir","ns2.digicloud.ir"]}`))
+		case "/v1/edge/domains/example.com/dnssec":
+			_, _ = w.Write([]byte(`{"dnssec":true,"ds":"12345 13 2 ` + strings.Repeat("AB", 32) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	// The parent zone still publishes the DS of a previous key
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	nameserver := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if q := r.Question[0]; q.Qtype == dns.TypeDS && q.Name == "example.com." {
+			m.Answer = []dns.RR{&dns.DS{
+				Hdr:        dns.RR_Header{Name: q.Name, Rrtype: dns.TypeDS, Class: dns.ClassINET, Ttl: 300},
+				KeyTag:     54321,
+				Algorithm:  dns.ECDSAP256SHA256,
+				DigestType: dns.SHA256,
+				Digest:     strings.Repeat("CD", 32),
+			}}
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = nameserver.ActivateAndServe() }()
+	defer func() { _ = nameserver.Shutdown() }()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:        api.URL,
+				APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+			},
+		},
+	}
+	cr := newTestCertificateRequest(t, "www.example.com")
+	cr.Spec.IssuerRef = cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: v1alpha1.GroupVersion.Group}
+	fakeClient := newSigningClient(t, issuer, cr, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("test-token")},
+	})
+
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	s.nsResolver = &fakeNSResolver{hosts: []string{"ns1.digicloud.ir.", "ns2.digicloud.ir."}}
+	s.nameservers = []string{conn.LocalAddr().String()}
+
+	signed := reconcileSigning(t, fakeClient, s.Sign, cr)
+
+	ready := certificateRequestCondition(signed, cmapi.CertificateRequestConditionReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, cmmeta.ConditionFalse, ready.Status)
+	failed := certificateRequestCondition(signed, CertificateRequestConditionSigningFailed)
+	require.NotNil(t, failed)
+	assert.Equal(t, cmmeta.ConditionTrue, failed.Status)
+	assert.Equal(t, ReasonDNSSECMismatch, failed.Reason)
+	assert.Contains(t, failed.Message, "54321 13 2")
+
+	// The mismatch is also reported on the issuer, where it outlives the request
+	var updated v1alpha1.DigicloudIssuer
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), &updated))
+	var condition *cmapi.IssuerCondition
+	for i := range updated.Status.Conditions {
+		if updated.Status.Conditions[i].Type == IssuerConditionDNSSEC {
+			condition = &updated.Status.Conditions[i]
+		}
+	}
+	require.NotNil(t, condition)
+	assert.Equal(t, cmmeta.ConditionFalse, condition.Status)
+	assert.Equal(t, ReasonDNSSECMismatch, condition.Reason)
+	assert.Contains(t, condition.Message, "54321 13 2")
+}
+
+func TestDigicloudSigner_RecordDNSSEC(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	issuer := &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"}}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		Build()
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+
+	dnssecCondition := func() *cmapi.IssuerCondition {
+		var updated v1alpha1.DigicloudIssuer
+		require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), &updated))
+		for _, condition := range updated.Status.Conditions {
+			if condition.Type == IssuerConditionDNSSEC {
+				return &condition
+			}
+		}
+		return nil
+	}
+
+	// Preflight failures unrelated to DNSSEC do not set the condition
+	require.NoError(t, s.recordDNSSEC(context.Background(), issuer, nil, errors.New("zone not delegated")))
+	assert.Nil(t, dnssecCondition())
+
+	mismatch := signingFailedError(&dnsprovider.DNSSECMismatchError{Zone: "example.com", Message: "stale DS"}, ReasonDNSSECMismatch)
+	require.NoError(t, s.recordDNSSEC(context.Background(), issuer, nil, mismatch))
+	condition := dnssecCondition()
+	require.NotNil(t, condition)
+	assert.Equal(t, cmmeta.ConditionFalse, condition.Status)
+	assert.Equal(t, "DNSSEC validation for zone example.com failed: stale DS", condition.Message)
+
+	require.NoError(t, s.recordDNSSEC(context.Background(), issuer, map[string]*dnsprovider.ChallengeTarget{
+		"www.example.com": {Zone: "example.com"},
+		"example.com":     {Zone: "example.com"},
+		"www.example.org": {Zone: "example.org"},
+	}, nil))
+	condition = dnssecCondition()
+	require.NotNil(t, condition)
+	assert.Equal(t, cmmeta.ConditionTrue, condition.Status)
+	assert.Equal(t, ReasonDNSSECChecked, condition.Reason)
+	assert.Equal(t, "DNSSEC chain of trust is intact or DNSSEC is disabled for example.com, example.org", condition.Message)
+}
+
+func TestDigicloudSigner_GetACMEAccountKey(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	issuer := &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"}}
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+
+	// The key is generated and stored on first use, and read back afterwards
+	key, err := s.getACMEAccountKey(context.Background(), issuer)
+	require.NoError(t, err)
+	var secret corev1.Secret
+	require.NoError(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "test-issuer-acme-account-key", Namespace: "default"}, &secret))
+	assert.NotEmpty(t, secret.Data[corev1.TLSPrivateKeyKey])
+
+	again, err := s.getACMEAccountKey(context.Background(), issuer)
+	require.NoError(t, err)
+	assert.Equal(t, key, again)
+}
+
+func TestDigicloudSigner_GetACMEAccountKey_ConcurrentCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	// Another request for the issuer stores its key between the Get and the Create
+	winner, winnerPEM, err := acme.GenerateAccountKey()
+	require.NoError(t, err)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if err := c.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: obj.GetName(), Namespace: obj.GetNamespace()},
+					Data:       map[string][]byte{corev1.TLSPrivateKeyKey: winnerPEM},
+				}); err != nil {
+					return err
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	issuer := &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"}}
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+
+	key, err := s.getACMEAccountKey(context.Background(), issuer)
+	require.NoError(t, err)
+	assert.Equal(t, winner, key)
+}
+
+func TestDigicloudSigner_RecordChallengeDelegations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	issuer := &v1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-cluster-issuer"},
+		Status: v1alpha1.DigicloudClusterIssuerStatus{
+			ChallengeDelegations: []v1alpha1.ChallengeDelegation{
+				{DNSName: "old.example.com", ChallengeFQDN: "_acme-challenge.old.example.com.", Target: "old.validation.ir.", Zone: "validation.ir"},
+				{DNSName: "other.example.com", ChallengeFQDN: "_acme-challenge.other.example.com.", Target: "other.validation.ir.", Zone: "validation.ir"},
+			},
+		},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		Build()
+
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	err := s.recordChallengeDelegations(context.Background(), issuer, map[string]*dnsprovider.ChallengeTarget{
+		"www.example.com": {FQDN: "_acme-challenge.www.example.com.", EffectiveFQDN: "www.validation.ir.", Zone: "validation.ir"},
+		"old.example.com": {FQDN: "_acme-challenge.old.example.com.", EffectiveFQDN: "_acme-challenge.old.example.com.", Zone: "example.com"},
+	})
+	require.NoError(t, err)
+
+	var updated v1alpha1.DigicloudClusterIssuer
+	require.NoError(t, fakeClient.Get(context.Background(), types.NamespacedName{Name: "test-cluster-issuer"}, &updated))
+	assert.Equal(t, []v1alpha1.ChallengeDelegation{
+		{DNSName: "other.example.com", ChallengeFQDN: "_acme-challenge.other.example.com.", Target: "other.validation.ir.", Zone: "validation.ir"},
+		{DNSName: "www.example.com", ChallengeFQDN: "_acme-challenge.www.example.com.", Target: "www.validation.ir.", Zone: "validation.ir"},
+	}, updated.Status.ChallengeDelegations)
+}
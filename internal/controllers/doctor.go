@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// Checks run by Diagnose
+const (
+	CheckIssuerReady     = "Issuer ready"
+	CheckSecrets         = "API token secrets"
+	CheckAPI             = "Digicloud API"
+	CheckACMEServer      = "ACME server"
+	CheckChallengeTarget = "Challenge target"
+	CheckZone            = "Zone"
+	CheckDelegation      = "NS delegation"
+	CheckDNSSEC          = "DNSSEC"
+	CheckCAA             = "CAA"
+	CheckResolvers       = "Resolvers"
+	CheckTXTRecord       = "TXT record"
+	CheckTXTCleanUp      = "TXT record clean up"
+)
+
+// DiagnosticResult is the outcome of one check run by Diagnose
+type DiagnosticResult struct {
+	// Check is the name of the check
+	Check string
+
+	// Subject is what the check ran against, such as an account or a DNS name
+	Subject string
+
+	// Message describes the outcome of a passed check
+	Message string
+
+	// Err is why the check failed, or nil if it passed
+	Err error
+
+	// Hint suggests how to fix a failed check
+	Hint string
+}
+
+// Passed reports whether the check passed
+func (r DiagnosticResult) Passed() bool {
+	return r.Err == nil
+}
+
+// diagnosticAccount is a Digicloud account of an issuer as checked by Diagnose
+type diagnosticAccount struct {
+	name     string
+	secret   string
+	provider *dnsprovider.DigicloudProvider
+	zones    []string
+}
+
+// Diagnose runs every check the signer depends on against issuerObj, in the order
+// issuance would run into them: the API token secrets, the Digicloud API and
+// namespace of every account, and for each DNS name the zone, its NS delegation,
+// DNSSEC, CAA and the resolvers. A throwaway TXT record is created for each name,
+// looked up at the zone's authoritative nameservers and deleted again. Without
+// dnsNames, the zones of all accounts are checked.
+func (s *DigicloudSigner) Diagnose(ctx context.Context, issuerObj IssuerObject, dnsNames []string) []DiagnosticResult {
+	var results []DiagnosticResult
+	pass := func(check, subject, message string) {
+		results = append(results, DiagnosticResult{Check: check, Subject: subject, Message: message})
+	}
+	fail := func(check, subject string, err error, hint string) {
+		results = append(results, DiagnosticResult{Check: check, Subject: subject, Err: err, Hint: hint})
+	}
+
+	issuerName := fmt.Sprintf("%s %s", issuerKindOf(issuerObj), client.ObjectKeyFromObject(issuerObj))
+	if condition := readyCondition(issuerObj); condition == nil {
+		fail(CheckIssuerReady, issuerName, errors.New("the issuer has no Ready condition"),
+			"Check that the controller is running and watching the issuer")
+	} else if condition.Status != cmmeta.ConditionTrue {
+		fail(CheckIssuerReady, issuerName, fmt.Errorf("%s: %s", condition.Reason, condition.Message),
+			"The checks below show which part of the configuration is at fault")
+	} else {
+		pass(CheckIssuerReady, issuerName, condition.Message)
+	}
+
+	secretNamespace := issuerSecretNamespace(issuerObj, s.clusterResourceNamespace)
+	if err := validateProvisioner(ctx, s.client, s.issuerSpec, secretNamespace); err != nil {
+		fail(CheckSecrets, secretNamespace, err, fmt.Sprintf(
+			"Create the secret with: kubectl create secret generic <name> -n %s --from-literal=token=<API token> --from-literal=namespace=<Digicloud namespace>",
+			secretNamespace))
+		return results
+	}
+	pass(CheckSecrets, secretNamespace, "All referenced secrets and keys exist")
+
+	// Check the account of every solver, like the issuer controller's API check
+	accounts := map[int]*diagnosticAccount{}
+	indexes := s.solverIndexes()
+	for _, index := range indexes {
+		solver := s.solver(index)
+		account := &diagnosticAccount{name: accountName(index), secret: secretNamespace + "/" + solver.APITokenSecretRef.Name}
+
+		provider, err := s.newProvider(ctx, issuerObj, solver)
+		if err != nil {
+			fail(CheckAPI, account.name, err, "Check the secret "+account.secret)
+			continue
+		}
+		provider.SetContext(ctx)
+		account.provider = provider
+
+		domains, err := provider.ListDomains(ctx)
+		if err != nil {
+			fail(CheckAPI, account.name, err, apiHint(err, account.secret))
+			continue
+		}
+		for _, domain := range domains {
+			account.zones = append(account.zones, domain.Name)
+		}
+		if len(account.zones) == 0 {
+			fail(CheckAPI, account.name, fmt.Errorf("the token was accepted but namespace %s holds no zones", provider.Namespace()),
+				"Set the namespace key of the secret "+account.secret+" to the Digicloud namespace holding your zones")
+			continue
+		}
+		pass(CheckAPI, account.name, fmt.Sprintf("Token accepted, namespace %s holds zones %s",
+			provider.Namespace(), strings.Join(account.zones, ", ")))
+		accounts[index] = account
+	}
+
+	// Without DNS names, check the zones of every account
+	type diagnosticName struct {
+		name  string
+		index int
+	}
+	var names []diagnosticName
+	if len(dnsNames) == 0 {
+		for _, index := range indexes {
+			if account := accounts[index]; account != nil {
+				for _, zone := range account.zones {
+					names = append(names, diagnosticName{name: zone, index: index})
+				}
+			}
+		}
+	}
+	for _, dnsName := range dnsNames {
+		names = append(names, diagnosticName{name: dnsName, index: selectSolver(s.issuerSpec.Solvers, dnsName, nil)})
+	}
+
+	caaIdentities, err := s.caaIdentities(ctx)
+	if err != nil {
+		fail(CheckACMEServer, s.getACMEServer(), err,
+			"Check acme.server of the issuer and that the ACME directory can be reached from here")
+	} else {
+		pass(CheckACMEServer, s.getACMEServer(), "Directory reachable, CAA identities "+strings.Join(caaIdentities, ", "))
+	}
+
+	resolversChecked := map[string]bool{}
+	for _, n := range names {
+		account := accounts[n.index]
+		if account == nil {
+			if !slices.Contains(indexes, n.index) {
+				fail(CheckChallengeTarget, n.name, errors.New("no solver matches and the issuer has no API token secret reference"),
+					"Add a solver whose selector matches "+n.name)
+			}
+			// Otherwise the failed account check already explains why
+			continue
+		}
+		provider := account.provider
+
+		target, err := provider.ResolveChallenge(ctx, n.name)
+		if err != nil {
+			fail(CheckChallengeTarget, n.name, err,
+				"Check the CNAME records at _acme-challenge."+strings.TrimPrefix(n.name, "*.")+" and the cnameStrategy of the issuer")
+			continue
+		}
+		if target.Delegated() {
+			pass(CheckChallengeTarget, n.name, fmt.Sprintf("CNAME-delegated to %s in zone %s", target.EffectiveFQDN, target.Zone))
+		} else {
+			pass(CheckChallengeTarget, n.name, fmt.Sprintf("%s in zone %s", target.EffectiveFQDN, target.Zone))
+		}
+
+		if !slices.Contains(account.zones, target.Zone) {
+			fail(CheckZone, n.name, fmt.Errorf("zone %s is not in Digicloud namespace %s", target.Zone, provider.Namespace()),
+				fmt.Sprintf("Add %s to Digicloud namespace %s, or configure a solver whose account holds it", target.Zone, provider.Namespace()))
+			continue
+		}
+		pass(CheckZone, n.name, fmt.Sprintf("Zone %s found in namespace %s of %s", target.Zone, provider.Namespace(), account.name))
+
+		var notDelegated *dnsprovider.ZoneNotDelegatedError
+		if err := provider.CheckDelegation(ctx, target.Zone); errors.As(err, &notDelegated) {
+			fail(CheckDelegation, n.name, err,
+				fmt.Sprintf("Set the NS records of %s at your registrar to %s", target.Zone, strings.Join(notDelegated.Expected, ", ")))
+		} else if err != nil {
+			fail(CheckDelegation, n.name, err, apiHint(err, account.secret))
+		} else {
+			pass(CheckDelegation, n.name, fmt.Sprintf("Zone %s is delegated to Digicloud", target.Zone))
+		}
+
+		var dnssecMismatch *dnsprovider.DNSSECMismatchError
+		if err := provider.CheckDNSSEC(ctx, target.Zone); errors.As(err, &dnssecMismatch) {
+			fail(CheckDNSSEC, n.name, err, "Publish the DS record Digicloud reports at your registrar, or disable DNSSEC for "+target.Zone)
+		} else if err != nil {
+			fail(CheckDNSSEC, n.name, err, "Check that the resolvers can be reached and answer DS queries")
+		} else {
+			pass(CheckDNSSEC, n.name, "Chain of trust is intact or DNSSEC is disabled")
+		}
+
+		if caaIdentities != nil {
+			var caaForbidden *dnsprovider.CAAForbiddenError
+			if err := provider.CheckCAA(ctx, n.name, caaIdentities); errors.As(err, &caaForbidden) {
+				fail(CheckCAA, n.name, err, fmt.Sprintf("Add a CAA record %s \"%s\" at %s, or remove the CAA records",
+					caaTag(n.name), strings.Join(caaIdentities, ";"), caaForbidden.RecordName))
+			} else if err != nil {
+				fail(CheckCAA, n.name, err, "Check that the resolvers can be reached and answer CAA queries")
+			} else {
+				pass(CheckCAA, n.name, "CAA records authorize "+s.getACMEServer())
+			}
+		}
+
+		if !resolversChecked[target.Zone] {
+			resolversChecked[target.Zone] = true
+			if err := provider.CheckResolvers(ctx, target.Zone); err != nil {
+				fail(CheckResolvers, target.Zone, err,
+					"Challenge records are looked up with the nameservers in /etc/resolv.conf; make sure they are reachable on port 53")
+			} else {
+				pass(CheckResolvers, target.Zone, "All resolvers answered")
+			}
+		}
+
+		results = append(results, s.diagnoseTXTRecord(ctx, provider, target, n.name)...)
+	}
+
+	return results
+}
+
+// diagnoseTXTRecord creates a throwaway challenge record for dnsName, waits for it at
+// the authoritative nameservers of its zone and deletes it again
+func (s *DigicloudSigner) diagnoseTXTRecord(ctx context.Context, provider *dnsprovider.DigicloudProvider, target *dnsprovider.ChallengeTarget, dnsName string) []DiagnosticResult {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return []DiagnosticResult{{Check: CheckTXTRecord, Subject: dnsName, Err: err}}
+	}
+	keyAuth := "doctor." + hex.EncodeToString(random)
+	value := dnsprovider.ChallengeValue(keyAuth)
+
+	if err := provider.Present(dnsName, "", keyAuth); err != nil {
+		return []DiagnosticResult{{Check: CheckTXTRecord, Subject: dnsName, Err: err,
+			Hint: fmt.Sprintf("Check that the API token may create records in zone %s", target.Zone)}}
+	}
+
+	var results []DiagnosticResult
+	started := time.Now()
+	if err := waitForAuthoritativeTXT(ctx, provider, target, value); err != nil {
+		results = append(results, DiagnosticResult{Check: CheckTXTRecord, Subject: dnsName, Err: err,
+			Hint: "Digicloud did not publish the record in time; check the zone in the Digicloud panel or raise propagationTimeout"})
+	} else {
+		results = append(results, DiagnosticResult{Check: CheckTXTRecord, Subject: dnsName,
+			Message: fmt.Sprintf("Created %s, served by all authoritative nameservers after %s",
+				target.EffectiveFQDN, time.Since(started).Round(time.Second))})
+	}
+
+	if err := provider.CleanUp(dnsName, "", keyAuth); err != nil {
+		results = append(results, DiagnosticResult{Check: CheckTXTCleanUp, Subject: dnsName, Err: err,
+			Hint: fmt.Sprintf("Delete the TXT record %s with value %s in the Digicloud panel", target.EffectiveFQDN, value)})
+	} else {
+		results = append(results, DiagnosticResult{Check: CheckTXTCleanUp, Subject: dnsName, Message: "Deleted " + target.EffectiveFQDN})
+	}
+	return results
+}
+
+// waitForAuthoritativeTXT polls the authoritative nameservers of the zone of target
+// until all of them serve value, for the propagation timeout of the provider
+func waitForAuthoritativeTXT(ctx context.Context, provider *dnsprovider.DigicloudProvider, target *dnsprovider.ChallengeTarget, value string) error {
+	timeout, interval := provider.Timeout()
+	deadline := time.Now().Add(timeout)
+	for {
+		answers, err := provider.LookupAuthoritativeTXT(ctx, target.Zone, target.EffectiveFQDN)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for ns, values := range answers {
+			if !slices.Contains(values, value) {
+				missing = append(missing, ns)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		slices.Sort(missing)
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("%s not served by %s after %s", target.EffectiveFQDN, strings.Join(missing, ", "), timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// caaIdentities returns the CAA identities of the issuer's ACME server
+func (s *DigicloudSigner) caaIdentities(ctx context.Context) ([]string, error) {
+	return acme.CAAIdentities(ctx, nil, s.getACMEServer())
+}
+
+// apiHint returns the remediation hint for a failed Digicloud API call
+func apiHint(err error, secret string) string {
+	message := err.Error()
+	switch {
+	case strings.Contains(message, fmt.Sprintf("status %d", http.StatusUnauthorized)),
+		strings.Contains(message, fmt.Sprintf("status %d", http.StatusForbidden)):
+		return "The API token was rejected; create a new token in the Digicloud panel and store it in the secret " + secret
+	case strings.Contains(message, fmt.Sprintf("status %d", http.StatusNotFound)):
+		return "Check the namespace key of the secret " + secret + " and apiBaseUrl of the issuer"
+	default:
+		return "Check apiBaseUrl of the issuer and that the Digicloud API can be reached from here"
+	}
+}
+
+// caaTag returns the CAA property governing issuance for dnsName
+func caaTag(dnsName string) string {
+	if strings.HasPrefix(dnsName, "*.") {
+		return "issuewild"
+	}
+	return "issue"
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+)
+
+// newDoctorZone returns a fake Digicloud API holding example.com in namespace
+// my-namespace, and the address of the nameserver serving it
+func newDoctorZone(t *testing.T) (*digicloudtest.Server, string) {
+	t.Helper()
+
+	api := digicloudtest.NewServer(t)
+	nameserver := api.ServeDNS(t)
+	api.AddZone("my-namespace", "example.com", digicloudtest.WithNameservers(nameserver))
+	return api, nameserver
+}
+
+func newDoctorSigner(t *testing.T, api *digicloudtest.Server, nameserver string, objects ...runtime.Object) (*DigicloudSigner, *v1alpha1.DigicloudIssuer) {
+	t.Helper()
+
+	var directory *httptest.Server
+	directory = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"newNonce":   directory.URL + "/new-nonce",
+			"newAccount": directory.URL + "/new-account",
+			"newOrder":   directory.URL + "/new-order",
+			"meta":       map[string]any{"caaIdentities": []string{"letsencrypt.org"}},
+		})
+	}))
+	t.Cleanup(directory.Close)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:         api.URL,
+				APITokenSecretRef:  v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				ACME:               &v1alpha1.DigicloudIssuerACME{Server: directory.URL},
+				PropagationTimeout: &metav1.Duration{Duration: 2 * time.Second},
+				PollingInterval:    &metav1.Duration{Duration: 100 * time.Millisecond},
+			},
+		},
+		Status: v1alpha1.DigicloudIssuerStatus{
+			Conditions: []cmapi.IssuerCondition{{Type: cmapi.IssuerConditionReady, Status: cmmeta.ConditionTrue, Message: "Issuer is ready"}},
+		},
+	}
+
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+	s.nsResolver = &fakeNSResolver{hosts: []string{nameserver}}
+	s.nameservers = []string{nameserver}
+	return s, issuer
+}
+
+func TestDigicloudSigner_Diagnose(t *testing.T) {
+	api, nameserver := newDoctorZone(t)
+	s, issuer := newDoctorSigner(t, api, nameserver, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte(digicloudtest.DefaultToken), "namespace": []byte("my-namespace")},
+	})
+
+	results := s.Diagnose(context.Background(), issuer, []string{"*.example.com", "example.net"})
+
+	checks := map[string]DiagnosticResult{}
+	for _, result := range results {
+		checks[result.Check+" "+result.Subject] = result
+	}
+	for _, check := range []string{
+		CheckIssuerReady + " DigicloudIssuer default/test-issuer",
+		CheckSecrets + " default",
+		CheckAPI + " issuer",
+		CheckACMEServer + " " + issuer.Spec.Provisioner.ACME.Server,
+		CheckChallengeTarget + " *.example.com",
+		CheckZone + " *.example.com",
+		CheckDelegation + " *.example.com",
+		CheckDNSSEC + " *.example.com",
+		CheckCAA + " *.example.com",
+		CheckResolvers + " example.com",
+		CheckTXTRecord + " *.example.com",
+		CheckTXTCleanUp + " *.example.com",
+	} {
+		require.Contains(t, checks, check)
+		assert.NoError(t, checks[check].Err, check)
+	}
+
+	// example.net is not a zone of the account
+	zoneCheck := checks[CheckZone+" example.net"]
+	assert.ErrorContains(t, zoneCheck.Err, "zone example.net is not in Digicloud namespace my-namespace")
+	assert.Contains(t, zoneCheck.Hint, "Add example.net to Digicloud namespace my-namespace")
+	assert.NotContains(t, checks, CheckTXTRecord+" example.net")
+
+	// The throwaway record was created and deleted again
+	assert.Empty(t, api.Records("my-namespace", "example.com"))
+	var deleted int
+	for _, request := range api.Requests() {
+		if request.Method == http.MethodDelete {
+			deleted++
+		}
+	}
+	assert.Equal(t, 1, deleted)
+}
+
+func TestDigicloudSigner_Diagnose_MissingSecret(t *testing.T) {
+	api, nameserver := newDoctorZone(t)
+	s, issuer := newDoctorSigner(t, api, nameserver)
+
+	results := s.Diagnose(context.Background(), issuer, nil)
+
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed())
+	assert.Equal(t, CheckSecrets, results[1].Check)
+	assert.ErrorContains(t, results[1].Err, "API token secret digicloud-credentials not found in namespace default")
+	assert.Contains(t, results[1].Hint, "kubectl create secret generic <name> -n default")
+}
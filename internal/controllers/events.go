@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
+)
+
+// Reasons of the events recorded on issuers
+const (
+	// EventReasonCredentialsValidated is recorded when the issuer's secret references resolve
+	EventReasonCredentialsValidated = "CredentialsValidated"
+
+	// EventReasonValidationFailed is recorded when the issuer's configuration is invalid
+	EventReasonValidationFailed = "ValidationFailed"
+
+	// EventReasonAPICheckFailed is recorded when the zones of the issuer's Digicloud
+	// accounts cannot be listed
+	EventReasonAPICheckFailed = "APICheckFailed"
+
+	// EventReasonCleanupFailed is recorded when the challenge records of an issuer or
+	// request being deleted cannot be cleaned up and clean up is retried
+	EventReasonCleanupFailed = "CleanupFailed"
+
+	// EventReasonCleanupAbandoned is recorded when a finalizer is removed without
+	// cleaning up because clean up kept failing for CleanupGracePeriod
+	EventReasonCleanupAbandoned = "CleanupAbandoned"
+
+	// EventReasonAccountDeactivated is recorded when the ACME account of an issuer
+	// being deleted is deactivated
+	EventReasonAccountDeactivated = "AccountDeactivated"
+)
+
+// Reasons of the events recorded on CertificateRequests
+const (
+	// EventReasonZoneResolved is recorded for the Digicloud zone a challenge is solved in
+	EventReasonZoneResolved = "ZoneResolved"
+
+	// EventReasonRecordCreated is recorded when a challenge TXT record is created
+	EventReasonRecordCreated = "TXTRecordCreated"
+
+	// EventReasonRecordCreateFailed is recorded when a challenge TXT record cannot be created
+	EventReasonRecordCreateFailed = "TXTRecordCreateFailed"
+
+	// EventReasonRecordDeleted is recorded when a challenge TXT record is removed
+	EventReasonRecordDeleted = "TXTRecordDeleted"
+
+	// EventReasonRecordDeleteFailed is recorded when a challenge TXT record cannot be removed
+	EventReasonRecordDeleteFailed = "TXTRecordDeleteFailed"
+
+	// EventReasonPropagationConfirmed is recorded once a challenge TXT record is visible
+	EventReasonPropagationConfirmed = "PropagationConfirmed"
+
+	// EventReasonPropagationTimedOut is recorded when a challenge TXT record did not
+	// become visible within the propagation timeout
+	EventReasonPropagationTimedOut = "PropagationTimedOut"
+
+	// EventReasonOrderCreated is recorded when a certificate is ordered from the ACME server
+	EventReasonOrderCreated = "OrderCreated"
+
+	// EventReasonOrderFailed is recorded when the ACME server does not issue the certificate
+	EventReasonOrderFailed = "OrderFailed"
+
+	// EventReasonIssued is recorded when the certificate is signed
+	EventReasonIssued = "Issued"
+
+	// EventReasonSigningFailed is recorded when signing fails for a reason without its
+	// own CertificateRequest condition reason
+	EventReasonSigningFailed = "SigningFailed"
+)
+
+// challengeRecorder wraps a provider set to record the lifecycle of the challenges
+// it solves as events on a CertificateRequest
+type challengeRecorder struct {
+	*dnsprovider.ProviderSet
+
+	// ctx is the context propagation checks are traced in
+	ctx      context.Context
+	recorder record.EventRecorder
+	object   runtime.Object
+
+	// onPresent is called before the TXT record for a challenge is created and
+	// onCleanUp after it is removed, if set. Calls are serialized.
+	onPresent func(domain, value string)
+	onCleanUp func(domain, value string)
+
+	mu          sync.Mutex
+	presented   []string
+	presentedAt map[string]time.Time
+	confirmed   map[string]bool
+}
+
+func newChallengeRecorder(ctx context.Context, providers *dnsprovider.ProviderSet, recorder record.EventRecorder, object runtime.Object) *challengeRecorder {
+	return &challengeRecorder{
+		ProviderSet: providers,
+		ctx:         ctx,
+		recorder:    recorder,
+		object:      object,
+		presentedAt: map[string]time.Time{},
+		confirmed:   map[string]bool{},
+	}
+}
+
+// Present creates the TXT record for domain and records the outcome
+func (r *challengeRecorder) Present(domain, token, keyAuth string) error {
+	fqdn := dnsprovider.ChallengeFQDN(domain)
+	if r.onPresent != nil {
+		r.mu.Lock()
+		r.onPresent(domain, dnsprovider.ChallengeValue(keyAuth))
+		r.mu.Unlock()
+	}
+
+	err := r.ProviderSet.Present(domain, token, keyAuth)
+	metrics.ChallengeOperations.WithLabelValues(metrics.OperationPresent, metrics.Result(err)).Inc()
+	if err != nil {
+		r.recorder.Eventf(r.object, corev1.EventTypeWarning, EventReasonRecordCreateFailed, "Failed to create TXT record %s: %v", fqdn, err)
+		return err
+	}
+
+	r.mu.Lock()
+	r.presented = append(r.presented, domain)
+	r.presentedAt[domain] = time.Now()
+	r.mu.Unlock()
+
+	r.recorder.Eventf(r.object, corev1.EventTypeNormal, EventReasonRecordCreated, "Created TXT record %s", fqdn)
+	return nil
+}
+
+// CleanUp removes the TXT record for domain and records the outcome
+func (r *challengeRecorder) CleanUp(domain, token, keyAuth string) error {
+	fqdn := dnsprovider.ChallengeFQDN(domain)
+	err := r.ProviderSet.CleanUp(domain, token, keyAuth)
+	metrics.ChallengeOperations.WithLabelValues(metrics.OperationCleanUp, metrics.Result(err)).Inc()
+	if err != nil {
+		r.recorder.Eventf(r.object, corev1.EventTypeWarning, EventReasonRecordDeleteFailed, "Failed to delete TXT record %s: %v", fqdn, err)
+		return err
+	}
+
+	if r.onCleanUp != nil {
+		r.mu.Lock()
+		r.onCleanUp(domain, dnsprovider.ChallengeValue(keyAuth))
+		r.mu.Unlock()
+	}
+
+	r.recorder.Eventf(r.object, corev1.EventTypeNormal, EventReasonRecordDeleted, "Deleted TXT record %s", fqdn)
+	return nil
+}
+
+// PreCheck runs the pre-check of the provider set and records the first time the
+// TXT record of domain is found. It implements dns01.WrapPreCheckFunc.
+func (r *challengeRecorder) PreCheck(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+	_, span := tracing.Start(r.ctx, "DNS01 propagation check", trace.WithAttributes(attribute.String("dns.fqdn", fqdn)))
+	found, err := r.ProviderSet.PreCheck(domain, fqdn, value, check)
+	span.SetAttributes(attribute.Bool("dns.found", found))
+	tracing.End(span, err)
+	if err != nil || !found {
+		return found, err
+	}
+
+	r.mu.Lock()
+	first := !r.confirmed[domain]
+	r.confirmed[domain] = true
+	presentedAt, presented := r.presentedAt[domain]
+	r.mu.Unlock()
+
+	if first {
+		if presented {
+			metrics.PropagationDuration.WithLabelValues(metrics.ResultConfirmed).Observe(time.Since(presentedAt).Seconds())
+		}
+		r.recorder.Eventf(r.object, corev1.EventTypeNormal, EventReasonPropagationConfirmed, "TXT record %s has propagated", fqdn)
+	}
+	return true, nil
+}
+
+// recordTimeouts records a propagation timeout for every presented challenge whose
+// TXT record was never found, if err is lego's propagation time limit error
+func (r *challengeRecorder) recordTimeouts(err error) {
+	if err == nil || !strings.Contains(err.Error(), "time limit exceeded") {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, domain := range r.presented {
+		if !r.confirmed[domain] {
+			if presentedAt, ok := r.presentedAt[domain]; ok {
+				metrics.PropagationDuration.WithLabelValues(metrics.ResultTimeout).Observe(time.Since(presentedAt).Seconds())
+			}
+			r.recorder.Eventf(r.object, corev1.EventTypeWarning, EventReasonPropagationTimedOut,
+				"TXT record for %s did not propagate within the propagation timeout", dns01.UnFqdn(domain))
+		}
+	}
+}
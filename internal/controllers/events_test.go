@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+)
+
+func TestChallengeRecorder_PresentFailed(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	challenges := newChallengeRecorder(context.Background(), dnsprovider.NewProviderSet(), recorder, &cmapi.CertificateRequest{})
+	failures := metrics.ChallengeOperations.WithLabelValues(metrics.OperationPresent, metrics.ResultError)
+	before := testutil.ToFloat64(failures)
+
+	err := challenges.Present("example.com", "token", "key-auth")
+	assert.Error(t, err)
+	assert.Equal(t, before+1, testutil.ToFloat64(failures))
+
+	require.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, "Warning "+EventReasonRecordCreateFailed)
+	assert.Contains(t, event, "_acme-challenge.example.com.")
+}
+
+func TestChallengeRecorder_RecordTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantEvents []string
+	}{
+		{
+			name:       "propagation timeout",
+			err:        errors.New("propagation: time limit exceeded: last error: NS ns1.digicloud.ir. did not return the expected TXT record"),
+			wantEvents: []string{"Warning PropagationTimedOut TXT record for www.example.com did not propagate"},
+		},
+		{
+			name: "other error",
+			err:  errors.New("acme: error: 403 :: urn:ietf:params:acme:error:unauthorized"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			challenges := newChallengeRecorder(context.Background(), dnsprovider.NewProviderSet(), recorder, &cmapi.CertificateRequest{})
+			challenges.presented = []string{"example.com", "www.example.com"}
+			challenges.confirmed["example.com"] = true
+
+			challenges.recordTimeouts(tt.err)
+
+			require.Len(t, recorder.Events, len(tt.wantEvents))
+			for _, want := range tt.wantEvents {
+				assert.Contains(t, <-recorder.Events, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+const (
+	// IssuerFinalizer holds the deletion of an issuer until the challenge records it
+	// presented are removed
+	IssuerFinalizer = "digicloud.issuer.vamirreza.github.io/finalizer"
+
+	// RequestFinalizer holds the deletion of a CertificateRequest until the challenge
+	// records presented for it are removed
+	RequestFinalizer = "digicloud.issuer.vamirreza.github.io/challenge-records"
+
+	// CleanupGracePeriod is how long after deletion clean up is retried before a
+	// finalizer is removed regardless, so that an unreachable API does not block the
+	// deletion forever
+	CleanupGracePeriod = 10 * time.Minute
+
+	// cleanupTimeout bounds one clean up attempt
+	cleanupTimeout = 30 * time.Second
+
+	// cleanupRetryInterval is how soon a failed clean up is retried
+	cleanupRetryInterval = 30 * time.Second
+)
+
+// challengeSolvers returns the index of the solver selected for the challenge record
+// of each DNS name, keyed by the name without a wildcard prefix
+func (s *DigicloudSigner) challengeSolvers(dnsNames []string, labels map[string]string) map[string]int {
+	solvers := make(map[string]int, len(dnsNames))
+	for _, dnsName := range dnsNames {
+		solvers[dnsname.Key(dnsName)] = selectSolver(s.issuerSpec.Solvers, dnsName, labels)
+	}
+	return solvers
+}
+
+// newChallengeRecord returns the record presented for request with value for the
+// challenge of domain, given the challenge targets and solvers of the request
+func newChallengeRecord(request types.UID, targets map[string]*dnsprovider.ChallengeTarget, solvers map[string]int, domain, value string) (digicloudv1alpha1.ChallengeRecord, bool) {
+	key := dnsname.Key(domain)
+	target, ok := targets[key]
+	if !ok {
+		return digicloudv1alpha1.ChallengeRecord{}, false
+	}
+
+	record := digicloudv1alpha1.ChallengeRecord{
+		Request: string(request),
+		FQDN:    target.EffectiveFQDN,
+		Zone:    target.Zone,
+		Value:   value,
+	}
+	if index, ok := solvers[key]; ok && index != defaultSolver {
+		solver := int32(index)
+		record.Solver = &solver
+	}
+	return record, true
+}
+
+// addChallengeRecord records a challenge record in the issuer status before it is
+// presented
+func (s *DigicloudSigner) addChallengeRecord(ctx context.Context, issuerObj client.Object, record digicloudv1alpha1.ChallengeRecord) error {
+	return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+		records := issuer.GetChallengeRecords()
+		for _, existing := range records {
+			if sameChallengeRecord(existing, record) {
+				return
+			}
+		}
+		setChallengeRecords(issuer, append(records, record))
+	})
+}
+
+// removeChallengeRecord removes a challenge record from the issuer status once it is
+// cleaned up
+func (s *DigicloudSigner) removeChallengeRecord(ctx context.Context, issuerObj client.Object, record digicloudv1alpha1.ChallengeRecord) error {
+	return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+		var records []digicloudv1alpha1.ChallengeRecord
+		for _, existing := range issuer.GetChallengeRecords() {
+			if !sameChallengeRecord(existing, record) {
+				records = append(records, existing)
+			}
+		}
+		setChallengeRecords(issuer, records)
+	})
+}
+
+// sameChallengeRecord reports whether a and b are the same TXT record presented for
+// the same request
+func sameChallengeRecord(a, b digicloudv1alpha1.ChallengeRecord) bool {
+	return a.Request == b.Request && a.FQDN == b.FQDN && a.Value == b.Value
+}
+
+// cleanUpChallengeRecords removes the challenge records of the issuer presented for
+// request, or all of them if request is empty, and drops them from its status
+func (s *DigicloudSigner) cleanUpChallengeRecords(ctx context.Context, issuerObj client.Object, request types.UID) error {
+	issuer, ok := issuerObj.(IssuerObject)
+	if !ok {
+		return fmt.Errorf("unsupported issuer type %T", issuerObj)
+	}
+
+	providers := map[int]*dnsprovider.DigicloudProvider{}
+	var errs []error
+	// removeChallengeRecord reads the issuer again on conflicts, which may decode into
+	// the records being iterated
+	for _, record := range slices.Clone(issuer.GetChallengeRecords()) {
+		if request != "" && record.Request != string(request) {
+			continue
+		}
+
+		index := defaultSolver
+		if record.Solver != nil {
+			index = int(*record.Solver)
+		}
+		if index >= len(s.issuerSpec.Solvers) {
+			// The solver was removed from the issuer, so its account is unknown
+			errs = append(errs, fmt.Errorf("solver %d of TXT record %s no longer exists", index, record.FQDN))
+			continue
+		}
+
+		provider, ok := providers[index]
+		if !ok {
+			var err error
+			provider, err = s.newProvider(ctx, issuerObj, s.solver(index))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			providers[index] = provider
+		}
+
+		if err := provider.DeleteTXTRecord(ctx, record.Zone, record.FQDN, record.Value); err != nil {
+			errs = append(errs, fmt.Errorf("failed to clean up TXT record %s: %w", record.FQDN, err))
+			continue
+		}
+		if err := s.removeChallengeRecord(ctx, issuerObj, record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// deactivateACMEAccount deactivates the ACME account of the issuer's account key. It
+// reports false if there is no account key, and thus no account.
+func (s *DigicloudSigner) deactivateACMEAccount(ctx context.Context, issuerObj client.Object) (bool, error) {
+	name, key := s.acmeAccountKeyRef(issuerObj)
+
+	var secret corev1.Secret
+	if err := s.client.Get(ctx, name, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	accountKey, err := acme.ParseAccountKey(secret.Data[key])
+	if err != nil {
+		return false, err
+	}
+
+	acmeClient, err := acme.NewClient(ctx, acme.Options{DirectoryURL: s.getACMEServer(), Key: accountKey})
+	if err != nil {
+		return false, err
+	}
+	if err := acmeClient.DeactivateAccount(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// addRequestFinalizer adds RequestFinalizer to object if it is a CertificateRequest,
+// before any challenge record is presented for it
+func (s *DigicloudSigner) addRequestFinalizer(ctx context.Context, object runtime.Object) error {
+	cr, ok := object.(*cmapi.CertificateRequest)
+	if !ok || controllerutil.ContainsFinalizer(cr, RequestFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	controllerutil.AddFinalizer(cr, RequestFinalizer)
+	if err := s.client.Patch(ctx, cr, patch); err != nil {
+		return fmt.Errorf("failed to add finalizer to CertificateRequest: %w", err)
+	}
+	return nil
+}
+
+// releaseRequest cleans up any challenge record left over for the request in object
+// once its order completed, and removes RequestFinalizer when none is left
+func (s *DigicloudSigner) releaseRequest(ctx context.Context, issuerObj client.Object, object runtime.Object) error {
+	cr, ok := object.(*cmapi.CertificateRequest)
+	if !ok {
+		return nil
+	}
+
+	if err := s.cleanUpChallengeRecords(ctx, issuerObj, cr.UID); err != nil {
+		return err
+	}
+	if !controllerutil.ContainsFinalizer(cr, RequestFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFrom(cr.DeepCopy())
+	controllerutil.RemoveFinalizer(cr, RequestFinalizer)
+	if err := s.client.Patch(ctx, cr, patch); err != nil {
+		return fmt.Errorf("failed to remove finalizer from CertificateRequest: %w", err)
+	}
+	return nil
+}
+
+// finalize cleans up after an issuer being deleted and removes IssuerFinalizer once
+// done, or once CleanupGracePeriod has passed since the deletion
+func (r *IssuerReconciler) finalize(ctx context.Context, issuer IssuerObject) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(issuer, IssuerFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(ctx, cleanupTimeout)
+	defer cancel()
+
+	s := NewDigicloudSigner(r.Client, *issuer.GetProvisioner(), r.ClusterResourceNamespace)
+	err := s.cleanUpChallengeRecords(cleanupCtx, issuer, "")
+	if acmeSpec := issuer.GetProvisioner().ACME; err == nil && acmeSpec != nil && acmeSpec.DeactivateAccountOnDelete {
+		var deactivated bool
+		if deactivated, err = s.deactivateACMEAccount(cleanupCtx, issuer); deactivated {
+			r.Recorder.Event(issuer, corev1.EventTypeNormal, EventReasonAccountDeactivated, "Deactivated ACME account")
+		}
+	}
+
+	if err != nil {
+		if cleanupPending(issuer) {
+			logger.Error(err, "Failed to clean up after issuer, retrying")
+			r.Recorder.Eventf(issuer, corev1.EventTypeWarning, EventReasonCleanupFailed, "Failed to clean up, retrying: %v", err)
+			return ctrl.Result{RequeueAfter: cleanupRetryInterval}, nil
+		}
+		logger.Error(err, "Giving up cleaning up after issuer")
+		r.Recorder.Eventf(issuer, corev1.EventTypeWarning, EventReasonCleanupAbandoned,
+			"Removing finalizer after failing to clean up for %s: %v", CleanupGracePeriod, err)
+	}
+
+	patch := client.MergeFrom(issuer.DeepCopyObject().(client.Object))
+	controllerutil.RemoveFinalizer(issuer, IssuerFinalizer)
+	if err := r.Patch(ctx, issuer, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// cleanupPending reports whether clean up of obj, which is being deleted, should
+// still be retried
+func cleanupPending(obj client.Object) bool {
+	deleted := obj.GetDeletionTimestamp()
+	return deleted == nil || time.Since(deleted.Time) < CleanupGracePeriod
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// recordsServer is a Digicloud API serving the TXT records of a zone and recording
+// the records deleted
+type recordsServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	deleted []string
+}
+
+// newRecordsServer returns a Digicloud API listing challenge records in example.com,
+// or failing every request with status if it is not http.StatusOK
+func newRecordsServer(t *testing.T, status int) *recordsServer {
+	t.Helper()
+
+	server := &recordsServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"records": [
+				{"id": "rec-1", "name": "_acme-challenge", "type": "TXT", "content": "value-1"},
+				{"id": "rec-2", "name": "_acme-challenge.www", "type": "TXT", "content": "value-2"}
+			]}`))
+		case http.MethodDelete:
+			server.mu.Lock()
+			server.deleted = append(server.deleted, r.URL.Path)
+			server.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+var testChallengeRecords = []v1alpha1.ChallengeRecord{
+	{Request: "request-1", FQDN: "_acme-challenge.example.com.", Zone: "example.com", Value: "value-1"},
+	{Request: "request-2", FQDN: "_acme-challenge.www.example.com.", Zone: "example.com", Value: "value-2", Solver: ptr.To[int32](0)},
+}
+
+// newDeletedIssuer returns an issuer being deleted, holding IssuerFinalizer and
+// testChallengeRecords, and the secret with its API token
+func newDeletedIssuer(apiBaseURL string, deleted time.Time) (*v1alpha1.DigicloudIssuer, *corev1.Secret) {
+	tokenRef := v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"}
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-issuer",
+			Namespace:         "default",
+			Finalizers:        []string{IssuerFinalizer},
+			DeletionTimestamp: &metav1.Time{Time: deleted},
+		},
+		Spec: v1alpha1.DigicloudIssuerSpec{Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+			APIBaseURL:        apiBaseURL,
+			APITokenSecretRef: tokenRef,
+			Solvers:           []v1alpha1.DigicloudSolver{{APITokenSecretRef: tokenRef}},
+			ACME:              &v1alpha1.DigicloudIssuerACME{DeactivateAccountOnDelete: true},
+		}},
+		Status: v1alpha1.DigicloudIssuerStatus{
+			ChallengeRecords:   testChallengeRecords,
+			InFlightChallenges: int32(len(testChallengeRecords)),
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("test-token")},
+	}
+	return issuer, secret
+}
+
+func TestIssuerReconciler_Finalize(t *testing.T) {
+	server := newRecordsServer(t, http.StatusOK)
+	issuer, secret := newDeletedIssuer(server.URL, time.Now())
+
+	scheme := newIssuerTestScheme(t)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer, secret).
+		WithStatusSubresource(issuer).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ForObject: &v1alpha1.DigicloudIssuer{},
+	}
+
+	result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(issuer),
+	})
+	require.NoError(t, err)
+	assert.Zero(t, result.RequeueAfter)
+
+	// Both records are removed and, without an account key, no account is deactivated
+	assert.ElementsMatch(t, []string{
+		"/v1/edge/domains/example.com/records/rec-1",
+		"/v1/edge/domains/example.com/records/rec-2",
+	}, server.deleted)
+	assert.Empty(t, recorder.Events)
+
+	err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), &v1alpha1.DigicloudIssuer{})
+	assert.True(t, apierrors.IsNotFound(err), "issuer should be deleted once its finalizer is removed")
+}
+
+func TestIssuerReconciler_Finalize_APIUnreachable(t *testing.T) {
+	tests := []struct {
+		name          string
+		deleted       time.Time
+		wantRequeue   time.Duration
+		wantEvent     string
+		wantFinalizer bool
+	}{
+		{
+			name:          "within grace period",
+			deleted:       time.Now(),
+			wantRequeue:   cleanupRetryInterval,
+			wantEvent:     "Warning " + EventReasonCleanupFailed,
+			wantFinalizer: true,
+		},
+		{
+			name:      "grace period passed",
+			deleted:   time.Now().Add(-CleanupGracePeriod - time.Minute),
+			wantEvent: "Warning " + EventReasonCleanupAbandoned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRecordsServer(t, http.StatusServiceUnavailable)
+			issuer, secret := newDeletedIssuer(server.URL, tt.deleted)
+
+			scheme := newIssuerTestScheme(t)
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(issuer, secret).
+				WithStatusSubresource(issuer).
+				Build()
+
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &IssuerReconciler{
+				Client:    fakeClient,
+				Scheme:    scheme,
+				Recorder:  recorder,
+				ForObject: &v1alpha1.DigicloudIssuer{},
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(issuer),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRequeue, result.RequeueAfter)
+
+			require.Len(t, recorder.Events, 1)
+			assert.Contains(t, <-recorder.Events, tt.wantEvent)
+
+			updated := &v1alpha1.DigicloudIssuer{}
+			err = fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated)
+			if !tt.wantFinalizer {
+				assert.True(t, apierrors.IsNotFound(err), "issuer should be deleted once its finalizer is removed")
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, updated.Finalizers, IssuerFinalizer)
+			assert.Equal(t, testChallengeRecords, updated.Status.ChallengeRecords)
+		})
+	}
+}
+
+func TestDigicloudSigner_AddChallengeRecord_Concurrent(t *testing.T) {
+	issuer := &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"}}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(newIssuerTestScheme(t)).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		Build()
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), issuer))
+
+	// Every signer starts from the same resource version, as concurrent requests
+	// signed with the same cached issuer do
+	const signers = 10
+	var wg sync.WaitGroup
+	errs := make([]error, signers)
+	for i := range signers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+			record := v1alpha1.ChallengeRecord{
+				Request: fmt.Sprintf("request-%d", i),
+				FQDN:    "_acme-challenge.example.com.",
+				Zone:    "example.com",
+				Value:   fmt.Sprintf("value-%d", i),
+			}
+			errs[i] = s.addChallengeRecord(context.Background(), issuer.DeepCopy(), record)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	updated := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+	assert.Len(t, updated.Status.ChallengeRecords, signers)
+	assert.Equal(t, int32(signers), updated.Status.InFlightChallenges)
+}
+
+func TestNewChallengeRecord(t *testing.T) {
+	targets := map[string]*dnsprovider.ChallengeTarget{
+		"example.com": {
+			FQDN:          "_acme-challenge.example.com.",
+			EffectiveFQDN: "_acme-challenge.example.com.",
+			Zone:          "example.com",
+		},
+		"www.example.com": {
+			FQDN:          "_acme-challenge.www.example.com.",
+			EffectiveFQDN: "www.acme.example.net.",
+			Zone:          "example.net",
+		},
+	}
+	solvers := map[string]int{"example.com": defaultSolver, "www.example.com": 1}
+
+	tests := []struct {
+		name   string
+		domain string
+		want   v1alpha1.ChallengeRecord
+		wantOK bool
+	}{
+		{
+			name:   "issuer's own account",
+			domain: "example.com",
+			want:   v1alpha1.ChallengeRecord{Request: "uid", FQDN: "_acme-challenge.example.com.", Zone: "example.com", Value: "value"},
+			wantOK: true,
+		},
+		{
+			name:   "CNAME-delegated with solver",
+			domain: "WWW.example.com.",
+			want:   v1alpha1.ChallengeRecord{Request: "uid", FQDN: "www.acme.example.net.", Zone: "example.net", Value: "value", Solver: ptr.To[int32](1)},
+			wantOK: true,
+		},
+		{
+			name:   "unknown domain",
+			domain: "example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, ok := newChallengeRecord("uid", targets, solvers, tt.domain, "value")
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, record)
+		})
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/health"
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+)
+
+// DefaultClusterResourceNamespace is the namespace secrets referenced by cluster
+// issuers are read from when no cluster resource namespace is configured
+const DefaultClusterResourceNamespace = "digicloud-issuer-system"
+
+// RevalidateAnnotation requests that an issuer's configuration is re-validated and
+// the Digicloud API checked again whenever its value changes. kubectl digicloud
+// revalidate sets it to the current time.
+const RevalidateAnnotation = "digicloud.issuer.vamirreza.github.io/revalidate"
+
+// IssuerConditionDNSSEC is the issuer condition reporting whether the DNSSEC chain of
+// trust of the zones the issuer last solved challenges in is intact
+const IssuerConditionDNSSEC cmapi.IssuerConditionType = "DNSSEC"
+
+// IssuerObject is implemented by DigicloudIssuer and DigicloudClusterIssuer
+type IssuerObject interface {
+	client.Object
+
+	GetProvisioner() *digicloudv1alpha1.DigicloudIssuerProvisioner
+	GetConditions() []cmapi.IssuerCondition
+	SetConditions(conditions []cmapi.IssuerCondition)
+	GetObservedGeneration() int64
+	SetObservedGeneration(generation int64)
+	GetChallengeDelegations() []digicloudv1alpha1.ChallengeDelegation
+	SetChallengeDelegations(delegations []digicloudv1alpha1.ChallengeDelegation)
+	SetAPICheck(namespace string, zones []digicloudv1alpha1.ZoneStatus, checked metav1.Time)
+	SetACMEAccount(account *digicloudv1alpha1.ACMEAccountStatus)
+	SetInFlightChallenges(count int32)
+	GetChallengeRecords() []digicloudv1alpha1.ChallengeRecord
+	SetChallengeRecords(records []digicloudv1alpha1.ChallengeRecord)
+}
+
+// IssuerReconciler reconciles DigicloudIssuer or DigicloudClusterIssuer objects,
+// depending on the kind of ForObject
+type IssuerReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// ForObject is an empty object of the issuer kind to reconcile
+	ForObject IssuerObject
+
+	// ClusterResourceNamespace is the namespace secrets referenced by cluster issuers
+	// are read from. Defaults to DefaultClusterResourceNamespace.
+	ClusterResourceNamespace string
+
+	// Watchdog tracks reconciles so that a wedged worker fails the liveness check.
+	// Optional.
+	Watchdog *health.Watchdog
+}
+
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *IssuerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	defer r.Watchdog.Track()()
+
+	issuer := r.ForObject.DeepCopyObject().(IssuerObject)
+	if err := r.Get(ctx, req.NamespacedName, issuer); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("Issuer resource not found, ignoring since object must be deleted")
+			metrics.DeleteIssuerReady(issuerKindOf(r.ForObject), req.Namespace, req.Name)
+			return ctrl.Result{}, nil
+		}
+		logger.Error(err, "Failed to get issuer")
+		return ctrl.Result{}, err
+	}
+
+	if !issuer.GetDeletionTimestamp().IsZero() {
+		metrics.DeleteIssuerReady(issuerKindOf(issuer), issuer.GetNamespace(), issuer.GetName())
+		return r.finalize(ctx, issuer)
+	}
+	if !controllerutil.ContainsFinalizer(issuer, IssuerFinalizer) {
+		patch := client.MergeFrom(issuer.DeepCopyObject().(client.Object))
+		controllerutil.AddFinalizer(issuer, IssuerFinalizer)
+		if err := r.Patch(ctx, issuer, patch); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Validate the issuer configuration
+	if err := r.validateIssuer(ctx, issuer); err != nil {
+		logger.Error(err, "Invalid issuer configuration")
+		r.Recorder.Eventf(issuer, corev1.EventTypeWarning, EventReasonValidationFailed, "Issuer configuration is invalid: %v", err)
+		if statusErr := r.patchStatus(ctx, issuer, func(issuer IssuerObject) {
+			setReadyCondition(issuer, cmmeta.ConditionFalse, "Failed", err.Error())
+		}); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+			return ctrl.Result{}, statusErr
+		}
+		return ctrl.Result{}, err
+	}
+	r.Recorder.Event(issuer, corev1.EventTypeNormal, EventReasonCredentialsValidated, "Issuer configuration and credentials are valid")
+
+	// Check the Digicloud API, keeping the last successful check in the status on failure
+	result := ctrl.Result{RequeueAfter: apiCheckInterval}
+	namespace, zones, apiErr := r.checkAPI(ctx, issuer)
+	if apiErr != nil {
+		logger.Error(apiErr, "Digicloud API check failed")
+		r.Recorder.Eventf(issuer, corev1.EventTypeWarning, EventReasonAPICheckFailed, "Digicloud API check failed: %v", apiErr)
+		result.RequeueAfter = apiCheckRetryInterval
+	}
+	checked := metav1.Now()
+
+	if err := r.patchStatus(ctx, issuer, func(issuer IssuerObject) {
+		setReadyCondition(issuer, cmmeta.ConditionTrue, "Checked", "Issuer configuration is valid")
+		if apiErr == nil {
+			issuer.SetAPICheck(namespace, zones, checked)
+		}
+	}); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Issuer reconciled successfully")
+	return result, nil
+}
+
+// patchStatus applies update to the issuer, records the generation the status was
+// computed for and merge-patches its status if anything changed. A merge patch
+// replaces the conditions whole, so the patch is conditional on the issuer's
+// resource version; on a conflict with a concurrent writer, such as the signer
+// recording the DNSSEC condition, the issuer is read again and update is reapplied.
+// If the spec changed meanwhile, the patch is dropped for the reconcile of the new
+// generation.
+func (r *IssuerReconciler) patchStatus(ctx context.Context, issuer IssuerObject, update func(issuer IssuerObject)) error {
+	generation := issuer.GetGeneration()
+	stale := false
+	err := retry.RetryOnConflict(statusPatchBackoff, func() error {
+		if stale {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(issuer), issuer); err != nil {
+				return err
+			}
+			if issuer.GetGeneration() != generation {
+				return nil
+			}
+		}
+		stale = true
+
+		original := issuer.DeepCopyObject().(IssuerObject)
+		update(issuer)
+		issuer.SetObservedGeneration(generation)
+		if equality.Semantic.DeepEqual(original, issuer) {
+			return nil
+		}
+		return r.Status().Patch(ctx, issuer, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+	metrics.SetIssuerReady(issuerKindOf(issuer), issuer.GetNamespace(), issuer.GetName(), issuerReady(issuer))
+	return err
+}
+
+// validateIssuer validates the issuer configuration
+func (r *IssuerReconciler) validateIssuer(ctx context.Context, issuer IssuerObject) error {
+	if clusterIssuer, ok := issuer.(*digicloudv1alpha1.DigicloudClusterIssuer); ok && clusterIssuer.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(clusterIssuer.Spec.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+	}
+
+	return validateProvisioner(ctx, r.Client, *issuer.GetProvisioner(), issuerSecretNamespace(issuer, r.ClusterResourceNamespace))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IssuerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// Status updates, such as the API check time, must not trigger a reconcile;
+		// the API is checked again on a timer or on request through
+		// RevalidateAnnotation instead. Deletion is reconciled to run the finalizer.
+		For(r.ForObject, builder.WithPredicates(predicate.Or[client.Object](
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool { return !obj.GetDeletionTimestamp().IsZero() }),
+			revalidatePredicate(),
+		))).
+		Complete(r)
+}
+
+// revalidatePredicate passes updates changing RevalidateAnnotation
+func revalidatePredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetAnnotations()[RevalidateAnnotation] != e.ObjectNew.GetAnnotations()[RevalidateAnnotation]
+		},
+	}
+}
+
+// issuerSecretNamespace returns the namespace the secrets referenced by an issuer
+// are read from: the issuer's own namespace, or clusterResourceNamespace for
+// cluster issuers
+func issuerSecretNamespace(issuer client.Object, clusterResourceNamespace string) string {
+	if _, ok := issuer.(*digicloudv1alpha1.DigicloudClusterIssuer); !ok {
+		return issuer.GetNamespace()
+	}
+	if clusterResourceNamespace == "" {
+		return DefaultClusterResourceNamespace
+	}
+	return clusterResourceNamespace
+}
+
+// issuerKindOf returns the kind of issuer
+func issuerKindOf(issuer client.Object) string {
+	if _, ok := issuer.(*digicloudv1alpha1.DigicloudClusterIssuer); ok {
+		return "DigicloudClusterIssuer"
+	}
+	return "DigicloudIssuer"
+}
+
+// issuerReady reports whether the Ready condition of issuer is True
+func issuerReady(issuer IssuerObject) bool {
+	condition := readyCondition(issuer)
+	return condition != nil && condition.Status == cmmeta.ConditionTrue
+}
+
+// readyCondition returns the Ready condition of issuer, or nil if it has none
+func readyCondition(issuer IssuerObject) *cmapi.IssuerCondition {
+	for _, condition := range issuer.GetConditions() {
+		if condition.Type == cmapi.IssuerConditionReady {
+			return &condition
+		}
+	}
+	return nil
+}
+
+// setReadyCondition sets the Ready condition on the issuer for its current generation
+func setReadyCondition(issuer IssuerObject, status cmmeta.ConditionStatus, reason, message string) {
+	setCondition(issuer, cmapi.IssuerConditionReady, status, reason, message)
+}
+
+// setCondition sets a condition on the issuer for its current generation. The
+// transition time only moves when the condition status changes.
+func setCondition(issuer IssuerObject, conditionType cmapi.IssuerConditionType, status cmmeta.ConditionStatus, reason, message string) {
+	now := metav1.Now()
+	condition := cmapi.IssuerCondition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: &now,
+		ObservedGeneration: issuer.GetGeneration(),
+	}
+
+	// Replace existing condition
+	conditions := issuer.GetConditions()
+	for i, existing := range conditions {
+		if existing.Type == conditionType {
+			if existing.Status == status && existing.LastTransitionTime != nil {
+				condition.LastTransitionTime = existing.LastTransitionTime
+			}
+			conditions[i] = condition
+			return
+		}
+	}
+
+	// Add new condition if not found
+	issuer.SetConditions(append(conditions, condition))
+}
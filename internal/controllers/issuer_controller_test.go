@@ -0,0 +1,574 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+)
+
+const testClusterResourceNamespace = "issuer-system"
+
+// issuerKind builds issuers of one kind for the reconciler tests
+type issuerKind struct {
+	name string
+	// secretNamespace is the namespace the reconciler reads the issuer's secrets from
+	secretNamespace string
+	newIssuer       func(provisioner v1alpha1.DigicloudIssuerProvisioner) IssuerObject
+}
+
+var issuerKinds = []issuerKind{
+	{
+		name:            "DigicloudIssuer",
+		secretNamespace: "default",
+		newIssuer: func(provisioner v1alpha1.DigicloudIssuerProvisioner) IssuerObject {
+			return &v1alpha1.DigicloudIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+				Spec:       v1alpha1.DigicloudIssuerSpec{Provisioner: provisioner},
+			}
+		},
+	},
+	{
+		name:            "DigicloudClusterIssuer",
+		secretNamespace: testClusterResourceNamespace,
+		newIssuer: func(provisioner v1alpha1.DigicloudIssuerProvisioner) IssuerObject {
+			return &v1alpha1.DigicloudClusterIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+				Spec:       v1alpha1.DigicloudClusterIssuerSpec{Provisioner: provisioner},
+			}
+		},
+	},
+}
+
+func TestIssuerReconciler_Reconcile(t *testing.T) {
+	tokenRef := v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"}
+
+	tests := []struct {
+		name        string
+		provisioner v1alpha1.DigicloudIssuerProvisioner
+		// secrets are created in the namespace the issuer's secrets are read from
+		secrets    []*corev1.Secret
+		conditions []cmapi.IssuerCondition
+		wantStatus cmmeta.ConditionStatus
+		wantReason string
+		wantErr    string
+		// wantNamespace is the Digicloud namespace reported after a successful API check
+		wantNamespace string
+	}{
+		{
+			name:        "valid configuration",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{APITokenSecretRef: tokenRef},
+			secrets: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials"},
+				Data:       map[string][]byte{"token": []byte("test-token")},
+			}},
+			wantStatus:    cmmeta.ConditionTrue,
+			wantReason:    "Checked",
+			wantNamespace: "default",
+		},
+		{
+			name:        "secret not found",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{APITokenSecretRef: tokenRef},
+			wantStatus:  cmmeta.ConditionFalse,
+			wantReason:  "Failed",
+			wantErr:     "API token secret digicloud-credentials not found",
+		},
+		{
+			name:        "secret without key",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{APITokenSecretRef: tokenRef},
+			secrets: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials"},
+				Data:       map[string][]byte{"other": []byte("test-token")},
+			}},
+			wantStatus: cmmeta.ConditionFalse,
+			wantReason: "Failed",
+			wantErr:    "does not contain key token",
+		},
+		{
+			name: "solver credentials only",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				Solvers: []v1alpha1.DigicloudSolver{{APITokenSecretRef: tokenRef}},
+			},
+			secrets: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials"},
+				Data:       map[string][]byte{"token": []byte("test-token")},
+			}},
+			wantStatus: cmmeta.ConditionTrue,
+			wantReason: "Checked",
+		},
+		{
+			name: "missing solver secret",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				Solvers: []v1alpha1.DigicloudSolver{{APITokenSecretRef: tokenRef}},
+			},
+			wantStatus: cmmeta.ConditionFalse,
+			wantReason: "Failed",
+			wantErr:    "solver 0: API token secret digicloud-credentials not found",
+		},
+		{
+			name:        "existing Ready condition is replaced",
+			provisioner: v1alpha1.DigicloudIssuerProvisioner{APITokenSecretRef: tokenRef},
+			secrets: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials"},
+				Data:       map[string][]byte{"token": []byte("test-token")},
+			}},
+			conditions: []cmapi.IssuerCondition{{
+				Type:    cmapi.IssuerConditionReady,
+				Status:  cmmeta.ConditionFalse,
+				Reason:  "Failed",
+				Message: "API token secret digicloud-credentials not found",
+			}},
+			wantStatus:    cmmeta.ConditionTrue,
+			wantReason:    "Checked",
+			wantNamespace: "default",
+		},
+	}
+
+	server := newDomainsServer(t, http.StatusOK)
+
+	for _, kind := range issuerKinds {
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				provisioner := *tt.provisioner.DeepCopy()
+				provisioner.APIBaseURL = server.URL
+				issuer := kind.newIssuer(provisioner)
+				issuer.SetGeneration(3)
+				issuer.SetConditions(tt.conditions)
+
+				objects := []client.Object{issuer}
+				for _, secret := range tt.secrets {
+					secret = secret.DeepCopy()
+					secret.Namespace = kind.secretNamespace
+					objects = append(objects, secret)
+				}
+
+				scheme := newIssuerTestScheme(t)
+				fakeClient := fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(objects...).
+					WithStatusSubresource(issuer).
+					Build()
+
+				recorder := record.NewFakeRecorder(10)
+				reconciler := &IssuerReconciler{
+					Client:                   fakeClient,
+					Scheme:                   scheme,
+					Recorder:                 recorder,
+					ForObject:                kind.newIssuer(v1alpha1.DigicloudIssuerProvisioner{}),
+					ClusterResourceNamespace: testClusterResourceNamespace,
+				}
+
+				result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(issuer),
+				})
+				if tt.wantErr != "" {
+					assert.ErrorContains(t, err, tt.wantErr)
+					require.Len(t, recorder.Events, 1)
+					assert.Contains(t, <-recorder.Events, "Warning "+EventReasonValidationFailed)
+				} else {
+					assert.NoError(t, err)
+					require.Len(t, recorder.Events, 1)
+					assert.Contains(t, <-recorder.Events, "Normal "+EventReasonCredentialsValidated)
+				}
+
+				updated := kind.newIssuer(v1alpha1.DigicloudIssuerProvisioner{})
+				require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+				require.Len(t, updated.GetConditions(), 1)
+				condition := updated.GetConditions()[0]
+				assert.Equal(t, cmapi.IssuerConditionReady, condition.Type)
+				assert.Equal(t, tt.wantStatus, condition.Status)
+				assert.Equal(t, tt.wantReason, condition.Reason)
+				assert.NotNil(t, condition.LastTransitionTime)
+				assert.Equal(t, int64(3), condition.ObservedGeneration)
+				assert.Equal(t, int64(3), updated.GetObservedGeneration())
+				assert.Contains(t, updated.GetFinalizers(), IssuerFinalizer)
+
+				wantReady := 0.0
+				if tt.wantStatus == cmmeta.ConditionTrue {
+					wantReady = 1
+				}
+				assert.Equal(t, wantReady, testutil.ToFloat64(metrics.IssuerReady.WithLabelValues(kind.name, "default", "test-issuer")))
+
+				status := issuerStatus(t, updated)
+				if tt.wantErr != "" {
+					assert.Nil(t, status.LastAPICheckTime)
+					assert.Empty(t, status.Zones)
+					return
+				}
+				assert.Equal(t, apiCheckInterval, result.RequeueAfter)
+				assert.NotNil(t, status.LastAPICheckTime)
+				assert.Equal(t, tt.wantNamespace, status.DigicloudNamespace)
+				assert.Equal(t, []v1alpha1.ZoneStatus{
+					{Name: "example.com", ID: "dom-1", Namespace: "default", NSVerification: "verified"},
+					{Name: "example.org", ID: "dom-2", Namespace: "default", NSVerification: "pending"},
+				}, status.Zones)
+			})
+		}
+	}
+}
+
+func TestIssuerReconciler_Reconcile_APICheckFailed(t *testing.T) {
+	server := newDomainsServer(t, http.StatusUnauthorized)
+	lastCheck := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	zones := []v1alpha1.ZoneStatus{{Name: "example.com", ID: "dom-1", Namespace: "default"}}
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+			APIBaseURL:        server.URL,
+			APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+		}},
+		Status: v1alpha1.DigicloudIssuerStatus{
+			DigicloudNamespace: "default",
+			Zones:              zones,
+			LastAPICheckTime:   &lastCheck,
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("test-token")},
+	}
+
+	scheme := newIssuerTestScheme(t)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer, secret).
+		WithStatusSubresource(issuer).
+		Build()
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ForObject: &v1alpha1.DigicloudIssuer{},
+	}
+
+	result, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(issuer),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, apiCheckRetryInterval, result.RequeueAfter)
+
+	require.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Normal "+EventReasonCredentialsValidated)
+	assert.Contains(t, <-recorder.Events, "Warning "+EventReasonAPICheckFailed)
+
+	// The issuer stays ready and keeps the result of the last successful check
+	updated := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, cmmeta.ConditionTrue, updated.Status.Conditions[0].Status)
+	assert.Equal(t, zones, updated.Status.Zones)
+	assert.True(t, lastCheck.Equal(updated.Status.LastAPICheckTime))
+}
+
+// newDomainsServer returns a Digicloud API server listing two domains, or failing
+// with status if it is not http.StatusOK
+func newDomainsServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK || r.URL.Path != "/v1/edge/domains" {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"id": "dom-2", "name": "example.org", "ns_verification": "pending"},
+			{"id": "dom-1", "name": "example.com", "ns_verification": "verified"}
+		]`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// issuerStatus returns the status of a DigicloudIssuer or DigicloudClusterIssuer
+func issuerStatus(t *testing.T, issuer IssuerObject) v1alpha1.DigicloudIssuerStatus {
+	t.Helper()
+
+	switch issuer := issuer.(type) {
+	case *v1alpha1.DigicloudIssuer:
+		return issuer.Status
+	case *v1alpha1.DigicloudClusterIssuer:
+		return v1alpha1.DigicloudIssuerStatus(issuer.Status)
+	}
+	t.Fatalf("unsupported issuer type %T", issuer)
+	return v1alpha1.DigicloudIssuerStatus{}
+}
+
+func TestIssuerReconciler_Reconcile_StaleResourceVersion(t *testing.T) {
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+	}
+
+	scheme := newIssuerTestScheme(t)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// Another writer updates the issuer between the reconciler's read and its
+			// status write, which fails an update with a conflict
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if err := c.Get(ctx, key, obj, opts...); err != nil {
+					return err
+				}
+				concurrent := &v1alpha1.DigicloudIssuer{}
+				if err := c.Get(ctx, key, concurrent); err != nil {
+					return err
+				}
+				concurrent.Status.ChallengeDelegations = []v1alpha1.ChallengeDelegation{{DNSName: "example.com"}}
+				return c.Status().Update(ctx, concurrent)
+			},
+		}).
+		Build()
+
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		Recorder:  record.NewFakeRecorder(10),
+		ForObject: &v1alpha1.DigicloudIssuer{},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(issuer),
+	})
+	assert.ErrorContains(t, err, "API token secret")
+
+	updated := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, cmmeta.ConditionFalse, updated.Status.Conditions[0].Status)
+	assert.Len(t, updated.Status.ChallengeDelegations, 1)
+}
+
+func TestIssuerReconciler_Reconcile_ConcurrentCondition(t *testing.T) {
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+	}
+
+	scheme := newIssuerTestScheme(t)
+	concurrentWrites := 0
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		WithInterceptorFuncs(interceptor.Funcs{
+			// The signer records the DNSSEC condition between the reconciler's read
+			// and its status patch, which must not drop it
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if concurrentWrites == 0 {
+					concurrentWrites++
+					concurrent := &v1alpha1.DigicloudIssuer{}
+					if err := c.Get(ctx, client.ObjectKeyFromObject(obj), concurrent); err != nil {
+						return err
+					}
+					setCondition(concurrent, IssuerConditionDNSSEC, cmmeta.ConditionFalse, ReasonDNSSECMismatch, "stale DS")
+					if err := c.Status().Update(ctx, concurrent); err != nil {
+						return err
+					}
+				}
+				return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		Recorder:  record.NewFakeRecorder(10),
+		ForObject: &v1alpha1.DigicloudIssuer{},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(issuer),
+	})
+	assert.ErrorContains(t, err, "API token secret")
+
+	updated := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKeyFromObject(issuer), updated))
+	require.Len(t, updated.Status.Conditions, 2)
+	ready := readyCondition(updated)
+	require.NotNil(t, ready)
+	assert.Equal(t, cmmeta.ConditionFalse, ready.Status)
+	assert.Equal(t, IssuerConditionDNSSEC, updated.Status.Conditions[0].Type)
+	assert.Equal(t, ReasonDNSSECMismatch, updated.Status.Conditions[0].Reason)
+}
+
+func TestSetReadyCondition(t *testing.T) {
+	transitioned := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name               string
+		existing           []cmapi.IssuerCondition
+		status             cmmeta.ConditionStatus
+		keepTransitionTime bool
+	}{
+		{
+			name:   "new condition",
+			status: cmmeta.ConditionTrue,
+		},
+		{
+			name: "unchanged status",
+			existing: []cmapi.IssuerCondition{{
+				Type:               cmapi.IssuerConditionReady,
+				Status:             cmmeta.ConditionTrue,
+				Reason:             "Checked",
+				LastTransitionTime: &transitioned,
+				ObservedGeneration: 1,
+			}},
+			status:             cmmeta.ConditionTrue,
+			keepTransitionTime: true,
+		},
+		{
+			name: "status flip",
+			existing: []cmapi.IssuerCondition{{
+				Type:               cmapi.IssuerConditionReady,
+				Status:             cmmeta.ConditionFalse,
+				Reason:             "Failed",
+				LastTransitionTime: &transitioned,
+				ObservedGeneration: 1,
+			}},
+			status: cmmeta.ConditionTrue,
+		},
+	}
+
+	for _, kind := range issuerKinds {
+		for _, tt := range tests {
+			t.Run(kind.name+"/"+tt.name, func(t *testing.T) {
+				issuer := kind.newIssuer(v1alpha1.DigicloudIssuerProvisioner{})
+				issuer.SetGeneration(2)
+				issuer.SetConditions(tt.existing)
+
+				setReadyCondition(issuer, tt.status, "Checked", "Issuer configuration is valid")
+
+				require.Len(t, issuer.GetConditions(), 1)
+				condition := issuer.GetConditions()[0]
+				assert.Equal(t, tt.status, condition.Status)
+				assert.Equal(t, "Issuer configuration is valid", condition.Message)
+				assert.Equal(t, int64(2), condition.ObservedGeneration)
+				require.NotNil(t, condition.LastTransitionTime)
+				if tt.keepTransitionTime {
+					assert.Equal(t, transitioned, *condition.LastTransitionTime)
+				} else {
+					assert.NotEqual(t, transitioned, *condition.LastTransitionTime)
+				}
+			})
+		}
+	}
+}
+
+func TestIssuerReconciler_Reconcile_InvalidNamespaceSelector(t *testing.T) {
+	issuer := &v1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudClusterIssuerSpec{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Matches"}},
+			},
+		},
+	}
+
+	scheme := newIssuerTestScheme(t)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(issuer).
+		WithStatusSubresource(issuer).
+		Build()
+
+	reconciler := &IssuerReconciler{
+		Client:    fakeClient,
+		Scheme:    scheme,
+		Recorder:  record.NewFakeRecorder(10),
+		ForObject: &v1alpha1.DigicloudClusterIssuer{},
+	}
+
+	_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+		NamespacedName: client.ObjectKeyFromObject(issuer),
+	})
+	assert.ErrorContains(t, err, "invalid namespaceSelector")
+}
+
+func TestIssuerSecretNamespace(t *testing.T) {
+	tests := []struct {
+		name                     string
+		issuer                   client.Object
+		clusterResourceNamespace string
+		want                     string
+	}{
+		{
+			name:                     "issuer namespace",
+			issuer:                   &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a"}},
+			clusterResourceNamespace: testClusterResourceNamespace,
+			want:                     "team-a",
+		},
+		{
+			name:                     "cluster resource namespace",
+			issuer:                   &v1alpha1.DigicloudClusterIssuer{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a"}},
+			clusterResourceNamespace: testClusterResourceNamespace,
+			want:                     testClusterResourceNamespace,
+		},
+		{
+			name:   "default cluster resource namespace",
+			issuer: &v1alpha1.DigicloudClusterIssuer{},
+			want:   DefaultClusterResourceNamespace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, issuerSecretNamespace(tt.issuer, tt.clusterResourceNamespace))
+		})
+	}
+}
+
+func newIssuerTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, cmapi.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestRevalidatePredicate(t *testing.T) {
+	issuer := func(annotations map[string]string) *v1alpha1.DigicloudIssuer {
+		return &v1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "issuer", Namespace: "default", Annotations: annotations}}
+	}
+	p := revalidatePredicate()
+
+	assert.True(t, p.Update(event.UpdateEvent{
+		ObjectOld: issuer(nil),
+		ObjectNew: issuer(map[string]string{RevalidateAnnotation: "2025-01-01T00:00:00Z"}),
+	}))
+	assert.True(t, p.Update(event.UpdateEvent{
+		ObjectOld: issuer(map[string]string{RevalidateAnnotation: "2025-01-01T00:00:00Z"}),
+		ObjectNew: issuer(map[string]string{RevalidateAnnotation: "2025-01-02T00:00:00Z"}),
+	}))
+	assert.False(t, p.Update(event.UpdateEvent{
+		ObjectOld: issuer(map[string]string{RevalidateAnnotation: "2025-01-01T00:00:00Z"}),
+		ObjectNew: issuer(map[string]string{RevalidateAnnotation: "2025-01-01T00:00:00Z", "other": "value"}),
+	}))
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+const (
+	// ReasonDomainNotAllowed is the CertificateRequest Denied condition reason used
+	// when a requested DNS name does not match the issuer's allowedDomains
+	ReasonDomainNotAllowed = "DomainNotAllowed"
+
+	// ReasonNamespaceNotAllowed is the CertificateRequest Denied condition reason used
+	// when a request's namespace does not match the cluster issuer's namespaceSelector
+	ReasonNamespaceNotAllowed = "NamespaceNotAllowed"
+
+	// ReasonUnsupportedSubjectAltName is the CertificateRequest Denied condition reason
+	// used when a request has IP address, URI or email subject alternative names,
+	// which DNS-01 challenges cannot validate
+	ReasonUnsupportedSubjectAltName = "UnsupportedSubjectAltName"
+)
+
+// requestedDNSNames returns the DNS names of the request template, including its
+// common name, which the ACME server validates as a DNS name too. Requests with
+// other subject alternative names are denied.
+func requestedDNSNames(template *x509.Certificate) ([]string, error) {
+	var unsupported []string
+	for _, ip := range template.IPAddresses {
+		unsupported = append(unsupported, "IP address "+ip.String())
+	}
+	for _, uri := range template.URIs {
+		unsupported = append(unsupported, "URI "+uri.String())
+	}
+	for _, email := range template.EmailAddresses {
+		unsupported = append(unsupported, "email address "+email)
+	}
+	if len(unsupported) > 0 {
+		return nil, deniedError(fmt.Errorf("only DNS names can be validated with DNS-01 challenges, the request also has %s",
+			strings.Join(unsupported, ", ")), ReasonUnsupportedSubjectAltName)
+	}
+
+	dnsNames := template.DNSNames
+	if cn := template.Subject.CommonName; cn != "" && !slices.ContainsFunc(dnsNames, func(dnsName string) bool {
+		return dnsname.Equal(dnsName, cn)
+	}) {
+		dnsNames = append(slices.Clip(dnsNames), cn)
+	}
+	return dnsNames, nil
+}
+
+// checkPolicy denies requests for DNS names outside the issuer's allowedDomains and,
+// for cluster issuers, requests from namespaces outside its namespaceSelector
+func (s *DigicloudSigner) checkPolicy(ctx context.Context, cr signer.CertificateRequestObject, issuerObj client.Object, dnsNames []string) error {
+	if clusterIssuer, ok := issuerObj.(*digicloudv1alpha1.DigicloudClusterIssuer); ok {
+		allowed, err := s.namespaceAllowed(ctx, clusterIssuer.Spec.NamespaceSelector, cr.GetNamespace())
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return deniedError(fmt.Errorf("namespace %s is not allowed to use cluster issuer %s", cr.GetNamespace(), clusterIssuer.Name), ReasonNamespaceNotAllowed)
+		}
+	}
+
+	matchers, err := compileAllowedDomains(s.issuerSpec.AllowedDomains)
+	if err != nil {
+		return signer.PermanentError{Err: err}
+	}
+	for _, dnsName := range dnsNames {
+		if !domainAllowed(matchers, dnsName) {
+			return deniedError(fmt.Errorf("DNS name %s is not allowed by the issuer's allowedDomains [%s]", dnsName, strings.Join(s.issuerSpec.AllowedDomains, ", ")), ReasonDomainNotAllowed)
+		}
+	}
+
+	return nil
+}
+
+// namespaceAllowed reports whether the labels of namespace match selector
+func (s *DigicloudSigner) namespaceAllowed(ctx context.Context, selector *metav1.LabelSelector, namespace string) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, signer.PermanentError{Err: fmt.Errorf("invalid namespaceSelector: %w", err)}
+	}
+
+	var ns corev1.Namespace
+	if err := s.client.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return false, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+
+	return labelSelector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// compileAllowedDomains compiles allowedDomains entries with dnsname.CompilePattern
+func compileAllowedDomains(allowedDomains []string) ([]*regexp.Regexp, error) {
+	matchers := make([]*regexp.Regexp, 0, len(allowedDomains))
+	for _, entry := range allowedDomains {
+		matcher, err := dnsname.CompilePattern(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowedDomains entry %q: %w", entry, err)
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+// domainAllowed reports whether dnsName matches one of matchers; all names are
+// allowed when there are no matchers
+func domainAllowed(matchers []*regexp.Regexp, dnsName string) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+
+	dnsName = strings.TrimSuffix(dnsName, ".")
+	for _, matcher := range matchers {
+		if matcher.MatchString(dnsName) {
+			return true
+		}
+	}
+	return false
+}
+
+// deniedError marks a CertificateRequest as Denied with reason and fails it permanently
+func deniedError(err error, reason string) error {
+	return signer.PermanentError{
+		Err: signer.SetCertificateRequestConditionError{
+			Err:           err,
+			ConditionType: cmapi.CertificateRequestConditionDenied,
+			Status:        cmmeta.ConditionTrue,
+			Reason:        reason,
+		},
+	}
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+func TestDomainAllowed(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedDomains []string
+		dnsName        string
+		allowed        bool
+	}{
+		{name: "no allowlist", dnsName: "www.example.com", allowed: true},
+		{name: "exact match", allowedDomains: []string{"example.com"}, dnsName: "example.com", allowed: true},
+		{name: "case-insensitive", allowedDomains: []string{"Example.COM"}, dnsName: "example.com.", allowed: true},
+		{name: "glob matches one label", allowedDomains: []string{"*.example.com"}, dnsName: "www.example.com", allowed: true},
+		{name: "glob matches a wildcard name", allowedDomains: []string{"*.example.com"}, dnsName: "*.example.com", allowed: true},
+		{name: "glob does not cross labels", allowedDomains: []string{"*.example.com"}, dnsName: "a.b.example.com"},
+		{name: "glob does not match the apex", allowedDomains: []string{"*.example.com"}, dnsName: "example.com"},
+		{name: "double star crosses labels", allowedDomains: []string{"**.example.com"}, dnsName: "a.b.example.com", allowed: true},
+		{name: "suffix is not a match", allowedDomains: []string{"*.example.com"}, dnsName: "www.example.com.evil.net"},
+		{name: "dots are literal", allowedDomains: []string{"www.example.com"}, dnsName: "wwwxexample.com"},
+		{name: "regular expression", allowedDomains: []string{`/(www|api)\.example\.com/`}, dnsName: "api.example.com", allowed: true},
+		{name: "regular expression is anchored", allowedDomains: []string{`/example\.com/`}, dnsName: "www.example.com.evil.net"},
+		{name: "any entry matches", allowedDomains: []string{"example.org", "*.example.com"}, dnsName: "www.example.com", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := compileAllowedDomains(tt.allowedDomains)
+			require.NoError(t, err)
+			assert.Equal(t, tt.allowed, domainAllowed(matchers, tt.dnsName))
+		})
+	}
+}
+
+func TestCompileAllowedDomains_InvalidRegexp(t *testing.T) {
+	_, err := compileAllowedDomains([]string{"/(example/"})
+	assert.Error(t, err)
+}
+
+func TestDigicloudSigner_Sign_Denied(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"team": "web"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox"}},
+		).
+		Build()
+
+	issuer := &v1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: v1alpha1.DigicloudClusterIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				AllowedDomains:    []string{"*.example.com"},
+			},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		csr       *x509.CertificateRequest
+		reason    string
+	}{
+		{
+			name:      "namespace not selected",
+			namespace: "sandbox",
+			csr:       &x509.CertificateRequest{DNSNames: []string{"www.example.com"}},
+			reason:    ReasonNamespaceNotAllowed,
+		},
+		{
+			name:      "DNS name not allowed",
+			namespace: "default",
+			csr:       &x509.CertificateRequest{DNSNames: []string{"www.example.com", "www.example.org"}},
+			reason:    ReasonDomainNotAllowed,
+		},
+		{
+			name:      "common name not allowed",
+			namespace: "default",
+			csr: &x509.CertificateRequest{
+				Subject:  pkix.Name{CommonName: "www.example.org"},
+				DNSNames: []string{"www.example.com"},
+			},
+			reason: ReasonDomainNotAllowed,
+		},
+		{
+			name:      "IP address",
+			namespace: "default",
+			csr: &x509.CertificateRequest{
+				DNSNames:    []string{"www.example.com"},
+				IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+			},
+			reason: ReasonUnsupportedSubjectAltName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "digicloud-issuer-system")
+
+			cr := newTestCertificateRequestFor(t, tt.csr)
+			cr.Namespace = tt.namespace
+			_, err := s.Sign(context.Background(), signer.CertificateRequestObjectFromCertificateRequest(cr), issuer)
+
+			assert.True(t, errors.As(err, &signer.PermanentError{}))
+			var conditionErr signer.SetCertificateRequestConditionError
+			require.True(t, errors.As(err, &conditionErr))
+			assert.Equal(t, cmapi.CertificateRequestConditionDenied, conditionErr.ConditionType)
+			assert.Equal(t, cmmeta.ConditionTrue, conditionErr.Status)
+			assert.Equal(t, tt.reason, conditionErr.Reason)
+		})
+	}
+}
+
+func TestRequestedDNSNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "DNS names",
+			template: &x509.Certificate{DNSNames: []string{"example.com", "*.example.com"}},
+			expected: []string{"example.com", "*.example.com"},
+		},
+		{
+			name: "common name among DNS names",
+			template: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "WWW.example.com."},
+				DNSNames: []string{"www.example.com"},
+			},
+			expected: []string{"www.example.com"},
+		},
+		{
+			name: "common name only",
+			template: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "www.example.com"},
+				DNSNames: []string{"example.com"},
+			},
+			expected: []string{"example.com", "www.example.com"},
+		},
+		{
+			name: "IP address, URI and email address",
+			template: &x509.Certificate{
+				DNSNames:       []string{"example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("192.0.2.1")},
+				URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/web"}},
+				EmailAddresses: []string{"admin@example.com"},
+			},
+			wantErr: "IP address 192.0.2.1, URI spiffe://example.com/web, email address admin@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dnsNames, err := requestedDNSNames(tt.template)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				var conditionErr signer.SetCertificateRequestConditionError
+				require.True(t, errors.As(err, &conditionErr))
+				assert.Equal(t, ReasonUnsupportedSubjectAltName, conditionErr.Reason)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, dnsNames)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	issuerlibv1alpha1 "github.com/cert-manager/issuer-lib/api/v1alpha1"
+	issuerlib "github.com/cert-manager/issuer-lib/controllers"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/health"
+)
+
+const (
+	// DefaultMaxConcurrentSigns is how many CertificateRequests are signed at once
+	// unless configured otherwise. Signing blocks its worker until the challenges
+	// are validated.
+	DefaultMaxConcurrentSigns = 10
+
+	// DefaultMaxRetryDuration is how long after its creation a CertificateRequest
+	// that fails to be signed is retried unless configured otherwise
+	DefaultMaxRetryDuration = 10 * time.Minute
+
+	// fieldOwner is the field manager issuer-lib patches CertificateRequest status with
+	fieldOwner = "digicloud-issuer"
+)
+
+var (
+	_ issuerlibv1alpha1.Issuer = &digicloudv1alpha1.DigicloudIssuer{}
+	_ issuerlibv1alpha1.Issuer = &digicloudv1alpha1.DigicloudClusterIssuer{}
+)
+
+// SignerController signs approved CertificateRequests referencing a Digicloud
+// issuer through issuer-lib's CertificateRequest controller. Issuer status is left
+// to IssuerReconciler, whose Ready condition issuer-lib waits for.
+type SignerController struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// ClusterResourceNamespace is the namespace secrets referenced by cluster issuers
+	// are read from. Defaults to DefaultClusterResourceNamespace.
+	ClusterResourceNamespace string
+
+	// Watchdog tracks signing so that a wedged worker fails the liveness check.
+	// Optional.
+	Watchdog *health.Watchdog
+
+	// Nameservers are the recursive nameservers challenge propagation is checked
+	// with. Defaults to the nameservers in /etc/resolv.conf.
+	Nameservers []string
+
+	// RecursiveNameserversOnly checks challenge propagation with Nameservers only,
+	// without querying the authoritative nameservers of the zone
+	RecursiveNameserversOnly bool
+
+	// MaxConcurrentSigns is how many CertificateRequests are signed at once.
+	// Defaults to DefaultMaxConcurrentSigns.
+	MaxConcurrentSigns int
+
+	// MaxRetryDuration is how long after its creation a CertificateRequest that
+	// fails to be signed is retried. Defaults to DefaultMaxRetryDuration.
+	MaxRetryDuration time.Duration
+
+	// ClusterID is stamped into the note of the challenge records presented, see
+	// ClusterID. Optional.
+	ClusterID string
+}
+
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificaterequests/status,verbs=patch
+
+// Sign signs cr with a DigicloudSigner configured from issuerObject
+func (r *SignerController) Sign(ctx context.Context, cr signer.CertificateRequestObject, issuerObject issuerlibv1alpha1.Issuer) (signer.PEMBundle, error) {
+	issuer, ok := issuerObject.(IssuerObject)
+	if !ok {
+		return signer.PEMBundle{}, signer.PermanentError{Err: fmt.Errorf("unexpected issuer type %T", issuerObject)}
+	}
+
+	s := NewDigicloudSigner(r.Client, *issuer.GetProvisioner(), r.ClusterResourceNamespace)
+	s.SetEventRecorder(r.Recorder)
+	s.SetWatchdog(r.Watchdog)
+	s.SetNameservers(r.Nameservers)
+	s.SetRecursiveNameserversOnly(r.RecursiveNameserversOnly)
+	s.SetClusterID(r.ClusterID)
+	return s.Sign(ctx, cr, issuer)
+}
+
+// SetupWithManager sets up issuer-lib's controllers with the Manager. Its issuer
+// controllers ignore every issuer, and Kubernetes CertificateSigningRequests are
+// not signed.
+func (r *SignerController) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	maxConcurrentSigns := r.MaxConcurrentSigns
+	if maxConcurrentSigns == 0 {
+		maxConcurrentSigns = DefaultMaxConcurrentSigns
+	}
+	maxRetryDuration := r.MaxRetryDuration
+	if maxRetryDuration == 0 {
+		maxRetryDuration = DefaultMaxRetryDuration
+	}
+
+	return (&issuerlib.CombinedController{
+		IssuerTypes:        []issuerlibv1alpha1.Issuer{&digicloudv1alpha1.DigicloudIssuer{}},
+		ClusterIssuerTypes: []issuerlibv1alpha1.Issuer{&digicloudv1alpha1.DigicloudClusterIssuer{}},
+		FieldOwner:         fieldOwner,
+		MaxRetryDuration:   maxRetryDuration,
+		Sign:               r.Sign,
+		IgnoreIssuer: func(context.Context, issuerlibv1alpha1.Issuer) (bool, error) {
+			return true, nil
+		},
+		EventRecorder:                  r.Recorder,
+		DisableKubernetesCSRController: true,
+		// issuer-lib's controllers are named after the kinds they reconcile, as are
+		// IssuerReconciler and CertificateRequestReconciler
+		PreSetupWithManager: func(_ context.Context, gvk schema.GroupVersionKind, _ ctrl.Manager, b *builder.Builder) error {
+			b.Named("issuer-lib-" + strings.ToLower(gvk.Kind))
+			if gvk.Kind == "CertificateRequest" {
+				b.WithOptions(controller.Options{MaxConcurrentReconciles: maxConcurrentSigns})
+			}
+			return nil
+		},
+	}).SetupWithManager(ctx, mgr)
+}
+
+// ClusterID returns the UID of the kube-system namespace, which identifies the
+// cluster the challenge records of its issuers are created for
+func ClusterID(ctx context.Context, c client.Reader) (string, error) {
+	var namespace corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: metav1.NamespaceSystem}, &namespace); err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", metav1.NamespaceSystem, err)
+	}
+	return string(namespace.UID), nil
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	issuerlibv1alpha1 "github.com/cert-manager/issuer-lib/api/v1alpha1"
+	issuerlib "github.com/cert-manager/issuer-lib/controllers"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+func TestClusterID(t *testing.T) {
+	_, err := ClusterID(context.Background(), fake.NewClientBuilder().Build())
+	assert.Error(t, err)
+
+	kubeSystem := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "3f2c9a4e"}}
+	clusterID, err := ClusterID(context.Background(), fake.NewClientBuilder().WithObjects(kubeSystem).Build())
+	require.NoError(t, err)
+	assert.Equal(t, "3f2c9a4e", clusterID)
+}
+
+func TestSigningFailedCondition(t *testing.T) {
+	cr := newTestCertificateRequest(t, "example.com")
+	cr.Spec.IssuerRef = cmmeta.ObjectReference{Name: "test-issuer", Kind: "DigicloudIssuer", Group: digicloudv1alpha1.GroupVersion.Group}
+	issuer := &digicloudv1alpha1.DigicloudIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"}}
+	c := newSigningClient(t, issuer, cr)
+
+	signed := reconcileSigning(t, c, func(context.Context, signer.CertificateRequestObject, client.Object) (signer.PEMBundle, error) {
+		return signer.PEMBundle{}, signingFailedError(assert.AnError, ReasonZoneNotDelegated)
+	}, cr)
+
+	// issuer-lib fails the Ready condition with its own reason, the reason of the
+	// failure is kept on the SigningFailed condition
+	ready := certificateRequestCondition(signed, cmapi.CertificateRequestConditionReady)
+	require.NotNil(t, ready)
+	assert.Equal(t, cmmeta.ConditionFalse, ready.Status)
+	assert.Equal(t, cmapi.CertificateRequestReasonFailed, ready.Reason)
+	assert.NotNil(t, signed.Status.FailureTime)
+
+	failed := certificateRequestCondition(signed, CertificateRequestConditionSigningFailed)
+	require.NotNil(t, failed)
+	assert.Equal(t, cmmeta.ConditionTrue, failed.Status)
+	assert.Equal(t, ReasonZoneNotDelegated, failed.Reason)
+	assert.Equal(t, assert.AnError.Error(), failed.Message)
+}
+
+// newSigningClient returns a fake client holding objects for reconcileSigning.
+// Issuers are marked Ready and CertificateRequests approved, and the server-side
+// apply patches issuer-lib writes CertificateRequest status with, which the fake
+// client does not support, are merged into the conditions by type.
+func newSigningClient(t *testing.T, objects ...client.Object) client.WithWatch {
+	t.Helper()
+
+	scheme := newIssuerTestScheme(t)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for gvk := range scheme.AllKnownTypes() {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	for _, object := range objects {
+		switch object := object.(type) {
+		case IssuerObject:
+			setReadyCondition(object, cmmeta.ConditionTrue, "Checked", "Issuer is ready")
+		case *cmapi.CertificateRequest:
+			object.CreationTimestamp = metav1.Now()
+			object.Status.Conditions = append(object.Status.Conditions, cmapi.CertificateRequestCondition{
+				Type: cmapi.CertificateRequestConditionApproved, Status: cmmeta.ConditionTrue, Reason: "Approved",
+			})
+		}
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithObjects(objects...).
+		WithStatusSubresource(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if patch.Type() != types.ApplyPatchType {
+					return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+				}
+				data, err := patch.Data(obj)
+				if err != nil {
+					return err
+				}
+				var applied cmapi.CertificateRequest
+				if err := json.Unmarshal(data, &applied); err != nil {
+					return err
+				}
+				var current cmapi.CertificateRequest
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &current); err != nil {
+					return err
+				}
+				for _, condition := range applied.Status.Conditions {
+					if existing := certificateRequestCondition(&current, condition.Type); existing != nil {
+						*existing = condition
+					} else {
+						current.Status.Conditions = append(current.Status.Conditions, condition)
+					}
+				}
+				if applied.Status.FailureTime != nil {
+					current.Status.FailureTime = applied.Status.FailureTime
+				}
+				if len(applied.Status.Certificate) > 0 {
+					current.Status.Certificate = applied.Status.Certificate
+					current.Status.CA = applied.Status.CA
+				}
+				return c.Status().Update(ctx, &current)
+			},
+		}).
+		Build()
+}
+
+// reconcileSigning reconciles cr with issuer-lib's CertificateRequest controller,
+// signing it with sign, until it is Ready or failed, and returns it
+func reconcileSigning(t *testing.T, c client.WithWatch, sign func(context.Context, signer.CertificateRequestObject, client.Object) (signer.PEMBundle, error), cr *cmapi.CertificateRequest) *cmapi.CertificateRequest {
+	t.Helper()
+
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{
+		Scheme:  c.Scheme(),
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		// Every test sets up a controller of the same name
+		Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+		NewClient: func(*rest.Config, client.Options) (client.Client, error) {
+			return c, nil
+		},
+		MapperProvider: func(*rest.Config, *http.Client) (meta.RESTMapper, error) {
+			return c.RESTMapper(), nil
+		},
+	})
+	require.NoError(t, err)
+
+	r := &issuerlib.CertificateRequestReconciler{RequestController: issuerlib.RequestController{
+		IssuerTypes:        []issuerlibv1alpha1.Issuer{&digicloudv1alpha1.DigicloudIssuer{}},
+		ClusterIssuerTypes: []issuerlibv1alpha1.Issuer{&digicloudv1alpha1.DigicloudClusterIssuer{}},
+		FieldOwner:         fieldOwner,
+		MaxRetryDuration:   DefaultMaxRetryDuration,
+		Client:             c,
+		Sign: func(ctx context.Context, cr signer.CertificateRequestObject, issuer issuerlibv1alpha1.Issuer) (signer.PEMBundle, error) {
+			return sign(ctx, cr, issuer)
+		},
+		EventRecorder: record.NewFakeRecorder(100),
+		Clock:         clock.RealClock{},
+	}}
+	require.NoError(t, r.SetupWithManager(context.Background(), mgr))
+
+	var updated cmapi.CertificateRequest
+	for range 5 {
+		_, _ = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+		require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(cr), &updated))
+		if ready := certificateRequestCondition(&updated, cmapi.CertificateRequestConditionReady); ready != nil && ready.Status != cmmeta.ConditionUnknown {
+			break
+		}
+	}
+	return &updated
+}
+
+func certificateRequestCondition(cr *cmapi.CertificateRequest, conditionType cmapi.CertificateRequestConditionType) *cmapi.CertificateRequestCondition {
+	for i := range cr.Status.Conditions {
+		if cr.Status.Conditions[i].Type == conditionType {
+			return &cr.Status.Conditions[i]
+		}
+	}
+	return nil
+}
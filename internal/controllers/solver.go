@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// defaultSolver is the index used for the solver made of the provisioner's own settings
+const defaultSolver = -1
+
+// newProviderSet selects the solver for each DNS name and returns a provider set
+// dispatching every challenge to a provider for the selected solver's account
+func (s *DigicloudSigner) newProviderSet(ctx context.Context, issuerObj client.Object, dnsNames []string, labels map[string]string) (*dnsprovider.ProviderSet, error) {
+	providers := dnsprovider.NewProviderSet()
+	bySolver := map[int]*dnsprovider.DigicloudProvider{}
+	selected := map[string]int{}
+
+	for _, dnsName := range dnsNames {
+		index := selectSolver(s.issuerSpec.Solvers, dnsName, labels)
+
+		// A wildcard shares its challenge record with the base domain, so both must
+		// be solved with the same account
+		key := dnsname.Key(dnsName)
+		if previous, ok := selected[key]; ok && previous != index {
+			return nil, fmt.Errorf("DNS names %s and *.%s share a challenge record but select different solvers", key, key)
+		}
+		selected[key] = index
+
+		provider, ok := bySolver[index]
+		if !ok {
+			var err error
+			provider, err = s.newProvider(ctx, issuerObj, s.solver(index))
+			if err != nil {
+				return nil, err
+			}
+			bySolver[index] = provider
+		}
+		providers.Add(dnsName, provider)
+	}
+
+	return providers, nil
+}
+
+// solver returns the solver at index, or a solver made of the provisioner's own
+// settings for defaultSolver
+func (s *DigicloudSigner) solver(index int) digicloudv1alpha1.DigicloudSolver {
+	if index != defaultSolver {
+		return s.issuerSpec.Solvers[index]
+	}
+	return digicloudv1alpha1.DigicloudSolver{
+		APIBaseURL:        s.issuerSpec.APIBaseURL,
+		APITokenSecretRef: s.issuerSpec.APITokenSecretRef,
+		Namespace:         s.issuerSpec.Namespace,
+		TTL:               s.issuerSpec.TTL,
+	}
+}
+
+// newProvider creates a DNS provider for the Digicloud account of solver
+func (s *DigicloudSigner) newProvider(ctx context.Context, issuerObj client.Object, solver digicloudv1alpha1.DigicloudSolver) (*dnsprovider.DigicloudProvider, error) {
+	if solver.APITokenSecretRef.Name == "" {
+		return nil, fmt.Errorf("no solver matches and the issuer has no API token secret reference")
+	}
+
+	apiToken, namespace, err := s.getAPIToken(ctx, issuerObj, solver.APITokenSecretRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	if solver.Namespace != "" {
+		namespace = solver.Namespace
+	}
+
+	ttl := s.getTTL()
+	if solver.TTL != nil {
+		ttl = *solver.TTL
+	}
+
+	apiBaseURL := solver.APIBaseURL
+	if apiBaseURL == "" {
+		apiBaseURL = s.issuerSpec.APIBaseURL
+	}
+
+	provider := dnsprovider.NewDigicloudProvider(apiBaseURL, apiToken, namespace, ttl)
+	provider.SetLogger(log.FromContext(ctx).WithName("dnsprovider").WithValues("issuer", klog.KObj(issuerObj), "digicloudNamespace", namespace))
+	provider.SetNSResolver(s.nsResolver)
+	provider.SetNameservers(s.nameservers)
+	provider.SetClusterID(s.clusterID)
+	provider.SetPropagationTimeout(s.getPropagationTimeout())
+	provider.SetFollowCNAME(s.issuerSpec.CNAMEStrategy == digicloudv1alpha1.FollowStrategy)
+
+	return provider, nil
+}
+
+// selectSolver returns the index of the most specific solver matching dnsName and
+// the CertificateRequest labels, or defaultSolver if none matches. Like cert-manager's
+// ACME solver selection, a dnsNames match beats the longest dnsZones match, which
+// beats the most matchLabels; ties are resolved by the order of the solvers.
+func selectSolver(solvers []digicloudv1alpha1.DigicloudSolver, dnsName string, labels map[string]string) int {
+	best := defaultSolver
+	var bestScore solverScore
+	for i, solver := range solvers {
+		score, ok := matchSolver(solver.Selector, dnsName, labels)
+		if !ok {
+			continue
+		}
+		if best == defaultSolver || score.beats(bestScore) {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// solverScore describes how specifically a solver matches a DNS name
+type solverScore struct {
+	dnsName bool
+	zoneLen int
+	labels  int
+}
+
+func (a solverScore) beats(b solverScore) bool {
+	if a.dnsName != b.dnsName {
+		return a.dnsName
+	}
+	if a.zoneLen != b.zoneLen {
+		return a.zoneLen > b.zoneLen
+	}
+	return a.labels > b.labels
+}
+
+// matchSolver reports whether selector matches dnsName and labels, and how specifically
+func matchSolver(selector *digicloudv1alpha1.SolverSelector, dnsName string, labels map[string]string) (solverScore, bool) {
+	var score solverScore
+	if selector == nil {
+		return score, true
+	}
+
+	for key, value := range selector.MatchLabels {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return score, false
+		}
+	}
+	score.labels = len(selector.MatchLabels)
+
+	for _, name := range selector.DNSNames {
+		if dnsname.Equal(name, dnsName) {
+			score.dnsName = true
+			break
+		}
+	}
+
+	base := dnsname.Key(dnsName)
+	for _, zone := range selector.DNSZones {
+		zone = dnsname.Key(zone)
+		if dnsname.InZone(base, zone) && len(zone) > score.zoneLen {
+			score.zoneLen = len(zone)
+		}
+	}
+
+	if len(selector.DNSNames) > 0 || len(selector.DNSZones) > 0 {
+		if !score.dnsName && score.zoneLen == 0 {
+			return score, false
+		}
+	}
+
+	return score, true
+}
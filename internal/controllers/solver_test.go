@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+)
+
+func TestSelectSolver(t *testing.T) {
+	solvers := []v1alpha1.DigicloudSolver{
+		{Selector: nil},
+		{Selector: &v1alpha1.SolverSelector{DNSZones: []string{"example.com"}}},
+		{Selector: &v1alpha1.SolverSelector{DNSZones: []string{"eu.example.com"}}},
+		{Selector: &v1alpha1.SolverSelector{DNSNames: []string{"shop.eu.example.com", "*.example.org"}}},
+		{Selector: &v1alpha1.SolverSelector{DNSZones: []string{"example.com"}, MatchLabels: map[string]string{"team": "payments"}}},
+		{Selector: &v1alpha1.SolverSelector{MatchLabels: map[string]string{"team": "search"}}},
+		{Selector: &v1alpha1.SolverSelector{DNSZones: []string{"مثال.ایران"}}},
+	}
+
+	tests := []struct {
+		name     string
+		solvers  []v1alpha1.DigicloudSolver
+		dnsName  string
+		labels   map[string]string
+		expected int
+	}{
+		{
+			name:     "no solvers",
+			dnsName:  "www.example.com",
+			expected: defaultSolver,
+		},
+		{
+			name:     "no selector matches everything",
+			solvers:  solvers,
+			dnsName:  "www.example.net",
+			expected: 0,
+		},
+		{
+			name:     "zone match",
+			solvers:  solvers,
+			dnsName:  "www.example.com",
+			expected: 1,
+		},
+		{
+			name:     "zone apex",
+			solvers:  solvers,
+			dnsName:  "example.com",
+			expected: 1,
+		},
+		{
+			name:     "longest zone wins",
+			solvers:  solvers,
+			dnsName:  "www.eu.example.com",
+			expected: 2,
+		},
+		{
+			name:     "wildcard matches its zone",
+			solvers:  solvers,
+			dnsName:  "*.eu.example.com",
+			expected: 2,
+		},
+		{
+			name:     "zone match ignores case and trailing dot",
+			solvers:  solvers,
+			dnsName:  "WWW.Example.COM.",
+			expected: 1,
+		},
+		{
+			name:     "IDN zone matches its A-label names",
+			solvers:  solvers,
+			dnsName:  "*.www.xn--mgbh0fb.xn--mgba3a4f16a",
+			expected: 6,
+		},
+		{
+			name:     "DNS name beats zone",
+			solvers:  solvers,
+			dnsName:  "shop.eu.example.com",
+			expected: 3,
+		},
+		{
+			name:     "wildcard DNS name",
+			solvers:  solvers,
+			dnsName:  "*.example.org",
+			expected: 3,
+		},
+		{
+			name:     "labels break a zone tie",
+			solvers:  solvers,
+			dnsName:  "pay.example.com",
+			labels:   map[string]string{"team": "payments"},
+			expected: 4,
+		},
+		{
+			name:     "labels only",
+			solvers:  solvers,
+			dnsName:  "www.example.net",
+			labels:   map[string]string{"team": "search"},
+			expected: 5,
+		},
+		{
+			name:     "zone beats labels",
+			solvers:  solvers,
+			dnsName:  "www.example.com",
+			labels:   map[string]string{"team": "search"},
+			expected: 1,
+		},
+		{
+			name:     "no selector matches",
+			solvers:  solvers[1:2],
+			dnsName:  "www.example.net",
+			expected: defaultSolver,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, selectSolver(tt.solvers, tt.dnsName, tt.labels))
+		})
+	}
+}
+
+func TestDigicloudSigner_NewProviderSet(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "retail-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"token": []byte("retail-token"), "namespace": []byte("retail")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "media-credentials", Namespace: "default"},
+				Data:       map[string][]byte{"token": []byte("media-token")},
+			},
+		).
+		Build()
+
+	issuer := &v1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1alpha1.DigicloudIssuerSpec{
+			Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+				Solvers: []v1alpha1.DigicloudSolver{
+					{
+						Selector:          &v1alpha1.SolverSelector{DNSZones: []string{"retail.example"}},
+						APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "retail-credentials", Key: "token"},
+					},
+					{
+						Selector:          &v1alpha1.SolverSelector{DNSZones: []string{"media.example"}},
+						APITokenSecretRef: v1alpha1.SecretKeySelector{Name: "media-credentials", Key: "token"},
+						Namespace:         "media",
+					},
+				},
+			},
+		},
+	}
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+
+	providers, err := s.newProviderSet(context.Background(), issuer, []string{"retail.example", "*.retail.example", "www.media.example"}, nil)
+	require.NoError(t, err)
+
+	retail, err := providers.For("*.retail.example")
+	require.NoError(t, err)
+	media, err := providers.For("www.media.example")
+	require.NoError(t, err)
+	assert.NotSame(t, retail, media)
+
+	// Names no solver matches fall back to the provisioner, which has no token here
+	_, err = s.newProviderSet(context.Background(), issuer, []string{"www.example.com"}, nil)
+	assert.Error(t, err)
+}
+
+func TestDigicloudSigner_NewProviderSet_ConvertedNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("token"), "namespace": []byte("retail")},
+		}).
+		Build()
+
+	hub := &v1beta1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-issuer", Namespace: "default"},
+		Spec: v1beta1.DigicloudIssuerSpec{
+			Digicloud: &v1beta1.DigicloudAccount{
+				APITokenSecretRef: v1beta1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				Namespace:         "media",
+			},
+		},
+	}
+	issuer := &v1alpha1.DigicloudIssuer{}
+	require.NoError(t, issuer.ConvertFrom(hub))
+	s := NewDigicloudSigner(fakeClient, issuer.Spec.Provisioner, "")
+
+	providers, err := s.newProviderSet(context.Background(), issuer, []string{"www.media.example"}, nil)
+	require.NoError(t, err)
+	provider, err := providers.For("www.media.example")
+	require.NoError(t, err)
+	assert.Equal(t, "media", provider.Namespace())
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+const (
+	// apiCheckInterval is how often the Digicloud API is checked for a ready issuer
+	apiCheckInterval = 5 * time.Minute
+
+	// apiCheckRetryInterval is how soon a failed Digicloud API check is retried
+	apiCheckRetryInterval = time.Minute
+
+	// apiCheckTimeout bounds a Digicloud API check of all accounts of an issuer
+	apiCheckTimeout = 30 * time.Second
+)
+
+// statusPatchBackoff retries status patches that conflict with another writer. Up to
+// DefaultMaxConcurrentSigns requests of an issuer patch its status at once, and the
+// jitter spreads their retries apart.
+var statusPatchBackoff = wait.Backoff{
+	Steps:    2 * DefaultMaxConcurrentSigns,
+	Duration: 10 * time.Millisecond,
+	Factor:   1.2,
+	Jitter:   1,
+}
+
+// updateIssuerStatus applies update to the issuer and merge-patches its status if
+// anything changed. A merge patch replaces lists such as the challenge records
+// whole, so the patch is conditional on the issuer's resource version; on a
+// conflict the issuer is read again and update is reapplied.
+func (s *DigicloudSigner) updateIssuerStatus(ctx context.Context, issuerObj client.Object, update func(issuer IssuerObject)) error {
+	issuer, ok := issuerObj.(IssuerObject)
+	if !ok {
+		return fmt.Errorf("unsupported issuer type %T", issuerObj)
+	}
+
+	stale := false
+	return retry.RetryOnConflict(statusPatchBackoff, func() error {
+		if stale {
+			if err := s.client.Get(ctx, client.ObjectKeyFromObject(issuer), issuer); err != nil {
+				return err
+			}
+		}
+		stale = true
+
+		original := issuer.DeepCopyObject().(IssuerObject)
+		update(issuer)
+		if equality.Semantic.DeepEqual(original, issuer) {
+			return nil
+		}
+		return s.client.Status().Patch(ctx, issuer, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// recordACMEAccount records the ACME account acmeClient registered or looked up in
+// the issuer status
+func (s *DigicloudSigner) recordACMEAccount(ctx context.Context, issuerObj client.Object, acmeClient *acme.Client) error {
+	uri, status := acmeClient.Account()
+	if uri == "" {
+		return nil
+	}
+	return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+		issuer.SetACMEAccount(&digicloudv1alpha1.ACMEAccountStatus{URI: uri, Status: status})
+	})
+}
+
+// recordDNSSEC sets the DNSSEC condition of the issuer from the preflight of a
+// request: False if the chain of trust of a zone is broken, True if the zones of all
+// targets were checked. Preflight failures for other reasons leave it unchanged.
+func (s *DigicloudSigner) recordDNSSEC(ctx context.Context, issuerObj client.Object, targets map[string]*dnsprovider.ChallengeTarget, preflightErr error) error {
+	var mismatch *dnsprovider.DNSSECMismatchError
+	switch {
+	case errors.As(preflightErr, &mismatch):
+		return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+			setCondition(issuer, IssuerConditionDNSSEC, cmmeta.ConditionFalse, ReasonDNSSECMismatch, mismatch.Error())
+		})
+	case preflightErr != nil:
+		return nil
+	}
+
+	var zones []string
+	for _, target := range targets {
+		if !slices.Contains(zones, target.Zone) {
+			zones = append(zones, target.Zone)
+		}
+	}
+	sort.Strings(zones)
+	return s.updateIssuerStatus(ctx, issuerObj, func(issuer IssuerObject) {
+		setCondition(issuer, IssuerConditionDNSSEC, cmmeta.ConditionTrue, ReasonDNSSECChecked,
+			"DNSSEC chain of trust is intact or DNSSEC is disabled for "+strings.Join(zones, ", "))
+	})
+}
+
+// setChallengeRecords replaces the challenge records of the issuer and counts them as
+// its in-flight challenges
+func setChallengeRecords(issuer IssuerObject, records []digicloudv1alpha1.ChallengeRecord) {
+	issuer.SetChallengeRecords(records)
+	issuer.SetInFlightChallenges(int32(len(records)))
+}
+
+// checkAPI lists the zones visible to every Digicloud account of the issuer, and
+// returns them along with the namespace of the issuer's own account
+func (r *IssuerReconciler) checkAPI(ctx context.Context, issuer IssuerObject) (string, []digicloudv1alpha1.ZoneStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiCheckTimeout)
+	defer cancel()
+
+	provisioner := *issuer.GetProvisioner()
+	s := NewDigicloudSigner(r.Client, provisioner, r.ClusterResourceNamespace)
+
+	var namespace string
+	var zones []digicloudv1alpha1.ZoneStatus
+	seen := map[digicloudv1alpha1.ZoneStatus]bool{}
+	var errs []error
+	for _, index := range s.solverIndexes() {
+		provider, err := s.newProvider(ctx, issuer, s.solver(index))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if index == defaultSolver {
+			namespace = provider.Namespace()
+		}
+
+		domains, err := provider.ListDomains(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, domain := range domains {
+			zone := digicloudv1alpha1.ZoneStatus{
+				Name:           domain.Name,
+				ID:             domain.ID,
+				Namespace:      provider.Namespace(),
+				NSVerification: domain.NSVerification,
+			}
+			if !seen[zone] {
+				seen[zone] = true
+				zones = append(zones, zone)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "", nil, err
+	}
+
+	sort.Slice(zones, func(i, j int) bool {
+		if zones[i].Name != zones[j].Name {
+			return zones[i].Name < zones[j].Name
+		}
+		return zones[i].Namespace < zones[j].Namespace
+	})
+	return namespace, zones, nil
+}
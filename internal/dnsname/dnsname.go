@@ -0,0 +1,235 @@
+// Package dnsname normalizes domain names and splits them into zones and record
+// names as the Digicloud API expects them.
+//
+// A normalized name is lower case, holds internationalized labels as punycode
+// A-labels and has no trailing dot, so that "WWW.Example.COM.", "www.example.com"
+// and their IDN forms compare equal. Names may start with a "*" wildcard label and
+// hold underscore labels such as "_acme-challenge", which IDNA does not allow and
+// which are therefore kept as ASCII labels outside of it.
+package dnsname
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	// Apex is the record name of the apex of a zone
+	Apex = "@"
+
+	// Wildcard is the label a wildcard name starts with
+	Wildcard = "*"
+
+	// maxLabelLength and maxNameLength are the limits of RFC 1035 on the length of
+	// a label and of a name without its trailing dot
+	maxLabelLength = 63
+	maxNameLength  = 253
+)
+
+// profile maps internationalized labels for lookup as defined by UTS 46. It is not
+// transitional, so that joiners such as the ZERO WIDTH NON-JOINER common in Persian
+// names are kept rather than dropped. The Bidi rule is checked per label, so that an
+// underscore or ASCII label does not make a right-to-left name invalid.
+var profile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+)
+
+// labelSeparators are the full stops UTS 46 maps to "."
+var labelSeparators = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+
+// Normalize returns name in normal form. A trailing dot is dropped, ASCII letters
+// are lower-cased and other labels are mapped to A-labels. A "*" label is only
+// allowed as the first label.
+func Normalize(name string) (string, error) {
+	name = labelSeparators.Replace(strings.TrimSpace(name))
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return "", errors.New("empty domain name")
+	}
+	if !utf8.ValidString(name) {
+		return "", fmt.Errorf("invalid domain name %q: not valid UTF-8", name)
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		normalized, err := normalizeLabel(label, i == 0)
+		if err != nil {
+			return "", fmt.Errorf("invalid domain name %q: %w", name, err)
+		}
+		labels[i] = normalized
+	}
+
+	normalized := strings.Join(labels, ".")
+	if len(normalized) > maxNameLength {
+		return "", fmt.Errorf("invalid domain name %q: longer than %d characters", name, maxNameLength)
+	}
+	return normalized, nil
+}
+
+// normalizeLabel returns a single label in normal form
+func normalizeLabel(label string, first bool) (string, error) {
+	switch {
+	case label == "":
+		return "", errors.New("empty label")
+	case label == Wildcard:
+		if !first {
+			return "", errors.New("wildcard label is only allowed first")
+		}
+		return label, nil
+	}
+
+	if isASCII(label) {
+		label = strings.ToLower(label)
+		if !strings.HasPrefix(label, "xn--") {
+			for _, c := range []byte(label) {
+				if !isLDH(c) && c != '_' {
+					return "", fmt.Errorf("label %q holds the invalid character %q", label, c)
+				}
+			}
+			return checkLength(label)
+		}
+	}
+
+	// Map U-labels and check A-labels, which are re-encoded from their U-label
+	ascii, err := profile.ToASCII(label)
+	if err != nil {
+		return "", err
+	}
+	if ascii == "" || strings.Contains(ascii, ".") {
+		return "", fmt.Errorf("label %q does not map to a single label", label)
+	}
+	if strings.HasPrefix(label, "xn--") && !strings.HasPrefix(ascii, "xn--") {
+		return "", fmt.Errorf("label %q is not a valid A-label", label)
+	}
+	return checkLength(ascii)
+}
+
+// checkLength returns label, or an error if it is too long
+func checkLength(label string) (string, error) {
+	if len(label) > maxLabelLength {
+		return "", fmt.Errorf("label %q is longer than %d characters", label, maxLabelLength)
+	}
+	return label, nil
+}
+
+// isASCII reports whether s only holds ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// isLDH reports whether c is a lower-case letter, a digit or a hyphen
+func isLDH(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-'
+}
+
+// Fqdn returns name in normal form with a trailing dot
+func Fqdn(name string) (string, error) {
+	normalized, err := Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	return normalized + ".", nil
+}
+
+// IsWildcard reports whether name starts with a "*" label
+func IsWildcard(name string) bool {
+	return strings.HasPrefix(strings.TrimSpace(name), Wildcard+".")
+}
+
+// TrimWildcard returns name without a leading "*" label
+func TrimWildcard(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), Wildcard+".")
+}
+
+// Key returns name in normal form without a wildcard label, which identifies the
+// challenge record of name. Names that cannot be normalized are only lower-cased
+// and stripped of their trailing dot, so that they still compare with themselves.
+func Key(name string) string {
+	if normalized, err := Normalize(name); err == nil {
+		return TrimWildcard(normalized)
+	}
+	return strings.ToLower(strings.TrimSuffix(TrimWildcard(name), "."))
+}
+
+// Equal reports whether a and b are the same name
+func Equal(a, b string) bool {
+	na, errA := Normalize(a)
+	nb, errB := Normalize(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+	}
+	return na == nb
+}
+
+// InZone reports whether name is zone or a name below it
+func InZone(name, zone string) bool {
+	_, err := RecordName(name, zone)
+	return err == nil
+}
+
+// Zone returns the zone name is registered in, which is the public suffix of name
+// and one more label: _acme-challenge.www.example.co.ir is in example.co.ir. A
+// wildcard label is ignored.
+func Zone(name string) (string, error) {
+	normalized, err := Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	normalized = TrimWildcard(normalized)
+	zone, err := publicsuffix.EffectiveTLDPlusOne(normalized)
+	if err != nil {
+		return "", fmt.Errorf("could not find the zone of %s: %w", normalized, err)
+	}
+	return zone, nil
+}
+
+// RecordName returns the name of the record for fqdn in zone, which is Apex for the
+// apex of zone: _acme-challenge.www.example.com in example.com is
+// _acme-challenge.www. It returns an error if fqdn is not in zone.
+func RecordName(fqdn, zone string) (string, error) {
+	name, err := Normalize(fqdn)
+	if err != nil {
+		return "", err
+	}
+	zone, err = Normalize(zone)
+	if err != nil {
+		return "", err
+	}
+
+	if name == zone {
+		return Apex, nil
+	}
+	if record, ok := strings.CutSuffix(name, "."+zone); ok {
+		return record, nil
+	}
+	return "", fmt.Errorf("%s is not in zone %s", name, zone)
+}
+
+// Join returns the FQDN, with a trailing dot, of the record named record in zone.
+// It is the inverse of RecordName.
+func Join(record, zone string) (string, error) {
+	record = strings.TrimSpace(record)
+	if record == Apex || record == "" {
+		return Fqdn(zone)
+	}
+	if strings.HasSuffix(record, ".") {
+		return "", fmt.Errorf("record name %q is not relative to zone %s", record, zone)
+	}
+	zone, err := Normalize(zone)
+	if err != nil {
+		return "", err
+	}
+	return Fqdn(record + "." + zone)
+}
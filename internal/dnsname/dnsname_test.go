@@ -0,0 +1,272 @@
+package dnsname
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seeds are the names the fuzz targets start from
+var seeds = []string{
+	"example.com",
+	"WWW.Example.COM.",
+	"*.example.com",
+	"_acme-challenge.www.example.com.",
+	"example.co.ir",
+	"bücher.de",
+	"xn--bcher-kva.de",
+	"XN--MGBA3A4F16A",
+	"مثال.ایران",
+	"*.فروشگاه.com",
+	"_acme-challenge.نمونه.ir",
+	"می‌خواهم.ایران",
+	"ｅｘａｍｐｌｅ．com",
+	"a..b",
+	"*.*.example.com",
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		expectError string
+	}{
+		{name: "lower case", input: "www.example.com", want: "www.example.com"},
+		{name: "upper case and trailing dot", input: "WWW.Example.COM.", want: "www.example.com"},
+		{name: "wildcard", input: "*.Example.com", want: "*.example.com"},
+		{name: "underscore label", input: "_ACME-Challenge.example.com", want: "_acme-challenge.example.com"},
+		{name: "IDN", input: "bücher.de", want: "xn--bcher-kva.de"},
+		{name: "upper case A-label", input: "XN--BCHER-KVA.de", want: "xn--bcher-kva.de"},
+		{name: "Persian IDN", input: "مثال.ایران", want: "xn--mgbh0fb.xn--mgba3a4f16a"},
+		{name: "Persian IDN challenge", input: "_acme-challenge.مثال.ایران.", want: "_acme-challenge.xn--mgbh0fb.xn--mgba3a4f16a"},
+		{name: "Persian IDN wildcard", input: "*.فروشگاه.com", want: "*.xn--mgbtj4c7ad63e.com"},
+		{name: "Persian zero width non-joiner is kept", input: "می‌خواهم.ایران", want: "xn--mgbn2ecje63gr19l.xn--mgba3a4f16a"},
+		{name: "full width", input: "ｅｘａｍｐｌｅ．com", want: "example.com"},
+		{name: "empty", input: ".", expectError: "empty domain name"},
+		{name: "empty label", input: "a..example.com", expectError: "empty label"},
+		{name: "inner wildcard", input: "www.*.example.com", expectError: "wildcard label is only allowed first"},
+		{name: "double wildcard", input: "*.*.example.com", expectError: "wildcard label is only allowed first"},
+		{name: "invalid character", input: "www.exa mple.com", expectError: "invalid character"},
+		{name: "invalid A-label", input: "xn--abc-.com", expectError: "not a valid A-label"},
+		{name: "long label", input: strings.Repeat("a", 64) + ".com", expectError: "longer than 63"},
+		{name: "long name", input: strings.Repeat("a.", 127) + "com", expectError: "longer than 253"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestZone(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        string
+		expectError string
+	}{
+		{name: "apex", input: "example.com", want: "example.com"},
+		{name: "challenge", input: "_acme-challenge.sub.Example.com.", want: "example.com"},
+		{name: "wildcard", input: "*.example.com", want: "example.com"},
+		{name: "second level suffix", input: "_acme-challenge.www.example.co.ir", want: "example.co.ir"},
+		{name: "Persian IDN", input: "_acme-challenge.مثال.ایران", want: "xn--mgbh0fb.xn--mgba3a4f16a"},
+		{name: "public suffix", input: "co.ir", expectError: "could not find the zone"},
+		{name: "single label", input: "localhost", expectError: "could not find the zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Zone(tt.input)
+
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRecordName(t *testing.T) {
+	tests := []struct {
+		name        string
+		fqdn        string
+		zone        string
+		want        string
+		expectError string
+	}{
+		{name: "challenge", fqdn: "_acme-challenge.sub.example.com.", zone: "example.com", want: "_acme-challenge.sub"},
+		{name: "rooted zone", fqdn: "_acme-challenge.example.com", zone: "example.com.", want: "_acme-challenge"},
+		{name: "case", fqdn: "_ACME-Challenge.EXAMPLE.com.", zone: "Example.COM", want: "_acme-challenge"},
+		{name: "apex", fqdn: "Example.com.", zone: "example.com", want: Apex},
+		{name: "wildcard", fqdn: "*.example.com", zone: "example.com", want: "*"},
+		{name: "Persian IDN", fqdn: "_acme-challenge.مثال.ایران.", zone: "xn--mgbh0fb.xn--mgba3a4f16a", want: "_acme-challenge"},
+		{name: "other zone", fqdn: "_acme-challenge.example.org", zone: "example.com", expectError: "is not in zone"},
+		{name: "zone as label suffix", fqdn: "_acme-challenge.myexample.com", zone: "example.com", expectError: "is not in zone"},
+		{name: "invalid name", fqdn: "a..example.com", zone: "example.com", expectError: "empty label"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecordName(tt.fqdn, tt.zone)
+
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      string
+		zone        string
+		want        string
+		expectError string
+	}{
+		{name: "record", record: "_acme-challenge.sub", zone: "example.com", want: "_acme-challenge.sub.example.com."},
+		{name: "apex", record: Apex, zone: "Example.com.", want: "example.com."},
+		{name: "empty record", record: "", zone: "example.com", want: "example.com."},
+		{name: "Persian IDN zone", record: "_acme-challenge", zone: "مثال.ایران", want: "_acme-challenge.xn--mgbh0fb.xn--mgba3a4f16a."},
+		{name: "rooted record", record: "www.example.com.", zone: "example.com", expectError: "is not relative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Join(tt.record, tt.zone)
+
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "example.com", Key("*.Example.com."))
+	assert.Equal(t, "xn--mgbh0fb.xn--mgba3a4f16a", Key("*.مثال.ایران"))
+	assert.Equal(t, "a..example.com", Key("*.A..example.com."))
+
+	assert.True(t, Equal("مثال.ایران.", "XN--MGBH0FB.xn--mgba3a4f16a"))
+	assert.False(t, Equal("example.com", "www.example.com"))
+	assert.True(t, InZone("*.www.example.com", "example.com"))
+	assert.False(t, InZone("example.com", "www.example.com"))
+}
+
+// upperASCII upper-cases the ASCII letters of s
+func upperASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, s)
+}
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		normalized, err := Normalize(name)
+		if err != nil {
+			return
+		}
+
+		assert.NotEmpty(t, normalized)
+		assert.True(t, isASCII(normalized), "%q is not ASCII", normalized)
+		assert.Equal(t, strings.ToLower(normalized), normalized)
+		assert.False(t, strings.HasSuffix(normalized, "."))
+		assert.LessOrEqual(t, len(normalized), maxNameLength)
+
+		again, err := Normalize(normalized)
+		require.NoError(t, err, "normal form %q of %q does not normalize", normalized, name)
+		assert.Equal(t, normalized, again, "normalizing %q is not idempotent", name)
+
+		upper, err := Normalize(upperASCII(name))
+		require.NoError(t, err)
+		assert.Equal(t, normalized, upper, "case of %q is not folded", name)
+
+		fqdn, err := Fqdn(name)
+		require.NoError(t, err)
+		assert.Equal(t, normalized+".", fqdn)
+		if name == strings.TrimSpace(name) && !strings.HasSuffix(name, ".") {
+			rooted, err := Normalize(name + ".")
+			require.NoError(t, err)
+			assert.Equal(t, normalized, rooted, "trailing dot of %q is not dropped", name)
+		}
+	})
+}
+
+func FuzzRecordName(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add("_acme-challenge", seed)
+		f.Add("*.www", seed)
+	}
+
+	f.Fuzz(func(t *testing.T, record, zone string) {
+		fqdn := record + "." + zone
+		want, err := Fqdn(fqdn)
+		if err != nil {
+			return
+		}
+		if _, err := Normalize(zone); err != nil {
+			return
+		}
+
+		name, err := RecordName(fqdn, zone)
+		require.NoError(t, err, "%q is not in zone %q", fqdn, zone)
+		assert.NotEqual(t, Apex, name)
+		assert.True(t, InZone(fqdn, zone))
+
+		// record name + zone == FQDN
+		got, err := Join(name, zone)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "record %q in zone %q", name, zone)
+
+		apex, err := RecordName(zone, zone)
+		require.NoError(t, err)
+		assert.Equal(t, Apex, apex)
+		got, err = Join(apex, zone)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(want, "."+got), "apex %q of zone %q", got, zone)
+	})
+}
+
+func FuzzZone(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		zone, err := Zone(name)
+		if err != nil {
+			return
+		}
+
+		assert.True(t, InZone(name, zone), "%q is not in its zone %q", name, zone)
+		again, err := Zone(zone)
+		require.NoError(t, err)
+		assert.Equal(t, zone, again, "zone of %q is not its own zone", zone)
+	})
+}
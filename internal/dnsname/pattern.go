@@ -0,0 +1,44 @@
+package dnsname
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// CompilePattern compiles a domain pattern into an anchored, case-insensitive regular
+// expression. A pattern enclosed in slashes is a regular expression that must match
+// the whole name; any other pattern is a glob in which "*" matches within a single
+// label and "**" across labels.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+
+	var expr string
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expr = "(?i)^(?:" + pattern[1:len(pattern)-1] + ")$"
+	} else {
+		expr = "(?i)^" + globToRegexp(strings.TrimSuffix(pattern, ".")) + "$"
+	}
+	return regexp.Compile(expr)
+}
+
+// globToRegexp translates a domain glob into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^.]*")
+		case glob[i] == '?':
+			b.WriteString("[^.]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package dnsname
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{pattern: "example.com", match: []string{"example.com", "EXAMPLE.com"}, noMatch: []string{"examplexcom", "www.example.com"}},
+		{pattern: "example.com.", match: []string{"example.com"}},
+		{pattern: "*.example.com", match: []string{"www.example.com", "*.example.com"}, noMatch: []string{"example.com", "a.b.example.com"}},
+		{pattern: "**.example.com", match: []string{"a.b.example.com"}, noMatch: []string{"example.org"}},
+		{pattern: "www?.example.com", match: []string{"www1.example.com"}, noMatch: []string{"www.example.com"}},
+		{pattern: `/(www|api)\.example\.com/`, match: []string{"api.example.com"}, noMatch: []string{"api.example.com.evil.net"}},
+		{pattern: "/", match: []string{"/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			matcher, err := CompilePattern(tt.pattern)
+			require.NoError(t, err)
+			for _, name := range tt.match {
+				assert.True(t, matcher.MatchString(name), name)
+			}
+			for _, name := range tt.noMatch {
+				assert.False(t, matcher.MatchString(name), name)
+			}
+		})
+	}
+}
+
+func TestCompilePattern_Invalid(t *testing.T) {
+	for _, pattern := range []string{"", "/(example/", `/example\.com[/`} {
+		_, err := CompilePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
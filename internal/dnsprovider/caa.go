@@ -0,0 +1,128 @@
+package dnsprovider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+// caaCritical is the issuer critical flag of a CAA record (RFC 8659 section 4.1)
+const caaCritical = 128
+
+// CAAForbiddenError is returned when the CAA records relevant to a domain do not
+// authorize the ACME server to issue certificates for it
+type CAAForbiddenError struct {
+	Domain     string
+	RecordName string
+	Message    string
+}
+
+func (e *CAAForbiddenError) Error() string {
+	return fmt.Sprintf("CAA records at %s forbid issuance for %s: %s", e.RecordName, e.Domain, e.Message)
+}
+
+// CheckCAA walks the CAA records for domain as described in RFC 8659 and verifies that
+// one of identities may issue a certificate for it. A "*." prefix on domain requests
+// a wildcard certificate, for which issuewild properties take precedence over issue.
+func (p *DigicloudProvider) CheckCAA(ctx context.Context, domain string, identities []string) error {
+	wildcard := dnsname.IsWildcard(domain)
+	name := dnsname.Key(domain)
+
+	if len(identities) == 0 {
+		p.logger.V(1).Info("ACME server advertises no CAA identities, skipping CAA check", "domain", domain)
+		return nil
+	}
+
+	// The relevant RRset is the CAA RRset of the closest ancestor (or the name
+	// itself) that has one, stopping before the root
+	for current := name; current != ""; {
+		records, err := p.lookupCAA(ctx, current)
+		if err != nil {
+			return fmt.Errorf("failed to look up CAA records for %s: %w", current, err)
+		}
+		if len(records) > 0 {
+			return p.evaluateCAA(domain, current, wildcard, records, identities)
+		}
+
+		_, parent, found := strings.Cut(current, ".")
+		if !found {
+			break
+		}
+		current = parent
+	}
+
+	return nil
+}
+
+// lookupCAA returns the CAA records at name, following CNAMEs through the resolver
+func (p *DigicloudProvider) lookupCAA(ctx context.Context, name string) ([]*dns.CAA, error) {
+	msg, err := p.exchange(ctx, dns.Fqdn(name), dns.TypeCAA)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Rcode != dns.RcodeSuccess && msg.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("resolver answered %s", dns.RcodeToString[msg.Rcode])
+	}
+
+	records, _ := splitRRSIG[*dns.CAA](msg.Answer)
+	return records, nil
+}
+
+// evaluateCAA decides whether the relevant CAA RRset found at recordName authorizes
+// one of identities to issue for domain
+func (p *DigicloudProvider) evaluateCAA(domain, recordName string, wildcard bool, records []*dns.CAA, identities []string) error {
+	var issue, issueWild []string
+	for _, caa := range records {
+		switch strings.ToLower(caa.Tag) {
+		case "issue":
+			issue = append(issue, caa.Value)
+		case "issuewild":
+			issueWild = append(issueWild, caa.Value)
+		case "iodef", "contactemail", "contactphone":
+		default:
+			if caa.Flag&caaCritical != 0 {
+				return &CAAForbiddenError{
+					Domain:     domain,
+					RecordName: recordName,
+					Message:    fmt.Sprintf("unknown critical property %q", caa.Tag),
+				}
+			}
+		}
+	}
+
+	tag, values := "issue", issue
+	if wildcard && len(issueWild) > 0 {
+		tag, values = "issuewild", issueWild
+	}
+
+	// An RRset without any applicable property does not restrict issuance
+	if len(values) == 0 {
+		return nil
+	}
+
+	for _, value := range values {
+		issuer, _, _ := strings.Cut(value, ";")
+		issuer = strings.TrimSpace(issuer)
+		for _, identity := range identities {
+			if issuer != "" && strings.EqualFold(issuer, identity) {
+				p.logger.V(1).Info("CAA record authorizes the ACME server", "domain", domain, "record", recordName, "tag", tag, "caaIssuer", issuer)
+				return nil
+			}
+		}
+	}
+
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%s %q", tag, value)
+	}
+	return &CAAForbiddenError{
+		Domain:     domain,
+		RecordName: recordName,
+		Message: fmt.Sprintf("none of [%s] authorizes the ACME server identities [%s]",
+			strings.Join(quoted, ", "), strings.Join(identities, ", ")),
+	}
+}
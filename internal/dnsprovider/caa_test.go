@@ -0,0 +1,180 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveCAA serves the given CAA records, keyed by owner name, over UDP
+func serveCAA(t *testing.T, records map[string][]dns.RR) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if q := r.Question[0]; q.Qtype == dns.TypeCAA {
+			m.Answer = records[q.Name]
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+func caa(name string, flag uint8, tag, value string) dns.RR {
+	return &dns.CAA{
+		Hdr:   dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeCAA, Class: dns.ClassINET, Ttl: 300},
+		Flag:  flag,
+		Tag:   tag,
+		Value: value,
+	}
+}
+
+func TestDigicloudProvider_CheckCAA(t *testing.T) {
+	tests := []struct {
+		name       string
+		domain     string
+		records    []dns.RR
+		identities []string
+		forbidden  string
+	}{
+		{
+			name:       "no CAA records",
+			domain:     "www.example.com",
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:       "issue authorizes the ACME server",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("www.example.com", 0, "issue", "letsencrypt.org")},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:       "issue authorizes another CA",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("www.example.com", 0, "issue", "pki.goog")},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "www.example.com",
+		},
+		{
+			name:       "records inherited from the parent",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("example.com", 0, "issue", "pki.goog")},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "example.com",
+		},
+		{
+			name:   "closest records take precedence over the parent",
+			domain: "www.example.com",
+			records: []dns.RR{
+				caa("www.example.com", 0, "issue", "letsencrypt.org"),
+				caa("example.com", 0, "issue", "pki.goog"),
+			},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:       "issue with parameters",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("example.com", 0, "issue", "LetsEncrypt.org; validationmethods=dns-01")},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:       "empty issue forbids all issuance",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("example.com", 0, "issue", ";")},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "example.com",
+		},
+		{
+			name:   "issuewild forbids a wildcard",
+			domain: "*.example.com",
+			records: []dns.RR{
+				caa("example.com", 0, "issue", "letsencrypt.org"),
+				caa("example.com", 0, "issuewild", ";"),
+			},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "example.com",
+		},
+		{
+			name:       "issuewild does not restrict a non-wildcard",
+			domain:     "www.example.com",
+			records:    []dns.RR{caa("example.com", 0, "issuewild", ";")},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:   "issuewild authorizes a wildcard",
+			domain: "*.example.com",
+			records: []dns.RR{
+				caa("example.com", 0, "issue", "pki.goog"),
+				caa("example.com", 0, "issuewild", "letsencrypt.org"),
+			},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:       "wildcard falls back to issue",
+			domain:     "*.example.com",
+			records:    []dns.RR{caa("example.com", 0, "issue", "pki.goog")},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "example.com",
+		},
+		{
+			name:   "unknown critical property",
+			domain: "www.example.com",
+			records: []dns.RR{
+				caa("example.com", 0, "issue", "letsencrypt.org"),
+				caa("example.com", 128, "tbs", "unknown"),
+			},
+			identities: []string{"letsencrypt.org"},
+			forbidden:  "example.com",
+		},
+		{
+			name:   "unknown non-critical property",
+			domain: "www.example.com",
+			records: []dns.RR{
+				caa("example.com", 0, "issue", "letsencrypt.org"),
+				caa("example.com", 0, "tbs", "unknown"),
+				caa("example.com", 0, "iodef", "mailto:security@example.com"),
+			},
+			identities: []string{"letsencrypt.org"},
+		},
+		{
+			name:    "ACME server without CAA identities",
+			domain:  "www.example.com",
+			records: []dns.RR{caa("example.com", 0, "issue", "pki.goog")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := map[string][]dns.RR{}
+			for _, rr := range tt.records {
+				records[rr.Header().Name] = append(records[rr.Header().Name], rr)
+			}
+
+			provider := NewDigicloudProvider("http://127.0.0.1", "test-token", "default", 300)
+			provider.SetNameservers([]string{serveCAA(t, records)})
+
+			err := provider.CheckCAA(context.Background(), tt.domain, tt.identities)
+
+			var forbidden *CAAForbiddenError
+			if tt.forbidden != "" {
+				require.True(t, errors.As(err, &forbidden), "expected CAAForbiddenError, got %v", err)
+				assert.Equal(t, tt.domain, forbidden.Domain)
+				assert.Equal(t, tt.forbidden, forbidden.RecordName)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
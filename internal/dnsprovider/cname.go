@@ -0,0 +1,116 @@
+package dnsprovider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+// maxCNAMEHops bounds how many CNAMEs are followed from a challenge FQDN
+const maxCNAMEHops = 50
+
+// ChallengeTarget describes where the TXT record for a domain's dns-01 challenge is written
+type ChallengeTarget struct {
+	// FQDN is the challenge FQDN the ACME server queries, _acme-challenge.<domain>.
+	FQDN string
+
+	// EffectiveFQDN is the name the TXT record is written to after following CNAMEs
+	EffectiveFQDN string
+
+	// Zone is the Digicloud zone holding EffectiveFQDN
+	Zone string
+}
+
+// Delegated reports whether the challenge FQDN is CNAME-delegated to another name
+func (t *ChallengeTarget) Delegated() bool {
+	return !strings.EqualFold(t.FQDN, t.EffectiveFQDN)
+}
+
+// ChallengeFQDN returns the challenge FQDN the ACME server queries for domain,
+// _acme-challenge.<domain>., without following CNAMEs. A "*." prefix on domain is
+// ignored.
+func ChallengeFQDN(domain string) string {
+	return dns.Fqdn("_acme-challenge." + dnsname.Key(domain))
+}
+
+// ChallengeValue returns the TXT record value of the dns-01 challenge with keyAuth.
+// Unlike dns01.GetChallengeInfo it does not look up CNAMEs.
+func ChallengeValue(keyAuth string) string {
+	digest := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// SetFollowCNAME controls whether CNAMEs at the challenge FQDN are followed, so that
+// the TXT record is written into the Digicloud zone the chain ends in
+func (p *DigicloudProvider) SetFollowCNAME(follow bool) {
+	p.followCNAME = follow
+}
+
+// ResolveChallenge returns where the TXT record for the challenge of domain is
+// written. A "*." prefix on domain is ignored. When CNAME following is enabled,
+// the CNAME chain starting at the challenge FQDN is followed to its final target.
+func (p *DigicloudProvider) ResolveChallenge(ctx context.Context, domain string) (*ChallengeTarget, error) {
+	fqdn := ChallengeFQDN(domain)
+
+	target := &ChallengeTarget{FQDN: fqdn, EffectiveFQDN: fqdn}
+	if p.followCNAME {
+		effective, err := p.followCNAMEs(ctx, fqdn)
+		if err != nil {
+			return nil, err
+		}
+		target.EffectiveFQDN = effective
+	}
+
+	zone, err := dnsname.Zone(target.EffectiveFQDN)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract domain name from %s: %w", target.EffectiveFQDN, err)
+	}
+	target.Zone = zone
+
+	if target.Delegated() {
+		p.logger.V(1).Info("Challenge is CNAME-delegated", "fqdn", target.FQDN, "zone", target.Zone, "record", target.EffectiveFQDN)
+	}
+	return target, nil
+}
+
+// followCNAMEs follows the CNAME chain starting at fqdn and returns its last name
+func (p *DigicloudProvider) followCNAMEs(ctx context.Context, fqdn string) (string, error) {
+	start := fqdn
+	seen := map[string]bool{}
+	for i := 0; i < maxCNAMEHops; i++ {
+		seen[strings.ToLower(fqdn)] = true
+
+		msg, err := p.exchange(ctx, fqdn, dns.TypeCNAME)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up CNAME for %s: %w", fqdn, err)
+		}
+		if msg.Rcode != dns.RcodeSuccess && msg.Rcode != dns.RcodeNameError {
+			return "", fmt.Errorf("failed to look up CNAME for %s: resolver answered %s", fqdn, dns.RcodeToString[msg.Rcode])
+		}
+
+		var next string
+		for _, rr := range msg.Answer {
+			if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, fqdn) {
+				next = dns.Fqdn(cname.Target)
+				break
+			}
+		}
+		if next == "" {
+			return fqdn, nil
+		}
+		if seen[strings.ToLower(next)] {
+			return "", fmt.Errorf("CNAME loop detected at %s", next)
+		}
+
+		p.logger.V(1).Info("Following CNAME", "from", fqdn, "to", next)
+		fqdn = next
+	}
+
+	return "", fmt.Errorf("CNAME chain from %s is longer than %d hops", start, maxCNAMEHops)
+}
@@ -0,0 +1,215 @@
+package dnsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveCNAMEs answers CNAME queries from the given owner name to target map over UDP
+func serveCNAMEs(t *testing.T, cnames map[string]string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if target, ok := cnames[q.Name]; ok {
+			m.Answer = []dns.RR{&dns.CNAME{
+				Hdr:    dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300},
+				Target: target,
+			}}
+		} else {
+			m.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+func TestDigicloudProvider_ResolveChallenge(t *testing.T) {
+	tests := []struct {
+		name          string
+		follow        bool
+		cnames        map[string]string
+		domain        string
+		effectiveFQDN string
+		zone          string
+		expectError   bool
+	}{
+		{
+			name:          "CNAME following disabled",
+			cnames:        map[string]string{"_acme-challenge.www.example.com.": "www.acme.digicloud-zone.ir."},
+			domain:        "www.example.com",
+			effectiveFQDN: "_acme-challenge.www.example.com.",
+			zone:          "example.com",
+		},
+		{
+			name:          "no CNAME at the challenge FQDN",
+			follow:        true,
+			domain:        "*.www.example.com",
+			effectiveFQDN: "_acme-challenge.www.example.com.",
+			zone:          "example.com",
+		},
+		{
+			name:          "challenge delegated to a Digicloud zone",
+			follow:        true,
+			cnames:        map[string]string{"_acme-challenge.www.example.com.": "www.acme.validation.ir."},
+			domain:        "www.example.com",
+			effectiveFQDN: "www.acme.validation.ir.",
+			zone:          "validation.ir",
+		},
+		{
+			name:   "CNAME chain",
+			follow: true,
+			cnames: map[string]string{
+				"_acme-challenge.example.com.":     "example.com.auth.other-host.net.",
+				"example.com.auth.other-host.net.": "d420c923-bbd7.acme.validation.ir.",
+			},
+			domain:        "example.com",
+			effectiveFQDN: "d420c923-bbd7.acme.validation.ir.",
+			zone:          "validation.ir",
+		},
+		{
+			name:          "upper case and trailing dot",
+			domain:        "*.WWW.Example.COM.",
+			effectiveFQDN: "_acme-challenge.www.example.com.",
+			zone:          "example.com",
+		},
+		{
+			name:          "second level public suffix",
+			domain:        "shop.example.co.ir",
+			effectiveFQDN: "_acme-challenge.shop.example.co.ir.",
+			zone:          "example.co.ir",
+		},
+		{
+			name:          "Persian IDN",
+			domain:        "*.فروشگاه.مثال.ایران",
+			effectiveFQDN: "_acme-challenge.xn--mgbtj4c7ad63e.xn--mgbh0fb.xn--mgba3a4f16a.",
+			zone:          "xn--mgbh0fb.xn--mgba3a4f16a",
+		},
+		{
+			name:   "CNAME loop",
+			follow: true,
+			cnames: map[string]string{
+				"_acme-challenge.example.com.": "a.validation.ir.",
+				"a.validation.ir.":             "_acme-challenge.example.com.",
+			},
+			domain:      "example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDigicloudProvider("http://127.0.0.1", "test-token", "default", 300)
+			provider.SetNameservers([]string{serveCNAMEs(t, tt.cnames)})
+			provider.SetFollowCNAME(tt.follow)
+
+			target, err := provider.ResolveChallenge(context.Background(), tt.domain)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.effectiveFQDN, target.EffectiveFQDN)
+			assert.Equal(t, tt.zone, target.Zone)
+			assert.Equal(t, tt.effectiveFQDN != target.FQDN, target.Delegated())
+		})
+	}
+}
+
+func TestDigicloudProvider_Present_FollowCNAME(t *testing.T) {
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record DNSTXTRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		created = append(created, r.URL.Path+" "+record.Name)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	provider.SetNameservers([]string{serveCNAMEs(t, map[string]string{
+		"_acme-challenge.www.example.com.": "www.acme.validation.ir.",
+	})})
+	provider.SetFollowCNAME(true)
+
+	require.NoError(t, provider.Present("www.example.com", "token", "key-auth"))
+	assert.Equal(t, []string{"/v1/edge/domains/validation.ir/records www.acme"}, created)
+}
+
+func TestDigicloudProvider_Present_NoCNAMEFollowing(t *testing.T) {
+	var created []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record DNSTXTRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		created = append(created, r.URL.Path+" "+record.Name)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	provider.SetNameservers([]string{serveCNAMEs(t, map[string]string{
+		"_acme-challenge.www.example.com.": "www.acme.validation.ir.",
+	})})
+
+	require.NoError(t, provider.Present("www.example.com", "token", "key-auth"))
+	assert.Equal(t, []string{"/v1/edge/domains/example.com/records _acme-challenge.www"}, created)
+}
+
+func TestDigicloudProvider_PreCheck_CNAME(t *testing.T) {
+	cnames := map[string]string{"_acme-challenge.www.example.com.": "www.acme.validation.ir."}
+
+	tests := []struct {
+		name    string
+		follow  bool
+		checked string
+	}{
+		{name: "CNAME following disabled", checked: "_acme-challenge.www.example.com."},
+		{name: "CNAME following enabled", follow: true, checked: "www.acme.validation.ir."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewDigicloudProvider("http://127.0.0.1", "test-token", "default", 300)
+			provider.SetNameservers([]string{serveCNAMEs(t, cnames)})
+			provider.SetFollowCNAME(tt.follow)
+
+			// lego passes the CNAME-followed name whatever the CNAME strategy
+			var checked string
+			found, err := provider.PreCheck("www.example.com", "www.acme.validation.ir.", "value", func(fqdn, value string) (bool, error) {
+				checked = fqdn
+				return false, nil
+			})
+
+			require.NoError(t, err)
+			assert.False(t, found)
+			assert.Equal(t, tt.checked, checked)
+		})
+	}
+}
+
+func TestChallengeFQDN(t *testing.T) {
+	assert.Equal(t, "_acme-challenge.www.example.com.", ChallengeFQDN("*.WWW.Example.com."))
+}
+
+func TestChallengeValue(t *testing.T) {
+	t.Setenv("LEGO_DISABLE_CNAME_SUPPORT", "true")
+	assert.Equal(t, dns01.GetChallengeInfo("www.example.com", "key-auth").Value, ChallengeValue("key-auth"))
+}
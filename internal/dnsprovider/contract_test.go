@@ -0,0 +1,353 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+)
+
+// specPath is the description of the Digicloud API the provider is written against
+const specPath = "../../openapi-spec.yml"
+
+// contractTransport validates every request sent through it, and the response to
+// it, against the operation of openapi-spec.yml the request is routed to
+type contractTransport struct {
+	next   http.RoundTripper
+	router routers.Router
+
+	// report is called with each violation, t.Errorf unless replaced
+	report func(format string, args ...any)
+
+	mu         sync.Mutex
+	operations map[string]bool
+}
+
+// newContractTransport returns a contractTransport for requests to the API at
+// baseURL, which are sent on through next
+func newContractTransport(t *testing.T, baseURL string, next http.RoundTripper) *contractTransport {
+	t.Helper()
+
+	doc := loadSpec(t)
+	doc.Servers = openapi3.Servers{{URL: baseURL + "/v1"}}
+	router, err := legacy.NewRouter(doc)
+	require.NoError(t, err)
+
+	return &contractTransport{next: next, router: router, report: t.Errorf, operations: map[string]bool{}}
+}
+
+// specPaths are the paths of openapi-spec.yml the provider and the fake serve. The
+// rest of the spec, parts of which are not valid OpenAPI, is dropped.
+var specPaths = []string{
+	"/edge/domains",
+	"/edge/domains/{domain_name_id}",
+	"/edge/domains/{domain_name_id}/records",
+	"/edge/domains/{domain_name_id}/records/{record_id}",
+	"/edge/domains/{domain_name_id}/ns-records",
+	"/edge/domains/{domain_name_id}/verify-ns-records",
+	"/edge/domains/{domain_name_id}/dnssec",
+	"/edge/domains/{domain_name_id}/ssl",
+}
+
+// loadSpec loads specPaths of openapi-spec.yml with the schemas they reference.
+// The record details schemas have no required properties, so every record matches
+// all of them and the oneOf of the record responses could never be satisfied; it
+// is relaxed to an anyOf.
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	data, err := os.ReadFile(specPath)
+	require.NoError(t, err)
+	var spec map[string]any
+	require.NoError(t, yaml.Unmarshal(data, &spec))
+
+	paths := map[string]any{}
+	for _, path := range specPaths {
+		require.Contains(t, spec["paths"], path)
+		paths[path] = spec["paths"].(map[string]any)[path]
+	}
+	components := spec["components"].(map[string]any)
+	schemas := components["schemas"].(map[string]any)
+	referenced := map[string]any{}
+	var collect func(node any)
+	collect = func(node any) {
+		switch node := node.(type) {
+		case map[string]any:
+			if ref, ok := node["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, seen := referenced[name]; !seen {
+					referenced[name] = schemas[name]
+					collect(schemas[name])
+				}
+			}
+			for _, value := range node {
+				collect(value)
+			}
+		case []any:
+			for _, value := range node {
+				collect(value)
+			}
+		}
+	}
+	collect(paths)
+	spec["paths"] = paths
+	spec["components"] = map[string]any{"schemas": referenced, "securitySchemes": components["securitySchemes"]}
+
+	data, err = json.Marshal(spec)
+	require.NoError(t, err)
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(data)
+	require.NoError(t, err)
+	// Not all examples are valid, "CNAME" exceeds the maxLength of the record type.
+	// They are dropped, since the router validates the spec with its examples.
+	for _, schema := range doc.Components.Schemas {
+		schema.Value.Example = nil
+	}
+	require.NoError(t, doc.Validate(loader.Context))
+
+	list := doc.Components.Schemas["DNSRecordListSchema"].Value.Items.Value
+	list.AnyOf, list.OneOf = list.OneOf, nil
+	get := doc.Paths.Value("/edge/domains/{domain_name_id}/records/{record_id}").Get
+	record := get.Responses.Status(http.StatusOK).Value.Content.Get("application/json").Schema.Value
+	record.AnyOf, record.OneOf = record.OneOf, nil
+
+	return doc
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *contractTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route, pathParams, err := c.router.FindRoute(req)
+	if err != nil {
+		c.report("%s %s is not an operation of the spec: %v", req.Method, req.URL.Path, err)
+		return c.next.RoundTrip(req)
+	}
+	c.mu.Lock()
+	c.operations[req.Method+" "+route.Path] = true
+	c.mu.Unlock()
+
+	// The body is read by the validation and restored for the server
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	input := &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			AuthenticationFunc: authenticate,
+		},
+	}
+	if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+		c.report("request %s %s violates the spec: %v", req.Method, req.URL.Path, err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if err := c.validateResponse(input, resp.StatusCode, resp.Header, respBody); err != nil {
+		c.report("response to %s %s violates the spec: %v", req.Method, req.URL.Path, err)
+	}
+
+	return resp, nil
+}
+
+// validateResponse validates a response to the request of input. Statuses the
+// operation does not document are not validated.
+func (c *contractTransport) validateResponse(input *openapi3filter.RequestValidationInput, status int, header http.Header, body []byte) error {
+	return openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 header,
+		Body:                   io.NopCloser(bytes.NewReader(body)),
+		Options:                &openapi3filter.Options{MultiError: true},
+	})
+}
+
+// Operations returns the operations of the spec requests were routed to
+func (c *contractTransport) Operations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var operations []string
+	for operation := range c.operations {
+		operations = append(operations, operation)
+	}
+	return operations
+}
+
+// authenticate checks the Bearer security scheme of the spec, an API key in the
+// Authorization header with a "Bearer " prefix
+func authenticate(_ context.Context, input *openapi3filter.AuthenticationInput) error {
+	if input.SecuritySchemeName != "Bearer" {
+		return input.NewError(nil)
+	}
+	token, ok := strings.CutPrefix(input.RequestValidationInput.Request.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return input.NewError(nil)
+	}
+	return nil
+}
+
+// newContractProvider returns a provider for the namespace default of a fake API
+// holding example.com, whose traffic is validated against the spec
+func newContractProvider(t *testing.T) (*DigicloudProvider, *digicloudtest.Server, *contractTransport) {
+	t.Helper()
+
+	api := digicloudtest.NewServer(t)
+	api.AddZone("default", "example.com", digicloudtest.WithDNSSEC("example.com. 3600 IN DS 2371 13 2 1F987CC6583E92DF0890718C42"))
+	contract := newContractTransport(t, api.URL, http.DefaultTransport)
+
+	provider := NewDigicloudProvider(api.URL, digicloudtest.DefaultToken, "default", 300)
+	provider.transport.next = contract
+	provider.SetNSResolver(&fakeNSResolver{hosts: digicloudtest.DefaultNameservers})
+	return provider, api, contract
+}
+
+func TestContract_Provider(t *testing.T) {
+	provider, api, contract := newContractProvider(t)
+	ctx := context.Background()
+
+	_, err := provider.ListDomains(ctx)
+	require.NoError(t, err)
+	require.NoError(t, provider.Present("www.example.com", "token", "key-auth"))
+	records, err := provider.ListTXTRecords(ctx, "example.com")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NoError(t, provider.CheckDelegation(ctx, "www.example.com"))
+	_, err = provider.getDNSSEC(ctx, "example.com")
+	require.NoError(t, err)
+	require.NoError(t, provider.CleanUp("www.example.com", "token", "key-auth"))
+	assert.Empty(t, api.Records("default", "example.com"))
+
+	assert.ElementsMatch(t, []string{
+		"GET /edge/domains",
+		"POST /edge/domains/{domain_name_id}/records",
+		"GET /edge/domains/{domain_name_id}/records",
+		"GET /edge/domains/{domain_name_id}/verify-ns-records",
+		"GET /edge/domains/{domain_name_id}/ns-records",
+		"GET /edge/domains/{domain_name_id}/dnssec",
+		"DELETE /edge/domains/{domain_name_id}/records/{record_id}",
+	}, contract.Operations())
+}
+
+func TestContract_Fake(t *testing.T) {
+	// The operations of the fake the provider does not use, so that the responses
+	// tests rely on are valid as well
+	api := digicloudtest.NewServer(t)
+	id := api.AddZone("default", "example.com")
+	recordID := api.AddRecord("default", "example.com", digicloudtest.Record{Name: "www", TTL: "5m", Type: "A", IPAddress: "192.0.2.1"})
+	client := &http.Client{Transport: newContractTransport(t, api.URL, http.DefaultTransport)}
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/v1/edge/domains", `{"name": "example.org", "package_id": "package-id"}`, http.StatusAccepted},
+		{http.MethodGet, "/v1/edge/domains/" + id, "", http.StatusOK},
+		{http.MethodGet, "/v1/edge/domains/example.com/records/" + recordID, "", http.StatusOK},
+		{http.MethodPatch, "/v1/edge/domains/example.com/records/" + recordID, `{"name": "www", "ttl": "1h", "type": "A", "ip_address": "192.0.2.2"}`, http.StatusAccepted},
+		{http.MethodPatch, "/v1/edge/domains/example.com/dnssec", `{"dnssec": true}`, http.StatusCreated},
+		{http.MethodGet, "/v1/edge/domains/example.com/ssl", "", http.StatusOK},
+		{http.MethodPatch, "/v1/edge/domains/example.com/ssl", `{"enable": true, "hsts": false, "https_redirect": true, "ocsp_check": false,
+			"min_tls_versions": "TLS_1_2", "policy": "modern", "type": "auto"}`, http.StatusCreated},
+		{http.MethodDelete, "/v1/edge/domains/example.org", "", http.StatusNoContent},
+	} {
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		req, err := http.NewRequest(tt.method, api.URL+tt.path, body)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+digicloudtest.DefaultToken)
+		req.Header.Set("Digicloud-Namespace", "default")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestContract_Violations(t *testing.T) {
+	api := digicloudtest.NewServer(t)
+	api.AddZone("default", "example.com")
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		body   string
+		want   string
+	}{
+		{
+			name:   "missing namespace header",
+			header: http.Header{"Authorization": {"Bearer " + digicloudtest.DefaultToken}},
+			body:   `{"name": "_acme-challenge", "ttl": "5m", "type": "TXT", "content": "value"}`,
+			want:   `parameter "Digicloud-Namespace" in header has an error: value is required but missing`,
+		},
+		{
+			name:   "missing bearer token",
+			header: http.Header{"Digicloud-Namespace": {"default"}},
+			body:   `{"name": "_acme-challenge", "ttl": "5m", "type": "TXT", "content": "value"}`,
+			want:   "security requirements failed",
+		},
+		{
+			name:   "TTL in seconds",
+			header: http.Header{"Authorization": {"Bearer " + digicloudtest.DefaultToken}, "Digicloud-Namespace": {"default"}},
+			body:   `{"name": "_acme-challenge", "ttl": "300s", "type": "TXT", "content": "value"}`,
+			want:   "maximum string length is 3",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := newContractTransport(t, api.URL, http.DefaultTransport)
+			var violations []string
+			contract.report = func(format string, args ...any) {
+				violations = append(violations, fmt.Sprintf(format, args...))
+			}
+
+			req, err := http.NewRequest(http.MethodPost, api.URL+"/v1/edge/domains/example.com/records", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header = tt.header
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := contract.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Len(t, violations, 1)
+			assert.Contains(t, violations[0], tt.want)
+		})
+	}
+}
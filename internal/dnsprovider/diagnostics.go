@@ -0,0 +1,90 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+)
+
+// CheckResolvers queries every recursive nameserver the provider uses for the SOA
+// of zone and returns an error naming the nameservers that did not answer
+func (p *DigicloudProvider) CheckResolvers(ctx context.Context, zone string) error {
+	nameservers := p.nameservers
+	if len(nameservers) == 0 {
+		nameservers = systemNameservers()
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	client := &dns.Client{Timeout: 5 * time.Second}
+
+	var failed []string
+	for _, ns := range nameservers {
+		in, _, err := client.ExchangeContext(ctx, msg, ns)
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("%s (%v)", ns, err))
+		case in.Rcode != dns.RcodeSuccess:
+			failed = append(failed, fmt.Sprintf("%s (answered %s)", ns, dns.RcodeToString[in.Rcode]))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("resolvers failed to resolve %s: %s", dns01.UnFqdn(zone), strings.Join(failed, ", "))
+	}
+
+	p.logger.V(1).Info("Resolvers answered", "zone", zone, "nameservers", nameservers)
+	return nil
+}
+
+// LookupAuthoritativeTXT queries every live nameserver of zone directly for the TXT
+// records at fqdn, bypassing resolver caches. It returns the values each nameserver
+// answered with, keyed by nameserver.
+func (p *DigicloudProvider) LookupAuthoritativeTXT(ctx context.Context, zone, fqdn string) (map[string][]string, error) {
+	zone = dns01.UnFqdn(zone)
+	nameservers, err := p.nsResolver.LookupNS(ctx, zone)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return nil, fmt.Errorf("failed to look up NS records for %s: %w", zone, err)
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, fmt.Errorf("zone %s has no NS records", zone)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(fqdn), dns.TypeTXT)
+	client := &dns.Client{Timeout: 5 * time.Second}
+
+	answers := make(map[string][]string, len(nameservers))
+	for _, ns := range nameservers {
+		host := normalizeNS(ns.Host)
+		addr := host
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(host, "53")
+		}
+
+		in, _, err := client.ExchangeContext(ctx, msg, addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s for %s: %w", host, fqdn, err)
+		}
+
+		values := []string{}
+		for _, rr := range in.Answer {
+			if txt, ok := rr.(*dns.TXT); ok {
+				values = append(values, strings.Join(txt.Txt, ""))
+			}
+		}
+		sort.Strings(values)
+		answers[host] = values
+	}
+
+	return answers, nil
+}
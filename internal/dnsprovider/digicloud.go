@@ -2,25 +2,64 @@ package dnsprovider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-acme/lego/v4/challenge/dns01"
-	"k8s.io/klog/v2"
+	"github.com/go-logr/logr"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
 )
 
 // DigicloudProvider implements the DNS provider for Digicloud Edge DNS API
 type DigicloudProvider struct {
 	client      *http.Client
+	transport   *instrumentedTransport
+	logger      logr.Logger
 	baseURL     string
 	apiToken    string
 	namespace   string
 	ttl         int
 	httpTimeout time.Duration
+	nsResolver  NSResolver
+	nameservers []string
+	followCNAME bool
+	recordNote  string
+
+	// ctx is the context of the calls lego makes without one
+	ctx context.Context
+
+	propagationTimeout time.Duration
+	pollingInterval    time.Duration
+}
+
+// NSResolver looks up the live NS records of a zone
+type NSResolver interface {
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+}
+
+// NewNSResolver returns an NSResolver that sends its queries to nameserver instead
+// of the nameservers in /etc/resolv.conf. Port 53 is used if nameserver has none.
+func NewNSResolver(nameserver string) NSResolver {
+	if _, _, err := net.SplitHostPort(nameserver); err != nil {
+		nameserver = net.JoinHostPort(nameserver, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, nameserver)
+		},
+	}
 }
 
 // NewDigicloudProvider creates a new Digicloud DNS provider
@@ -32,16 +71,86 @@ func NewDigicloudProvider(baseURL, apiToken, namespace string, ttl int) *Digiclo
 		ttl = 300 // Default TTL of 5 minutes
 	}
 
+	transport := newInstrumentedTransport(http.DefaultTransport)
 	return &DigicloudProvider{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
 		},
+		transport:   transport,
+		logger:      logr.Discard(),
 		baseURL:     strings.TrimSuffix(baseURL, "/"),
 		apiToken:    apiToken,
 		namespace:   namespace,
 		ttl:         ttl,
+		recordNote:  RecordNote,
 		httpTimeout: 30 * time.Second,
+		nsResolver:  net.DefaultResolver,
+		ctx:         context.Background(),
+
+		propagationTimeout: 5 * time.Minute,
+		pollingInterval:    10 * time.Second,
+	}
+}
+
+// SetPropagationTimeout overrides how long and how often DNS propagation is checked
+func (p *DigicloudProvider) SetPropagationTimeout(timeout, interval time.Duration) {
+	if timeout > 0 {
+		p.propagationTimeout = timeout
+	}
+	if interval > 0 {
+		p.pollingInterval = interval
+	}
+}
+
+// SetNSResolver overrides the resolver used to look up live NS records
+func (p *DigicloudProvider) SetNSResolver(resolver NSResolver) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	p.nsResolver = resolver
+}
+
+// SetLogger sets the logger of the provider and its API requests. Callers add the
+// key/values identifying the issuer and request. Nothing is logged by default.
+func (p *DigicloudProvider) SetLogger(logger logr.Logger) {
+	p.logger = logger
+	p.transport.logger = logger
+}
+
+// SetContext sets the context of Present, CleanUp and PreCheck, which lego calls
+// without one, so that their API calls are traced and canceled with the order
+func (p *DigicloudProvider) SetContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	p.ctx = ctx
+}
+
+// Namespace returns the Digicloud namespace the provider manages zones in
+func (p *DigicloudProvider) Namespace() string {
+	return p.namespace
+}
+
+// RecordNote is the note of the challenge records the provider creates, which marks
+// them as owned by the issuer. Records created for a cluster are noted with
+// RecordNoteFor instead.
+const RecordNote = "Created by cert-manager digicloud issuer"
+
+// RecordNoteFor returns the note of the challenge records created for the cluster
+// clusterID, or RecordNote if clusterID is empty
+func RecordNoteFor(clusterID string) string {
+	if clusterID == "" {
+		return RecordNote
+	}
+	return RecordNote + " in cluster " + clusterID
+}
+
+// SetClusterID marks the challenge records the provider creates as owned by the
+// issuers of the cluster clusterID, so that clusters sharing a Digicloud account
+// can tell their records apart
+func (p *DigicloudProvider) SetClusterID(clusterID string) {
+	p.recordNote = RecordNoteFor(clusterID)
 }
 
 // DNSTXTRecord represents a TXT record for the Digicloud API
@@ -68,120 +177,231 @@ type DNSRecordListResponse struct {
 	Records []DNSTXTRecordDetails `json:"records"`
 }
 
+// Domain represents a zone returned when listing the domains of a namespace
+type Domain struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status,omitempty"`
+	NSVerification string `json:"ns_verification,omitempty"`
+	RecordCount    int    `json:"record_count,omitempty"`
+	IsSubdomain    bool   `json:"is_subdomain,omitempty"`
+}
+
+// DomainNSRecords represents the nameservers Digicloud expects a domain to be delegated to
+type DomainNSRecords struct {
+	DigicloudNSRecords []string `json:"digicloud_ns_records"`
+}
+
+// ZoneNotDelegatedError is returned when a zone's live NS records do not point at Digicloud
+type ZoneNotDelegatedError struct {
+	Zone     string
+	Expected []string
+	Actual   []string
+}
+
+func (e *ZoneNotDelegatedError) Error() string {
+	return fmt.Sprintf("zone %s is not delegated to Digicloud: expected nameservers [%s], found [%s]",
+		e.Zone, strings.Join(e.Expected, ", "), strings.Join(e.Actual, ", "))
+}
+
 // Present creates a TXT record to fulfill the dns-01 challenge
 func (p *DigicloudProvider) Present(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	target, err := p.ResolveChallenge(p.ctx, domain)
+	if err != nil {
+		return fmt.Errorf("failed to resolve challenge target for %s: %w", domain, err)
+	}
 
-	klog.V(2).Infof("Creating TXT record for domain %s with value %s", info.EffectiveFQDN, info.Value)
+	logger := p.logger.WithValues("zone", target.Zone, "record", target.EffectiveFQDN)
+	logger.V(1).Info("Creating TXT record")
 
-	// Extract the domain name from the FQDN
-	domainName := p.extractDomainName(info.EffectiveFQDN)
-	if domainName == "" {
-		return fmt.Errorf("could not extract domain name from %s", info.EffectiveFQDN)
+	if err := p.PresentTXTRecord(p.ctx, target.Zone, target.EffectiveFQDN, ChallengeValue(keyAuth)); err != nil {
+		return err
 	}
 
-	// Get domain ID
-	domainID, err := p.getDomainID(domainName)
+	logger.V(1).Info("Created TXT record")
+	return nil
+}
+
+// PresentTXTRecord creates a TXT record for fqdn with content value in zone. Other
+// TXT records for fqdn are kept.
+func (p *DigicloudProvider) PresentTXTRecord(ctx context.Context, zone, fqdn, value string) error {
+	zone, recordName, err := splitRecordName(zone, fqdn)
 	if err != nil {
-		return fmt.Errorf("failed to get domain ID for %s: %w", domainName, err)
+		return err
 	}
 
-	// Extract record name (subdomain part)
-	recordName := p.extractRecordName(info.EffectiveFQDN, domainName)
+	// Get domain ID
+	domainID, err := p.getDomainID(zone)
+	if err != nil {
+		return fmt.Errorf("failed to get domain ID for %s: %w", zone, err)
+	}
 
 	// Create the TXT record
 	record := DNSTXTRecord{
 		Name:    recordName,
-		TTL:     fmt.Sprintf("%ds", p.ttl),
+		TTL:     formatTTL(p.ttl),
 		Type:    "TXT",
-		Content: info.Value,
-		Note:    "Created by cert-manager digicloud issuer",
+		Content: value,
+		Note:    p.recordNote,
 	}
 
-	err = p.createTXTRecord(domainID, record)
-	if err != nil {
+	if err := p.createTXTRecord(ctx, domainID, record); err != nil {
 		return fmt.Errorf("failed to create TXT record: %w", err)
 	}
-
-	klog.V(2).Infof("Successfully created TXT record for %s", info.EffectiveFQDN)
 	return nil
 }
 
+// ttlUnits are the units of formatted TTLs, largest first
+var ttlUnits = []struct {
+	suffix  string
+	seconds int
+}{{"h", 3600}, {"m", 60}, {"s", 1}}
+
+// formatTTL formats a TTL in seconds as the API expects it, in at most three
+// characters such as "90s", "5m" or "24h". A TTL that cannot be written exactly
+// is rounded up.
+func formatTTL(seconds int) string {
+	for _, unit := range ttlUnits {
+		if seconds%unit.seconds == 0 && seconds/unit.seconds < 100 {
+			return strconv.Itoa(seconds/unit.seconds) + unit.suffix
+		}
+	}
+	if minutes := (seconds + 59) / 60; minutes < 100 {
+		return strconv.Itoa(minutes) + "m"
+	}
+	return strconv.Itoa(min((seconds+3599)/3600, 99)) + "h"
+}
+
 // CleanUp removes the TXT record after the challenge is complete
 func (p *DigicloudProvider) CleanUp(domain, token, keyAuth string) error {
-	info := dns01.GetChallengeInfo(domain, keyAuth)
+	target, err := p.ResolveChallenge(p.ctx, domain)
+	if err != nil {
+		return fmt.Errorf("failed to resolve challenge target for %s: %w", domain, err)
+	}
+
+	p.logger.V(1).Info("Cleaning up TXT record", "zone", target.Zone, "record", target.EffectiveFQDN)
 
-	klog.V(2).Infof("Cleaning up TXT record for domain %s", info.EffectiveFQDN)
+	return p.DeleteTXTRecord(p.ctx, target.Zone, target.EffectiveFQDN, ChallengeValue(keyAuth))
+}
 
-	// Extract the domain name from the FQDN
-	domainName := p.extractDomainName(info.EffectiveFQDN)
-	if domainName == "" {
-		return fmt.Errorf("could not extract domain name from %s", info.EffectiveFQDN)
+// DeleteTXTRecord removes the TXT record for fqdn with content value from zone. A
+// record that does not exist is not an error.
+func (p *DigicloudProvider) DeleteTXTRecord(ctx context.Context, zone, fqdn, value string) error {
+	zone, recordName, err := splitRecordName(zone, fqdn)
+	if err != nil {
+		return err
 	}
 
 	// Get domain ID
-	domainID, err := p.getDomainID(domainName)
+	domainID, err := p.getDomainID(zone)
 	if err != nil {
-		return fmt.Errorf("failed to get domain ID for %s: %w", domainName, err)
+		return fmt.Errorf("failed to get domain ID for %s: %w", zone, err)
 	}
 
-	// Extract record name (subdomain part)
-	recordName := p.extractRecordName(info.EffectiveFQDN, domainName)
-
 	// Find and delete the TXT record
-	recordID, err := p.findTXTRecord(domainID, recordName, info.Value)
+	recordID, err := p.findTXTRecord(ctx, domainID, recordName, value)
 	if err != nil {
 		return fmt.Errorf("failed to find TXT record: %w", err)
 	}
 
 	if recordID != "" {
-		err = p.deleteTXTRecord(domainID, recordID)
+		err = p.deleteTXTRecord(ctx, domainID, recordID)
 		if err != nil {
 			return fmt.Errorf("failed to delete TXT record: %w", err)
 		}
-		klog.V(2).Infof("Successfully deleted TXT record for %s", info.EffectiveFQDN)
+		p.logger.V(1).Info("Deleted TXT record", "zone", zone, "record", fqdn)
 	} else {
-		klog.V(2).Infof("TXT record not found for %s, may have been already deleted", info.EffectiveFQDN)
+		p.logger.V(1).Info("TXT record not found, may have been deleted already", "zone", zone, "record", fqdn)
 	}
 
 	return nil
 }
 
-// Timeout returns the timeout for DNS propagation
-func (p *DigicloudProvider) Timeout() (timeout, interval time.Duration) {
-	return 5 * time.Minute, 10 * time.Second
-}
+// CheckDelegation verifies that the zone containing domain is delegated to Digicloud.
+// It asks Digicloud to re-verify the zone's NS records and compares the nameservers
+// Digicloud expects with the live NS answers for the zone.
+func (p *DigicloudProvider) CheckDelegation(ctx context.Context, domain string) error {
+	domainName, err := dnsname.Zone(domain)
+	if err != nil {
+		return fmt.Errorf("could not extract domain name from %s: %w", domain, err)
+	}
 
-// extractDomainName extracts the domain name from the FQDN
-// For example: _acme-challenge.sub.example.com -> example.com
-func (p *DigicloudProvider) extractDomainName(fqdn string) string {
-	fqdn = strings.TrimSuffix(fqdn, ".")
-	parts := strings.Split(fqdn, ".")
+	domainID, err := p.getDomainID(domainName)
+	if err != nil {
+		return fmt.Errorf("failed to get domain ID for %s: %w", domainName, err)
+	}
 
-	// We need to find the actual domain (not subdomain)
-	// This is a simplified approach - for production, you might want to use
-	// a more sophisticated domain detection algorithm or maintain a list of known domains
-	if len(parts) >= 2 {
-		return strings.Join(parts[len(parts)-2:], ".")
+	if err := p.verifyNSRecords(ctx, domainID); err != nil {
+		return fmt.Errorf("failed to verify NS records for %s: %w", domainName, err)
 	}
-	return ""
-}
 
-// extractRecordName extracts the record name from the FQDN
-// For example: _acme-challenge.sub.example.com with domain example.com -> _acme-challenge.sub
-func (p *DigicloudProvider) extractRecordName(fqdn, domain string) string {
-	fqdn = strings.TrimSuffix(fqdn, ".")
-	domain = strings.TrimSuffix(domain, ".")
+	expected, err := p.getNSRecords(ctx, domainID)
+	if err != nil {
+		return fmt.Errorf("failed to get NS records for %s: %w", domainName, err)
+	}
+	if len(expected) == 0 {
+		return fmt.Errorf("digicloud returned no nameservers for %s", domainName)
+	}
 
-	if fqdn == domain {
-		return "@"
+	var actual []string
+	nameservers, err := p.nsResolver.LookupNS(ctx, domainName)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return fmt.Errorf("failed to look up NS records for %s: %w", domainName, err)
+		}
 	}
+	for _, ns := range nameservers {
+		actual = append(actual, normalizeNS(ns.Host))
+	}
+	sort.Strings(actual)
 
-	if strings.HasSuffix(fqdn, "."+domain) {
-		return strings.TrimSuffix(fqdn, "."+domain)
+	expectedSet := make(map[string]bool, len(expected))
+	for i, ns := range expected {
+		expected[i] = normalizeNS(ns)
+		expectedSet[expected[i]] = true
+	}
+	sort.Strings(expected)
+
+	// Every live nameserver must be a Digicloud one; a mix of old and new
+	// nameservers makes validation depend on which one the CA happens to ask.
+	delegated := len(actual) > 0
+	for _, ns := range actual {
+		if !expectedSet[ns] {
+			delegated = false
+			break
+		}
 	}
+	if !delegated {
+		return &ZoneNotDelegatedError{Zone: domainName, Expected: expected, Actual: actual}
+	}
+
+	p.logger.V(1).Info("Zone is delegated to Digicloud", "zone", domainName, "nameservers", actual)
+	return nil
+}
+
+// normalizeNS lower-cases a nameserver host and strips the trailing dot
+func normalizeNS(host string) string {
+	return strings.ToLower(dns01.UnFqdn(strings.TrimSpace(host)))
+}
+
+// Timeout returns the timeout for DNS propagation
+func (p *DigicloudProvider) Timeout() (timeout, interval time.Duration) {
+	return p.propagationTimeout, p.pollingInterval
+}
 
-	return fqdn
+// splitRecordName returns zone in normal form and the name of the record for fqdn in it
+// For example: _acme-challenge.sub.example.com with zone example.com -> _acme-challenge.sub
+func splitRecordName(zone, fqdn string) (string, string, error) {
+	zone, err := dnsname.Normalize(zone)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid zone: %w", err)
+	}
+	recordName, err := dnsname.RecordName(fqdn, zone)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid record name: %w", err)
+	}
+	return zone, recordName, nil
 }
 
 // getDomainID gets the domain ID from the domain name
@@ -193,8 +413,38 @@ func (p *DigicloudProvider) getDomainID(domainName string) (string, error) {
 	return domainName, nil
 }
 
+// ListDomains returns the zones in the provider's namespace visible to its API token
+func (p *DigicloudProvider) ListDomains(ctx context.Context) ([]Domain, error) {
+	url := fmt.Sprintf("%s/v1/edge/domains", p.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Digicloud-Namespace", p.namespace)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.apiError(resp)
+	}
+
+	var domains []Domain
+	if err := json.NewDecoder(resp.Body).Decode(&domains); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return domains, nil
+}
+
 // createTXTRecord creates a TXT record via the Digicloud API
-func (p *DigicloudProvider) createTXTRecord(domainID string, record DNSTXTRecord) error {
+func (p *DigicloudProvider) createTXTRecord(ctx context.Context, domainID string, record DNSTXTRecord) error {
 	url := fmt.Sprintf("%s/v1/edge/domains/%s/records", p.baseURL, domainID)
 
 	jsonData, err := json.Marshal(record)
@@ -202,7 +452,7 @@ func (p *DigicloudProvider) createTXTRecord(domainID string, record DNSTXTRecord
 		return fmt.Errorf("failed to marshal record: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -218,20 +468,57 @@ func (p *DigicloudProvider) createTXTRecord(domainID string, record DNSTXTRecord
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return p.apiError(resp)
 	}
 
 	return nil
 }
 
+// ListTXTRecords returns the TXT records of zone
+func (p *DigicloudProvider) ListTXTRecords(ctx context.Context, zone string) ([]DNSTXTRecordDetails, error) {
+	domainID, err := p.getDomainID(zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain ID for %s: %w", zone, err)
+	}
+
+	records, err := p.listRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var txt []DNSTXTRecordDetails
+	for _, record := range records {
+		if record.Type == "TXT" {
+			txt = append(txt, record)
+		}
+	}
+	return txt, nil
+}
+
 // findTXTRecord finds a TXT record by name and content
-func (p *DigicloudProvider) findTXTRecord(domainID, recordName, content string) (string, error) {
+func (p *DigicloudProvider) findTXTRecord(ctx context.Context, domainID, recordName, content string) (string, error) {
+	records, err := p.listRecords(ctx, domainID)
+	if err != nil {
+		return "", err
+	}
+
+	// Find the TXT record with matching name and content
+	for _, record := range records {
+		if record.Type == "TXT" && strings.EqualFold(record.Name, recordName) && record.Content == content {
+			return record.ID, nil
+		}
+	}
+
+	return "", nil // Record not found
+}
+
+// listRecords lists the records of a domain
+func (p *DigicloudProvider) listRecords(ctx context.Context, domainID string) ([]DNSTXTRecordDetails, error) {
 	url := fmt.Sprintf("%s/v1/edge/domains/%s/records", p.baseURL, domainID)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+p.apiToken)
@@ -239,35 +526,93 @@ func (p *DigicloudProvider) findTXTRecord(domainID, recordName, content string)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("HTTP request failed: %w", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, p.apiError(resp)
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// openapi-spec.yml documents a bare array of records, the object wrapping it
+	// that this provider expected before is still accepted
+	var records []DNSTXTRecordDetails
+	if err := json.Unmarshal(body, &records); err == nil {
+		return records, nil
+	}
 	var recordList DNSRecordListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&recordList); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(body, &recordList); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Find the TXT record with matching name and content
-	for _, record := range recordList.Records {
-		if record.Type == "TXT" && record.Name == recordName && record.Content == content {
-			return record.ID, nil
-		}
+	return recordList.Records, nil
+}
+
+// verifyNSRecords asks Digicloud to re-check the NS delegation of a domain
+func (p *DigicloudProvider) verifyNSRecords(ctx context.Context, domainID string) error {
+	url := fmt.Sprintf("%s/v1/edge/domains/%s/verify-ns-records", p.baseURL, domainID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return "", nil // Record not found
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Digicloud-Namespace", p.namespace)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return p.apiError(resp)
+	}
+
+	return nil
+}
+
+// getNSRecords returns the nameservers Digicloud expects the domain to be delegated to
+func (p *DigicloudProvider) getNSRecords(ctx context.Context, domainID string) ([]string, error) {
+	url := fmt.Sprintf("%s/v1/edge/domains/%s/ns-records", p.baseURL, domainID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Digicloud-Namespace", p.namespace)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.apiError(resp)
+	}
+
+	var nsRecords DomainNSRecords
+	if err := json.NewDecoder(resp.Body).Decode(&nsRecords); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nsRecords.DigicloudNSRecords, nil
 }
 
 // deleteTXTRecord deletes a TXT record by ID
-func (p *DigicloudProvider) deleteTXTRecord(domainID, recordID string) error {
+func (p *DigicloudProvider) deleteTXTRecord(ctx context.Context, domainID, recordID string) error {
 	url := fmt.Sprintf("%s/v1/edge/domains/%s/records/%s", p.baseURL, domainID, recordID)
 
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -282,8 +627,7 @@ func (p *DigicloudProvider) deleteTXTRecord(domainID, recordID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return p.apiError(resp)
 	}
 
 	return nil
@@ -1,10 +1,22 @@
 package dnsprovider
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
 )
 
 func TestDigicloudProvider_Present(t *testing.T) {
@@ -12,83 +24,244 @@ func TestDigicloudProvider_Present(t *testing.T) {
 		name        string
 		domain      string
 		token       string
-		keyAuth     string
-		expectError bool
+		fault       *digicloudtest.Fault
+		wantRecord  string
+		expectError string
 	}{
 		{
-			name:        "successful TXT record creation",
+			name:       "successful TXT record creation",
+			domain:     "example.com",
+			token:      digicloudtest.DefaultToken,
+			wantRecord: "_acme-challenge",
+		},
+		{
+			name:       "wildcard subdomain",
+			domain:     "*.www.example.com",
+			token:      digicloudtest.DefaultToken,
+			wantRecord: "_acme-challenge.www",
+		},
+		{
+			name:       "rate limited once",
+			domain:     "example.com",
+			token:      digicloudtest.DefaultToken,
+			fault:      &digicloudtest.Fault{Method: http.MethodPost, Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 1},
+			wantRecord: "_acme-challenge",
+		},
+		{
+			name:        "server error",
 			domain:      "example.com",
-			token:       "test-token",
-			keyAuth:     "test-key-auth",
-			expectError: false,
+			token:       digicloudtest.DefaultToken,
+			fault:       &digicloudtest.Fault{Method: http.MethodPost, Status: http.StatusBadGateway},
+			expectError: "status 502",
+		},
+		{
+			name:        "invalid token",
+			domain:      "example.com",
+			token:       "wrong-token",
+			expectError: "status 401",
+		},
+		{
+			name:        "zone not in namespace",
+			domain:      "example.org",
+			token:       digicloudtest.DefaultToken,
+			expectError: "status 404",
 		},
 		{
 			name:        "empty domain",
 			domain:      "",
-			token:       "test-token",
-			keyAuth:     "test-key-auth",
-			expectError: true,
+			token:       digicloudtest.DefaultToken,
+			expectError: "could not extract domain name",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			provider := NewDigicloudProvider("https://api.digicloud.ir", "test-token", "default", 300)
+			server := digicloudtest.NewServer(t)
+			server.AddZone("default", "example.com")
+			if tt.fault != nil {
+				server.Inject(*tt.fault)
+			}
+			provider := NewDigicloudProvider(server.URL, tt.token, "default", 300)
 
-			err := provider.Present(tt.domain, tt.token, tt.keyAuth)
+			err := provider.Present(tt.domain, "token", "key-auth")
 
-			if tt.expectError {
-				assert.Error(t, err)
-			} else {
-				// Since we don't have a real API, we expect this to fail with HTTP error
-				// In a real test, you'd mock the HTTP client
-				assert.Error(t, err) // Will fail due to no real API
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				assert.Empty(t, server.Records("default", "example.com"))
+				return
 			}
+			require.NoError(t, err)
+			records := server.Records("default", "example.com")
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.wantRecord, records[0].Name)
+			assert.Equal(t, "TXT", records[0].Type)
+			assert.Equal(t, "5m", records[0].TTL)
+			assert.Equal(t, dns01.GetChallengeInfo(tt.domain, "key-auth").Value, records[0].Content)
+			assert.Equal(t, RecordNote, records[0].Note)
 		})
 	}
 }
 
+func TestDigicloudProvider_SetClusterID(t *testing.T) {
+	server := digicloudtest.NewServer(t)
+	server.AddZone("default", "example.com")
+	provider := NewDigicloudProvider(server.URL, digicloudtest.DefaultToken, "default", 300)
+	provider.SetClusterID("3f2c9a4e")
+
+	require.NoError(t, provider.Present("example.com", "token", "key-auth"))
+	records := server.Records("default", "example.com")
+	require.Len(t, records, 1)
+	assert.Equal(t, "Created by cert-manager digicloud issuer in cluster 3f2c9a4e", records[0].Note)
+	assert.Equal(t, RecordNoteFor("3f2c9a4e"), records[0].Note)
+	assert.Equal(t, RecordNote, RecordNoteFor(""))
+}
+
 func TestDigicloudProvider_CleanUp(t *testing.T) {
 	tests := []struct {
 		name        string
 		domain      string
-		token       string
 		keyAuth     string
-		expectError bool
+		fault       *digicloudtest.Fault
+		wantLeft    int
+		expectError string
 	}{
 		{
-			name:        "successful TXT record deletion",
+			name:    "successful TXT record deletion",
+			domain:  "example.com",
+			keyAuth: "key-auth",
+		},
+		{
+			name:     "record of another challenge is kept",
+			domain:   "example.com",
+			keyAuth:  "other-key-auth",
+			wantLeft: 1,
+		},
+		{
+			name:        "server error",
 			domain:      "example.com",
-			token:       "test-token",
-			keyAuth:     "test-key-auth",
-			expectError: false,
+			keyAuth:     "key-auth",
+			fault:       &digicloudtest.Fault{Method: http.MethodDelete, Status: http.StatusInternalServerError},
+			wantLeft:    1,
+			expectError: "status 500",
 		},
 		{
 			name:        "empty domain",
 			domain:      "",
-			token:       "test-token",
-			keyAuth:     "test-key-auth",
-			expectError: true,
+			keyAuth:     "key-auth",
+			wantLeft:    1,
+			expectError: "could not extract domain name",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			provider := NewDigicloudProvider("https://api.digicloud.ir", "test-token", "default", 300)
+			server := digicloudtest.NewServer(t)
+			server.AddZone("default", "example.com")
+			provider := NewDigicloudProvider(server.URL, digicloudtest.DefaultToken, "default", 300)
+			require.NoError(t, provider.Present("example.com", "token", "key-auth"))
+			if tt.fault != nil {
+				server.Inject(*tt.fault)
+			}
 
-			err := provider.CleanUp(tt.domain, tt.token, tt.keyAuth)
+			err := provider.CleanUp(tt.domain, "token", tt.keyAuth)
 
-			if tt.expectError {
-				assert.Error(t, err)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
 			} else {
-				// Since we don't have a real API, we expect this to fail with HTTP error
-				// In a real test, you'd mock the HTTP client
-				assert.Error(t, err) // Will fail due to no real API
+				assert.NoError(t, err)
 			}
+			assert.Len(t, server.Records("default", "example.com"), tt.wantLeft)
 		})
 	}
 }
 
+func TestDigicloudProvider_PresentTXTRecord_Concurrent(t *testing.T) {
+	server := digicloudtest.NewServer(t)
+	server.AddZone("default", "example.com")
+	addr := server.ServeDNS(t)
+	provider := NewDigicloudProvider(server.URL, digicloudtest.DefaultToken, "default", 300)
+
+	const fqdn = "_acme-challenge.example.com."
+	values := []string{"value-0", "value-1", "value-2", "value-3", "value-4", "value-5"}
+
+	txt := func() []string {
+		m := new(dns.Msg)
+		m.SetQuestion(fqdn, dns.TypeTXT)
+		in, err := dns.Exchange(m, addr)
+		require.NoError(t, err)
+		var got []string
+		for _, rr := range in.Answer {
+			got = append(got, rr.(*dns.TXT).Txt...)
+		}
+		return got
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(values))
+	for i, value := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = provider.PresentTXTRecord(context.Background(), "example.com.", fqdn, value)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.ElementsMatch(t, values, txt())
+
+	// Clean up half of the values concurrently, the others must be kept
+	for i, value := range values[:3] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = provider.DeleteTXTRecord(context.Background(), "example.com.", fqdn, value)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs[:3] {
+		require.NoError(t, err)
+	}
+	assert.ElementsMatch(t, values[3:], txt())
+}
+
+func TestDigicloudProvider_PresentTXTRecord_Names(t *testing.T) {
+	server := digicloudtest.NewServer(t)
+	server.AddZone("default", "xn--mgbh0fb.xn--mgba3a4f16a")
+	provider := NewDigicloudProvider(server.URL, digicloudtest.DefaultToken, "default", 300)
+
+	// The zone and FQDN are normalized, whatever form lego or a solver passes them in
+	require.NoError(t, provider.PresentTXTRecord(context.Background(), "مثال.ایران.", "_ACME-Challenge.مثال.ایران.", "value"))
+	require.NoError(t, provider.PresentTXTRecord(context.Background(), "XN--MGBH0FB.xn--mgba3a4f16a", "XN--MGBH0FB.xn--mgba3a4f16a.", "apex"))
+
+	records := server.Records("default", "xn--mgbh0fb.xn--mgba3a4f16a")
+	require.Len(t, records, 2)
+	assert.Equal(t, "_acme-challenge", records[0].Name)
+	assert.Equal(t, "@", records[1].Name)
+
+	err := provider.PresentTXTRecord(context.Background(), "مثال.ایران", "_acme-challenge.example.com.", "value")
+	assert.ErrorContains(t, err, "is not in zone")
+
+	require.NoError(t, provider.DeleteTXTRecord(context.Background(), "مثال.ایران", "_acme-challenge.مثال.ایران", "value"))
+	assert.Len(t, server.Records("default", "xn--mgbh0fb.xn--mgba3a4f16a"), 1)
+}
+
+func TestFormatTTL(t *testing.T) {
+	for seconds, want := range map[int]string{
+		60:    "1m",
+		90:    "90s",
+		120:   "2m",
+		300:   "5m",
+		3599:  "60m",
+		3600:  "1h",
+		7199:  "2h",
+		86400: "24h",
+	} {
+		assert.Equal(t, want, formatTTL(seconds), seconds)
+	}
+}
+
 func TestDigicloudProvider_Timeout(t *testing.T) {
 	provider := NewDigicloudProvider("https://api.digicloud.ir", "test-token", "default", 300)
 	timeout, interval := provider.Timeout()
@@ -109,3 +282,208 @@ func TestNewDigicloudProvider(t *testing.T) {
 	assert.Equal(t, "default", provider.namespace)
 	assert.Equal(t, 300, provider.ttl)
 }
+
+type fakeNSResolver struct {
+	hosts []string
+	err   error
+}
+
+func (r *fakeNSResolver) LookupNS(_ context.Context, _ string) ([]*net.NS, error) {
+	var nameservers []*net.NS
+	for _, host := range r.hosts {
+		nameservers = append(nameservers, &net.NS{Host: host})
+	}
+	return nameservers, r.err
+}
+
+func newNSRecordsServer(t *testing.T, nameservers []string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "default", r.Header.Get("Digicloud-Namespace"))
+
+		switch r.URL.Path {
+		case "/v1/edge/domains/example.com/verify-ns-records":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/edge/domains/example.com/ns-records":
+			_ = json.NewEncoder(w).Encode(DomainNSRecords{DigicloudNSRecords: nameservers})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestDigicloudProvider_CheckDelegation(t *testing.T) {
+	tests := []struct {
+		name          string
+		live          []string
+		lookupErr     error
+		notDelegated  bool
+		expectedError bool
+	}{
+		{
+			name: "delegated to all Digicloud nameservers",
+			live: []string{"NS1.DIGICLOUD.IR.", "ns2.digicloud.ir."},
+		},
+		{
+			name: "delegated to a subset of Digicloud nameservers",
+			live: []string{"ns1.digicloud.ir."},
+		},
+		{
+			name:         "still delegated to the previous provider",
+			live:         []string{"a.iana-servers.net.", "b.iana-servers.net."},
+			notDelegated: true,
+		},
+		{
+			name:         "partially delegated",
+			live:         []string{"ns1.digicloud.ir.", "a.iana-servers.net."},
+			notDelegated: true,
+		},
+		{
+			name:         "no NS records",
+			lookupErr:    &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true},
+			notDelegated: true,
+		},
+		{
+			name:          "resolver failure",
+			lookupErr:     &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newNSRecordsServer(t, []string{"ns1.digicloud.ir", "ns2.digicloud.ir"})
+			provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+			provider.SetNSResolver(&fakeNSResolver{hosts: tt.live, err: tt.lookupErr})
+
+			err := provider.CheckDelegation(context.Background(), "www.example.com")
+
+			var notDelegated *ZoneNotDelegatedError
+			switch {
+			case tt.notDelegated:
+				assert.True(t, errors.As(err, &notDelegated))
+				assert.Equal(t, "example.com", notDelegated.Zone)
+				assert.Equal(t, []string{"ns1.digicloud.ir", "ns2.digicloud.ir"}, notDelegated.Expected)
+				assert.Contains(t, err.Error(), "ns1.digicloud.ir, ns2.digicloud.ir")
+			case tt.expectedError:
+				assert.Error(t, err)
+				assert.False(t, errors.As(err, &notDelegated))
+			default:
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDigicloudProvider_CheckDelegation_UnknownDomain(t *testing.T) {
+	server := newNSRecordsServer(t, nil)
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	provider.SetNSResolver(&fakeNSResolver{hosts: []string{"ns1.digicloud.ir."}})
+
+	err := provider.CheckDelegation(context.Background(), "www.example.org")
+
+	var notDelegated *ZoneNotDelegatedError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &notDelegated))
+	assert.Contains(t, err.Error(), "status 404")
+}
+
+func TestDigicloudProvider_ListDomains(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/edge/domains" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "media", r.Header.Get("Digicloud-Namespace"))
+		_, _ = w.Write([]byte(`[
+			{"id": "dom-1", "name": "example.com", "status": "active", "ns_verification": "verified", "record_count": 4},
+			{"id": "dom-2", "name": "shop.example.com", "ns_verification": "pending", "is_subdomain": true}
+		]`))
+	}))
+	defer server.Close()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "media", 300)
+	domains, err := provider.ListDomains(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Domain{
+		{ID: "dom-1", Name: "example.com", Status: "active", NSVerification: "verified", RecordCount: 4},
+		{ID: "dom-2", Name: "shop.example.com", NSVerification: "pending", IsSubdomain: true},
+	}, domains)
+}
+
+func TestDigicloudProvider_ListDomains_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "media", 300)
+	_, err := provider.ListDomains(context.Background())
+
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestDigicloudProvider_DeleteTXTRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantDeleted []string
+	}{
+		{
+			name:        "matching record",
+			value:       "challenge-value",
+			wantDeleted: []string{"/v1/edge/domains/example.com/records/rec-2"},
+		},
+		{
+			name:  "record already deleted",
+			value: "other-value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					_, _ = w.Write([]byte(`{"records": [
+						{"id": "rec-1", "name": "_acme-challenge.www", "type": "TXT", "content": "challenge-value"},
+						{"id": "rec-2", "name": "_acme-challenge", "type": "TXT", "content": "challenge-value"}
+					]}`))
+				case http.MethodDelete:
+					deleted = append(deleted, r.URL.Path)
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer server.Close()
+
+			provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+			err := provider.DeleteTXTRecord(context.Background(), "example.com", "_acme-challenge.example.com.", tt.value)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestDigicloudProvider_DeleteTXTRecord_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	err := provider.DeleteTXTRecord(ctx, "example.com", "_acme-challenge.example.com.", "challenge-value")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,208 @@
+package digicloudtest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// defaultTTL is the TTL of records whose ttl cannot be parsed
+const defaultTTL = 60
+
+// ServeDNS starts an authoritative nameserver for the zones of all namespaces on a
+// port of 127.0.0.1, over both UDP and TCP, and returns its address. It answers from the records as
+// they are when queried, so records created through the API can be looked up right
+// away. Names outside the zones do not exist, so that the nameserver can also stand
+// in for a recursive resolver. The nameserver is stopped when the test ends.
+func (s *Server) ServeDNS(t testing.TB) string {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dnsAddr != "" {
+		return s.dnsAddr
+	}
+
+	conn, listener, err := listenDNS()
+	if err != nil {
+		t.Fatalf("digicloudtest: failed to listen for DNS: %v", err)
+	}
+	for _, server := range []*dns.Server{{PacketConn: conn}, {Listener: listener}} {
+		started := make(chan struct{})
+		server.Handler = dns.HandlerFunc(s.serveDNS)
+		server.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = server.ActivateAndServe() }()
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("digicloudtest: DNS server did not start")
+		}
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	s.dnsAddr = conn.LocalAddr().String()
+	return s.dnsAddr
+}
+
+// listenDNS listens on the same free port of 127.0.0.1 for UDP and TCP
+func listenDNS() (net.PacketConn, net.Listener, error) {
+	var err error
+	for range 10 {
+		var conn net.PacketConn
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+		var listener net.Listener
+		listener, err = net.Listen("tcp", conn.LocalAddr().String())
+		if err == nil {
+			return conn, listener, nil
+		}
+		_ = conn.Close()
+	}
+	return nil, nil, err
+}
+
+func (s *Server) serveDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	if len(r.Question) != 1 {
+		m.Rcode = dns.RcodeFormatError
+		_ = w.WriteMsg(m)
+		return
+	}
+	q := r.Question[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z := s.authoritativeZone(q.Name)
+	if z == nil {
+		m.Rcode = dns.RcodeNameError
+		_ = w.WriteMsg(m)
+		return
+	}
+	m.Authoritative = true
+
+	apex := dns.Fqdn(z.domain.Name)
+	name := strings.ToLower(q.Name)
+	exists := name == strings.ToLower(apex)
+	if exists {
+		switch q.Qtype {
+		case dns.TypeSOA:
+			m.Answer = append(m.Answer, z.soa())
+		case dns.TypeNS:
+			for _, ns := range z.nameservers {
+				m.Answer = append(m.Answer, &dns.NS{
+					Hdr: dns.RR_Header{Name: apex, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: defaultTTL},
+					Ns:  dns.Fqdn(ns),
+				})
+			}
+		}
+	}
+
+	for _, record := range z.records {
+		if strings.ToLower(recordFQDN(record.Name, apex)) != name {
+			continue
+		}
+		exists = true
+		rrType := dns.StringToType[strings.ToUpper(record.Type)]
+		if rrType != q.Qtype && rrType != dns.TypeCNAME {
+			continue
+		}
+		if rr := recordRR(q.Name, record); rr != nil {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+
+	if len(m.Answer) == 0 {
+		m.Ns = append(m.Ns, z.soa())
+		if !exists {
+			m.Rcode = dns.RcodeNameError
+		}
+	}
+	_ = w.WriteMsg(m)
+}
+
+// authoritativeZone returns the zone of any namespace that most closely encloses name
+func (s *Server) authoritativeZone(name string) *zone {
+	var closest *zone
+	for _, zones := range s.namespaces {
+		for _, z := range zones {
+			if !dns.IsSubDomain(dns.Fqdn(z.domain.Name), name) {
+				continue
+			}
+			if closest == nil || len(z.domain.Name) > len(closest.domain.Name) {
+				closest = z
+			}
+		}
+	}
+	return closest
+}
+
+func (z *zone) soa() dns.RR {
+	apex := dns.Fqdn(z.domain.Name)
+	primary := "ns1.digicloud.ir."
+	if len(z.nameservers) > 0 {
+		primary = dns.Fqdn(z.nameservers[0])
+	}
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: apex, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: defaultTTL},
+		Ns:      primary,
+		Mbox:    "hostmaster." + apex,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  defaultTTL,
+	}
+}
+
+// recordFQDN returns the FQDN of a record named name in the zone apex
+func recordFQDN(name, apex string) string {
+	if name == "@" || name == "" {
+		return apex
+	}
+	return dns.Fqdn(name + "." + strings.TrimSuffix(apex, "."))
+}
+
+// recordRR converts record to a resource record owned by owner, or returns nil if
+// its content is not valid for its type
+func recordRR(owner string, record Record) dns.RR {
+	ttl := uint32(defaultTTL)
+	if d, err := time.ParseDuration(record.TTL); err == nil && d > 0 {
+		ttl = uint32(d.Seconds())
+	}
+
+	if strings.EqualFold(record.Type, "TXT") {
+		return &dns.TXT{
+			Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl},
+			Txt: splitTXT(record.Content),
+		}
+	}
+
+	content := record.Content
+	if content == "" {
+		content = record.IPAddress
+	}
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", owner, ttl, strings.ToUpper(record.Type), content))
+	if err != nil {
+		return nil
+	}
+	return rr
+}
+
+// splitTXT splits content into the character strings of a TXT record, which are
+// at most 255 bytes long
+func splitTXT(content string) []string {
+	var chunks []string
+	for len(content) > 255 {
+		chunks = append(chunks, content[:255])
+		content = content[255:]
+	}
+	return append(chunks, content)
+}
@@ -0,0 +1,531 @@
+// Package digicloudtest provides an in-memory fake of the Digicloud Edge DNS API,
+// so that tests of the issuer run without network access.
+//
+// The fake implements the domains, records, ns-records, dnssec, verify-ns-records
+// and ssl endpoints of openapi-spec.yml. Requests must carry a known bearer token
+// and a Digicloud-Namespace header. Faults such as latency, rate limiting and
+// server errors can be injected per endpoint, and the zones can be served by an
+// in-process authoritative DNS listener.
+package digicloudtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultToken is the API token the server accepts unless WithToken is given
+const DefaultToken = "test-token"
+
+// DefaultNameservers are the nameservers a zone is expected to be delegated to
+// unless WithNameservers is given
+var DefaultNameservers = []string{"ns1.digicloud.ir", "ns2.digicloud.ir"}
+
+// Record is a DNS record of a zone, in the shape of the record schemas of the API
+type Record struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	TTL       string `json:"ttl"`
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Domain is a zone in the shape of DNSDomainRetrieveSchema
+type Domain struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	PackageID      string `json:"package_id,omitempty"`
+	Status         string `json:"status,omitempty"`
+	RecordCount    int    `json:"record_count"`
+	IsSubdomain    bool   `json:"is_subdomain"`
+	NSVerification string `json:"ns_verification,omitempty"`
+}
+
+// SSL is the TLS configuration of a zone in the shape of SSLSchema
+type SSL struct {
+	ID             string `json:"id,omitempty"`
+	Enable         bool   `json:"enable"`
+	ExpiresAt      string `json:"expires_at,omitempty"`
+	HSTS           bool   `json:"hsts"`
+	HTTPSRedirect  bool   `json:"https_redirect"`
+	OCSPCheck      bool   `json:"ocsp_check"`
+	MinTLSVersions string `json:"min_tls_versions"`
+	Policy         string `json:"policy"`
+	PrivateKey     string `json:"private_key,omitempty"`
+	PublicKey      string `json:"public_key,omitempty"`
+	Type           string `json:"type"`
+	UpdatedAt      string `json:"updated_at,omitempty"`
+}
+
+// Request is a request the server received
+type Request struct {
+	Method    string
+	Path      string
+	Namespace string
+}
+
+// Fault makes the server delay or fail the requests it matches
+type Fault struct {
+	// Method is the HTTP method matched, or any method if empty
+	Method string
+
+	// Path is the suffix of the URL paths matched, such as "/records", or any path if empty
+	Path string
+
+	// Latency delays the matched requests
+	Latency time.Duration
+
+	// Status answers the matched requests with this status instead of serving them
+	Status int
+
+	// RetryAfter is the Retry-After header sent with Status
+	RetryAfter string
+
+	// Times is how many requests the fault matches before it is removed, or all if 0
+	Times int
+}
+
+// Server is a fake Digicloud API
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	tokens     map[string][]string
+	namespaces map[string][]*zone
+	faults     []*Fault
+	requests   []Request
+	nextID     int
+	dnsAddr    string
+}
+
+type zone struct {
+	domain      Domain
+	nameservers []string
+	dnssec      bool
+	ds          string
+	ssl         SSL
+	records     []Record
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithToken makes the server accept token for the given namespaces, or for all
+// namespaces if none are given. The default token is no longer accepted.
+func WithToken(token string, namespaces ...string) Option {
+	return func(s *Server) {
+		delete(s.tokens, DefaultToken)
+		s.tokens[token] = namespaces
+	}
+}
+
+// NewServer starts a fake Digicloud API that is closed when the test ends. Its
+// URL is the API base URL for the provider.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s := &Server{
+		tokens:     map[string][]string{DefaultToken: nil},
+		namespaces: map[string][]*zone{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// ZoneOption configures a zone added with AddZone
+type ZoneOption func(*zone)
+
+// WithNameservers sets the nameservers Digicloud expects the zone to be delegated to
+func WithNameservers(nameservers ...string) ZoneOption {
+	return func(z *zone) {
+		z.nameservers = nameservers
+	}
+}
+
+// WithDNSSEC enables DNSSEC for the zone, reporting ds as its DS record
+func WithDNSSEC(ds string) ZoneOption {
+	return func(z *zone) {
+		z.dnssec = true
+		z.ds = ds
+	}
+}
+
+// WithNSVerification sets the ns_verification status of the zone
+func WithNSVerification(status string) ZoneOption {
+	return func(z *zone) {
+		z.domain.NSVerification = status
+	}
+}
+
+// AddZone adds the zone name to namespace and returns its ID
+func (s *Server) AddZone(namespace, name string, opts ...ZoneOption) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z := s.newZone(name)
+	for _, opt := range opts {
+		opt(z)
+	}
+	s.namespaces[namespace] = append(s.namespaces[namespace], z)
+	return z.domain.ID
+}
+
+func (s *Server) newZone(name string) *zone {
+	return &zone{
+		domain: Domain{
+			ID:             s.newID("dom"),
+			Name:           name,
+			NSVerification: "verified",
+			IsSubdomain:    strings.Count(name, ".") > 1,
+		},
+		nameservers: DefaultNameservers,
+		ssl: SSL{
+			ID:             s.newID("ssl"),
+			MinTLSVersions: "TLS_1_2",
+			Policy:         "modern",
+			Type:           "auto",
+		},
+	}
+}
+
+// AddRecord adds record to the zone name of namespace and returns its ID
+func (s *Server) AddRecord(namespace, name string, record Record) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z := s.zone(namespace, name)
+	if z == nil {
+		panic(fmt.Sprintf("digicloudtest: zone %s not found in namespace %s", name, namespace))
+	}
+	record.ID = s.newID("rec")
+	z.records = append(z.records, record)
+	return record.ID
+}
+
+// Records returns the records of the zone name of namespace
+func (s *Server) Records(namespace, name string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if z := s.zone(namespace, name); z != nil {
+		return slices.Clone(z.records)
+	}
+	return nil
+}
+
+// Inject adds fault to the faults of the server. A request is affected by the
+// first fault that matches it.
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all injected faults
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns the requests the server received, including rejected ones
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+// zone returns the zone of namespace whose ID or name is id
+func (s *Server) zone(namespace, id string) *zone {
+	for _, z := range s.namespaces[namespace] {
+		if z.domain.ID == id || strings.EqualFold(z.domain.Name, id) {
+			return z
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	namespace := r.Header.Get("Digicloud-Namespace")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Namespace: namespace})
+	fault := s.matchFault(r)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, fault.Status, "injected fault")
+			return
+		}
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	namespaces, known := s.tokens[token]
+	s.mu.Unlock()
+	switch {
+	case !ok || !known:
+		writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided or are invalid.")
+		return
+	case namespace == "":
+		writeError(w, http.StatusBadRequest, "Digicloud-Namespace header is required.")
+		return
+	case len(namespaces) > 0 && !slices.Contains(namespaces, namespace):
+		writeError(w, http.StatusForbidden, "You do not have permission to access this namespace.")
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 3 || segments[0] != "v1" || segments[1] != "edge" || segments[2] != "domains" {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.route(w, r, namespace, segments[3:])
+}
+
+// matchFault returns the first fault matching r and consumes one of its matches
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, fault := range s.faults {
+		if fault.Method != "" && fault.Method != r.Method {
+			continue
+		}
+		if !strings.HasSuffix(r.URL.Path, fault.Path) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+// route serves the request for the path segments after /v1/edge/domains
+func (s *Server) route(w http.ResponseWriter, r *http.Request, namespace string, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			domains := []Domain{}
+			for _, z := range s.namespaces[namespace] {
+				domains = append(domains, z.summary())
+			}
+			writeJSON(w, http.StatusOK, domains)
+		case http.MethodPost:
+			s.createDomain(w, r, namespace)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+		}
+		return
+	}
+
+	z := s.zone(namespace, path[0])
+	if z == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	switch {
+	case len(path) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, z.summary())
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		s.namespaces[namespace] = slices.DeleteFunc(s.namespaces[namespace], func(other *zone) bool { return other == z })
+		w.WriteHeader(http.StatusNoContent)
+	case len(path) == 2 && path[1] == "records" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, append([]Record{}, z.records...))
+	case len(path) == 2 && path[1] == "records" && r.Method == http.MethodPost:
+		var record Record
+		if !decodeRecord(w, r, &record) {
+			return
+		}
+		record.ID = s.newID("rec")
+		z.records = append(z.records, record)
+		w.WriteHeader(http.StatusAccepted)
+	case len(path) == 3 && path[1] == "records":
+		s.serveRecord(w, r, z, path[2])
+	case len(path) == 2 && path[1] == "ns-records" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string][]string{"digicloud_ns_records": z.nameservers})
+	case len(path) == 2 && path[1] == "verify-ns-records" && r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+	case len(path) == 2 && path[1] == "dnssec" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, z.dnssecState())
+	case len(path) == 2 && path[1] == "dnssec" && r.Method == http.MethodPatch:
+		var state struct {
+			DNSSEC bool `json:"dnssec"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		z.dnssec = state.DNSSEC
+		writeJSON(w, http.StatusCreated, z.dnssecState())
+	case len(path) == 2 && path[1] == "ssl" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, []SSL{z.ssl})
+	case len(path) == 2 && path[1] == "ssl" && r.Method == http.MethodPatch:
+		s.updateSSL(w, r, z)
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+func (s *Server) createDomain(w http.ResponseWriter, r *http.Request, namespace string) {
+	var domain Domain
+	if err := json.NewDecoder(r.Body).Decode(&domain); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(domain.Name) < 4 || len(domain.Name) > 128 {
+		writeError(w, http.StatusBadRequest, "name must be between 4 and 128 characters.")
+		return
+	}
+	if s.zone(namespace, domain.Name) != nil {
+		writeError(w, http.StatusBadRequest, "domain already exists.")
+		return
+	}
+
+	z := s.newZone(domain.Name)
+	z.domain.PackageID = domain.PackageID
+	z.domain.NSVerification = "pending"
+	s.namespaces[namespace] = append(s.namespaces[namespace], z)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) serveRecord(w http.ResponseWriter, r *http.Request, z *zone, id string) {
+	i := slices.IndexFunc(z.records, func(record Record) bool { return record.ID == id })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, z.records[i])
+	case http.MethodPatch:
+		record := z.records[i]
+		if !decodeRecord(w, r, &record) {
+			return
+		}
+		record.ID = id
+		z.records[i] = record
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodDelete:
+		z.records = slices.Delete(z.records, i, i+1)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed.")
+	}
+}
+
+func (s *Server) updateSSL(w http.ResponseWriter, r *http.Request, z *zone) {
+	ssl := z.ssl
+	if err := json.NewDecoder(r.Body).Decode(&ssl); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !slices.Contains([]string{"TLS_1_0", "TLS_1_1", "TLS_1_2", "TLS_1_3"}, ssl.MinTLSVersions) {
+		writeError(w, http.StatusBadRequest, "min_tls_versions is not a valid choice.")
+		return
+	}
+
+	ssl.ID = z.ssl.ID
+	ssl.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	// The key pair is write-only
+	ssl.PrivateKey, ssl.PublicKey = "", ""
+	z.ssl = ssl
+	writeJSON(w, http.StatusCreated, ssl)
+}
+
+// decodeRecord decodes the record in the body of r into record and validates the
+// fields required by the record schemas, answering 400 if they are invalid
+func decodeRecord(w http.ResponseWriter, r *http.Request, record *Record) bool {
+	if err := json.NewDecoder(r.Body).Decode(record); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	var problems []string
+	if len(record.Name) < 1 || len(record.Name) > 128 {
+		problems = append(problems, "name must be between 1 and 128 characters")
+	}
+	if record.TTL == "" || len(record.TTL) > 3 {
+		problems = append(problems, "ttl must be between 1 and 3 characters")
+	}
+	if record.Type == "" {
+		problems = append(problems, "type is required")
+	}
+	if record.Content == "" && record.IPAddress == "" {
+		problems = append(problems, "content is required")
+	}
+	if len(record.Content) > 2048 {
+		problems = append(problems, "content must be at most 2048 characters")
+	}
+	if len(record.Note) > 255 {
+		problems = append(problems, "note must be at most 255 characters")
+	}
+	if len(problems) > 0 {
+		writeError(w, http.StatusBadRequest, strings.Join(problems, "; "))
+		return false
+	}
+	return true
+}
+
+func (z *zone) summary() Domain {
+	domain := z.domain
+	domain.RecordCount = len(z.records)
+	return domain
+}
+
+func (z *zone) dnssecState() map[string]any {
+	state := map[string]any{"dnssec": z.dnssec}
+	if z.dnssec && z.ds != "" {
+		state["ds"] = z.ds
+	}
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
@@ -0,0 +1,183 @@
+package digicloudtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, s *Server, method, path, token, namespace string, body any) *http.Response {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, s.URL+path, &buf)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if namespace != "" {
+		req.Header.Set("Digicloud-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestServer_Headers(t *testing.T) {
+	s := NewServer(t, WithToken("media-token", "media"))
+	s.AddZone("media", "example.com")
+
+	assert.Equal(t, http.StatusUnauthorized, do(t, s, http.MethodGet, "/v1/edge/domains", "", "media", nil).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, do(t, s, http.MethodGet, "/v1/edge/domains", DefaultToken, "media", nil).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, do(t, s, http.MethodGet, "/v1/edge/domains", "media-token", "", nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, do(t, s, http.MethodGet, "/v1/edge/domains", "media-token", "retail", nil).StatusCode)
+
+	resp := do(t, s, http.MethodGet, "/v1/edge/domains", "media-token", "media", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var domains []Domain
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&domains))
+	require.Len(t, domains, 1)
+	assert.Equal(t, "example.com", domains[0].Name)
+}
+
+func TestServer_Records(t *testing.T) {
+	s := NewServer(t)
+	id := s.AddZone("default", "example.com")
+
+	// Zones are addressed by ID or by name
+	resp := do(t, s, http.MethodPost, "/v1/edge/domains/example.com/records", DefaultToken, "default",
+		Record{Name: "_acme-challenge", TTL: "5m", Type: "TXT", Content: "value"})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	records := s.Records("default", id)
+	require.Len(t, records, 1)
+	assert.Equal(t, "value", records[0].Content)
+
+	resp = do(t, s, http.MethodPost, "/v1/edge/domains/"+id+"/records", DefaultToken, "default", Record{Name: "www", Type: "TXT"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains/"+id+"/records", DefaultToken, "default", nil)
+	var listed []Record
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	assert.Equal(t, records, listed)
+
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains/example.com/records", DefaultToken, "other", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = do(t, s, http.MethodDelete, "/v1/edge/domains/example.com/records/"+records[0].ID, DefaultToken, "default", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, s.Records("default", id))
+}
+
+func TestServer_SSL(t *testing.T) {
+	s := NewServer(t)
+	s.AddZone("default", "example.com")
+
+	ssl := SSL{Enable: true, HSTS: true, MinTLSVersions: "TLS_1_3", Policy: "modern", Type: "custom", PrivateKey: "key", PublicKey: "cert"}
+	resp := do(t, s, http.MethodPatch, "/v1/edge/domains/example.com/ssl", DefaultToken, "default", ssl)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains/example.com/ssl", DefaultToken, "default", nil)
+	var configs []SSL
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&configs))
+	require.Len(t, configs, 1)
+	assert.True(t, configs[0].Enable)
+	assert.Equal(t, "TLS_1_3", configs[0].MinTLSVersions)
+	assert.Empty(t, configs[0].PrivateKey)
+
+	ssl.MinTLSVersions = "SSL_3"
+	resp = do(t, s, http.MethodPatch, "/v1/edge/domains/example.com/ssl", DefaultToken, "default", ssl)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_Faults(t *testing.T) {
+	s := NewServer(t)
+	s.AddZone("default", "example.com")
+
+	s.Inject(Fault{Path: "/records", Status: http.StatusTooManyRequests, RetryAfter: "3", Times: 1})
+	resp := do(t, s, http.MethodGet, "/v1/edge/domains/example.com/records", DefaultToken, "default", nil)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "3", resp.Header.Get("Retry-After"))
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains/example.com/records", DefaultToken, "default", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	s.Inject(Fault{Method: http.MethodGet, Latency: 100 * time.Millisecond})
+	start := time.Now()
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains", DefaultToken, "default", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	s.ClearFaults()
+	s.Inject(Fault{Status: http.StatusServiceUnavailable})
+	resp = do(t, s, http.MethodGet, "/v1/edge/domains", DefaultToken, "default", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	assert.Len(t, s.Requests(), 4)
+}
+
+func TestServer_ServeDNS(t *testing.T) {
+	s := NewServer(t)
+	s.AddZone("default", "example.com", WithNameservers("ns1.example.net", "ns2.example.net"))
+	s.AddRecord("default", "example.com", Record{Name: "www", TTL: "10m", Type: "A", IPAddress: "192.0.2.1"})
+	s.AddRecord("default", "example.com", Record{Name: "_acme-challenge.cdn", TTL: "5m", Type: "CNAME", Content: "cdn.validation.example.com."})
+	addr := s.ServeDNS(t)
+
+	resp := do(t, s, http.MethodPost, "/v1/edge/domains/example.com/records", DefaultToken, "default",
+		Record{Name: "_acme-challenge", TTL: "5m", Type: "TXT", Content: "challenge-value"})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		in, err := dns.Exchange(m, addr)
+		require.NoError(t, err)
+		return in
+	}
+
+	in := query("_acme-challenge.example.com.", dns.TypeTXT)
+	require.Len(t, in.Answer, 1)
+	assert.True(t, in.Authoritative)
+	assert.Equal(t, []string{"challenge-value"}, in.Answer[0].(*dns.TXT).Txt)
+
+	in = query("WWW.example.com.", dns.TypeA)
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, "192.0.2.1", in.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, uint32(600), in.Answer[0].Header().Ttl)
+
+	in = query("_acme-challenge.cdn.example.com.", dns.TypeTXT)
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, "cdn.validation.example.com.", in.Answer[0].(*dns.CNAME).Target)
+
+	in = query("example.com.", dns.TypeNS)
+	assert.Len(t, in.Answer, 2)
+
+	in = query("example.com.", dns.TypeSOA)
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, "ns1.example.net.", in.Answer[0].(*dns.SOA).Ns)
+
+	in = query("www.example.com.", dns.TypeTXT)
+	assert.Equal(t, dns.RcodeSuccess, in.Rcode)
+	assert.Empty(t, in.Answer)
+
+	in = query("missing.example.com.", dns.TypeTXT)
+	assert.Equal(t, dns.RcodeNameError, in.Rcode)
+
+	in = query("example.org.", dns.TypeTXT)
+	assert.Equal(t, dns.RcodeNameError, in.Rcode)
+	assert.False(t, in.Authoritative)
+
+	m := new(dns.Msg)
+	m.SetQuestion("_acme-challenge.example.com.", dns.TypeTXT)
+	in, _, err := (&dns.Client{Net: "tcp"}).Exchange(m, addr)
+	require.NoError(t, err)
+	assert.Len(t, in.Answer, 1)
+}
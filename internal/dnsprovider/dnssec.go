@@ -0,0 +1,385 @@
+package dnsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+const defaultResolvConf = "/etc/resolv.conf"
+
+// defaultNameservers are used when no resolver can be read from resolv.conf
+var defaultNameservers = []string{
+	"google-public-dns-a.google.com:53",
+	"google-public-dns-b.google.com:53",
+}
+
+// DomainDNSSEC represents the DNSSEC state of a domain returned from the API
+type DomainDNSSEC struct {
+	DNSSEC bool   `json:"dnssec"`
+	DS     string `json:"ds,omitempty"`
+}
+
+// DNSSECMismatchError is returned when the DNSSEC chain of trust for a zone is broken,
+// which makes validating resolvers answer SERVFAIL for challenge records
+type DNSSECMismatchError struct {
+	Zone    string
+	Message string
+}
+
+func (e *DNSSECMismatchError) Error() string {
+	return fmt.Sprintf("DNSSEC validation for zone %s failed: %s", e.Zone, e.Message)
+}
+
+// SetNameservers overrides the recursive nameservers used for DNSSEC lookups
+func (p *DigicloudProvider) SetNameservers(nameservers []string) {
+	p.nameservers = nil
+	for _, ns := range nameservers {
+		if _, _, err := net.SplitHostPort(ns); err != nil {
+			ns = net.JoinHostPort(ns, "53")
+		}
+		p.nameservers = append(p.nameservers, ns)
+	}
+}
+
+// CheckDNSSEC verifies that, when DNSSEC is enabled for the zone containing domain,
+// the DS records published in the parent zone match the DS reported by Digicloud
+func (p *DigicloudProvider) CheckDNSSEC(ctx context.Context, domain string) error {
+	domainName, err := dnsname.Zone(domain)
+	if err != nil {
+		return fmt.Errorf("could not extract domain name from %s: %w", domain, err)
+	}
+
+	expected, parent, err := p.lookupDS(ctx, domainName)
+	if err != nil {
+		return err
+	}
+	if expected == nil {
+		return nil
+	}
+
+	if len(parent) == 0 {
+		// An unsigned delegation is insecure rather than bogus, so validation still succeeds
+		p.logger.V(1).Info("DNSSEC is enabled but the parent zone publishes no DS record", "zone", domainName)
+		return nil
+	}
+
+	if matchDS(expected, parent) == nil {
+		return &DNSSECMismatchError{
+			Zone: domainName,
+			Message: fmt.Sprintf("parent zone publishes DS [%s] but Digicloud signs with DS [%s]; update the DS record at your registrar",
+				formatDS(parent), formatDS(expected)),
+		}
+	}
+
+	p.logger.V(1).Info("DNSSEC chain of trust is intact", "zone", domainName)
+	return nil
+}
+
+// PreCheck wraps lego's propagation check. The check runs for the name the TXT
+// record was written to, rather than the CNAME-followed name lego passes as fqdn, so
+// that no CNAME is followed unless CNAME following is enabled. Once the TXT record is
+// visible it also validates the DNSSEC signature of the TXT answer for zones with a
+// secure delegation. It implements dns01.WrapPreCheckFunc.
+func (p *DigicloudProvider) PreCheck(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.httpTimeout)
+	defer cancel()
+
+	target, err := p.ResolveChallenge(ctx, domain)
+	if err != nil {
+		return false, err
+	}
+
+	found, err := check(target.EffectiveFQDN, value)
+	if err != nil || !found {
+		return found, err
+	}
+
+	if err := p.validateSignedTXT(ctx, target.Zone, target.EffectiveFQDN); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// validateSignedTXT verifies the RRSIG of the TXT RRset at fqdn against the zone's
+// DNSKEY RRset, which is itself verified against the DS reported by Digicloud
+func (p *DigicloudProvider) validateSignedTXT(ctx context.Context, domain, fqdn string) error {
+	domainName, err := dnsname.Zone(domain)
+	if err != nil {
+		return fmt.Errorf("could not extract domain name from %s: %w", domain, err)
+	}
+
+	expected, parent, err := p.lookupDS(ctx, domainName)
+	if err != nil {
+		return err
+	}
+	if expected == nil || len(parent) == 0 {
+		return nil
+	}
+
+	trusted := matchDS(expected, parent)
+	if trusted == nil {
+		return &DNSSECMismatchError{
+			Zone: domainName,
+			Message: fmt.Sprintf("parent zone publishes DS [%s] but Digicloud signs with DS [%s]; update the DS record at your registrar",
+				formatDS(parent), formatDS(expected)),
+		}
+	}
+
+	keyMsg, err := p.exchange(ctx, dns.Fqdn(domainName), dns.TypeDNSKEY)
+	if err != nil {
+		return fmt.Errorf("failed to query DNSKEY for %s: %w", domainName, err)
+	}
+	keys, keySigs := splitRRSIG[*dns.DNSKEY](keyMsg.Answer)
+
+	var anchor *dns.DNSKEY
+	for _, key := range keys {
+		if ds := key.ToDS(trusted.DigestType); ds != nil && strings.EqualFold(ds.Digest, trusted.Digest) {
+			anchor = key
+			break
+		}
+	}
+	if anchor == nil {
+		return &DNSSECMismatchError{Zone: domainName, Message: fmt.Sprintf("no DNSKEY matches DS %s", formatDS([]*dns.DS{trusted}))}
+	}
+	if err := verifyRRSet(keySigs, keys, []*dns.DNSKEY{anchor}); err != nil {
+		return &DNSSECMismatchError{Zone: domainName, Message: fmt.Sprintf("DNSKEY RRset signature is invalid: %v", err)}
+	}
+
+	txtMsg, err := p.exchange(ctx, dns.Fqdn(fqdn), dns.TypeTXT)
+	if err != nil {
+		return fmt.Errorf("failed to query TXT for %s: %w", fqdn, err)
+	}
+	if txtMsg.Rcode == dns.RcodeServerFailure {
+		return &DNSSECMismatchError{Zone: domainName, Message: fmt.Sprintf("resolver answered SERVFAIL for %s", fqdn)}
+	}
+	txts, txtSigs := splitRRSIG[*dns.TXT](txtMsg.Answer)
+	if err := verifyRRSet(txtSigs, txts, keys); err != nil {
+		return &DNSSECMismatchError{Zone: domainName, Message: fmt.Sprintf("TXT answer for %s is not validly signed: %v", fqdn, err)}
+	}
+
+	p.logger.V(1).Info("Signed TXT answer validated", "zone", domainName, "record", fqdn)
+	return nil
+}
+
+// lookupDS returns the DS records Digicloud reports for the domain and the DS records
+// published in the parent zone. expected is nil when DNSSEC is disabled.
+func (p *DigicloudProvider) lookupDS(ctx context.Context, domainName string) (expected, parent []*dns.DS, err error) {
+	domainID, err := p.getDomainID(domainName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get domain ID for %s: %w", domainName, err)
+	}
+
+	state, err := p.getDNSSEC(ctx, domainID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get DNSSEC state for %s: %w", domainName, err)
+	}
+	if !state.DNSSEC {
+		return nil, nil, nil
+	}
+
+	expected, err = parseDS(domainName, state.DS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse DS reported for %s: %w", domainName, err)
+	}
+
+	msg, err := p.exchange(ctx, dns.Fqdn(domainName), dns.TypeDS)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query DS for %s: %w", domainName, err)
+	}
+	parent, _ = splitRRSIG[*dns.DS](msg.Answer)
+
+	return expected, parent, nil
+}
+
+// getDNSSEC gets the DNSSEC state of a domain via the Digicloud API
+func (p *DigicloudProvider) getDNSSEC(ctx context.Context, domainID string) (*DomainDNSSEC, error) {
+	url := fmt.Sprintf("%s/v1/edge/domains/%s/dnssec", p.baseURL, domainID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Digicloud-Namespace", p.namespace)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.apiError(resp)
+	}
+
+	var state DomainDNSSEC
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &state, nil
+}
+
+// exchange sends a DNSSEC-enabled query to the first nameserver that answers
+func (p *DigicloudProvider) exchange(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.SetEdns0(4096, true)
+
+	nameservers := p.nameservers
+	if len(nameservers) == 0 {
+		nameservers = systemNameservers()
+	}
+
+	client := &dns.Client{Timeout: 10 * time.Second}
+	var lastErr error
+	for _, ns := range nameservers {
+		in, _, err := client.ExchangeContext(ctx, msg, ns)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if in.Truncated {
+			tcp := &dns.Client{Net: "tcp", Timeout: client.Timeout}
+			if in, _, err = tcp.ExchangeContext(ctx, msg, ns); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		return in, nil
+	}
+
+	return nil, lastErr
+}
+
+// systemNameservers returns the nameservers configured in resolv.conf
+func systemNameservers() []string {
+	config, err := dns.ClientConfigFromFile(defaultResolvConf)
+	if err != nil || len(config.Servers) == 0 {
+		return defaultNameservers
+	}
+
+	var nameservers []string
+	for _, server := range config.Servers {
+		nameservers = append(nameservers, net.JoinHostPort(server, config.Port))
+	}
+	return nameservers
+}
+
+// parseDS parses the DS value reported by Digicloud. The value may hold one or more
+// full DS resource records or just their rdata ("keytag algorithm digesttype digest").
+func parseDS(zone, value string) ([]*dns.DS, error) {
+	var records []*dns.DS
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		rr, err := dns.NewRR(line)
+		if err != nil || rr == nil {
+			rr, err = dns.NewRR(dns.Fqdn(zone) + " IN DS " + line)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ds, ok := rr.(*dns.DS)
+		if !ok {
+			return nil, fmt.Errorf("unexpected record type %s", dns.TypeToString[rr.Header().Rrtype])
+		}
+		records = append(records, ds)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no DS record reported")
+	}
+	return records, nil
+}
+
+// matchDS returns the first expected DS that is also published in the parent zone
+func matchDS(expected, parent []*dns.DS) *dns.DS {
+	for _, e := range expected {
+		for _, p := range parent {
+			if e.KeyTag == p.KeyTag && e.Algorithm == p.Algorithm &&
+				e.DigestType == p.DigestType && strings.EqualFold(e.Digest, p.Digest) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// formatDS renders DS records as their rdata for error messages
+func formatDS(records []*dns.DS) string {
+	var values []string
+	for _, ds := range records {
+		values = append(values, fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, strings.ToUpper(ds.Digest)))
+	}
+	return strings.Join(values, ", ")
+}
+
+// splitRRSIG separates the records of type T from their signatures
+func splitRRSIG[T dns.RR](answer []dns.RR) ([]T, []*dns.RRSIG) {
+	var records []T
+	var sigs []*dns.RRSIG
+	for _, rr := range answer {
+		switch v := rr.(type) {
+		case T:
+			records = append(records, v)
+		case *dns.RRSIG:
+			sigs = append(sigs, v)
+		}
+	}
+	return records, sigs
+}
+
+// verifyRRSet checks that at least one signature over records was made by one of keys
+func verifyRRSet[T dns.RR](sigs []*dns.RRSIG, records []T, keys []*dns.DNSKEY) error {
+	if len(records) == 0 {
+		return fmt.Errorf("no records in answer")
+	}
+	if len(sigs) == 0 {
+		return fmt.Errorf("answer is not signed")
+	}
+
+	rrset := make([]dns.RR, len(records))
+	for i, rr := range records {
+		rrset[i] = rr
+	}
+
+	var lastErr error
+	for _, sig := range sigs {
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if err := sig.Verify(key, rrset); err != nil {
+				lastErr = err
+				continue
+			}
+			if !sig.ValidityPeriod(time.Now()) {
+				lastErr = fmt.Errorf("signature by key %d is outside its validity period", sig.KeyTag)
+				continue
+			}
+			return nil
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no signature made by a trusted key")
+	}
+	return lastErr
+}
@@ -0,0 +1,237 @@
+package dnsprovider
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedZone is a minimal DNSSEC-signed zone served over UDP
+type signedZone struct {
+	zone     string
+	key      *dns.DNSKEY
+	signer   crypto.Signer
+	parentDS []dns.RR
+	records  map[uint16][]dns.RR
+}
+
+func newSignedZone(t *testing.T, zone string) *signedZone {
+	t.Helper()
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(zone), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+
+	z := &signedZone{
+		zone:    dns.Fqdn(zone),
+		key:     key,
+		signer:  privateKey.(crypto.Signer),
+		records: map[uint16][]dns.RR{},
+	}
+	z.parentDS = []dns.RR{key.ToDS(dns.SHA256)}
+	z.records[dns.TypeDNSKEY] = z.sign(t, z.signer, key)
+
+	return z
+}
+
+// sign returns rrs followed by their RRSIG made with signer
+func (z *signedZone) sign(t *testing.T, signer crypto.Signer, rrs ...dns.RR) []dns.RR {
+	t.Helper()
+
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrs[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 3600},
+		Algorithm:  z.key.Algorithm,
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.zone,
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(t, sig.Sign(signer, rrs))
+
+	return append(rrs, sig)
+}
+
+func (z *signedZone) setTXT(t *testing.T, fqdn, value string, signer crypto.Signer) {
+	t.Helper()
+
+	txt := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(fqdn), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+		Txt: []string{value},
+	}
+	z.records[dns.TypeTXT] = z.sign(t, signer, txt)
+}
+
+func (z *signedZone) serve(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch q := r.Question[0]; q.Qtype {
+		case dns.TypeDS:
+			m.Answer = z.parentDS
+		default:
+			m.Answer = z.records[q.Qtype]
+		}
+		_ = w.WriteMsg(m)
+	})}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	return conn.LocalAddr().String()
+}
+
+func newDNSSECServer(t *testing.T, state DomainDNSSEC) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/edge/domains/example.com/dnssec" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(state)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func dsRdata(rr dns.RR) string {
+	ds := rr.(*dns.DS)
+	return fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, ds.Digest)
+}
+
+func TestDigicloudProvider_CheckDNSSEC(t *testing.T) {
+	zone := newSignedZone(t, "example.com")
+	otherZone := newSignedZone(t, "example.com")
+
+	tests := []struct {
+		name     string
+		state    DomainDNSSEC
+		parentDS []dns.RR
+		mismatch bool
+	}{
+		{
+			name:     "DNSSEC disabled",
+			state:    DomainDNSSEC{DNSSEC: false},
+			parentDS: otherZone.parentDS,
+		},
+		{
+			name:     "parent DS matches",
+			state:    DomainDNSSEC{DNSSEC: true, DS: dsRdata(zone.parentDS[0])},
+			parentDS: zone.parentDS,
+		},
+		{
+			name:     "Digicloud reports a full DS record",
+			state:    DomainDNSSEC{DNSSEC: true, DS: zone.parentDS[0].String()},
+			parentDS: zone.parentDS,
+		},
+		{
+			name:  "insecure delegation",
+			state: DomainDNSSEC{DNSSEC: true, DS: dsRdata(zone.parentDS[0])},
+		},
+		{
+			name:     "stale DS at parent",
+			state:    DomainDNSSEC{DNSSEC: true, DS: dsRdata(zone.parentDS[0])},
+			parentDS: otherZone.parentDS,
+			mismatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone.parentDS = tt.parentDS
+			nameserver := zone.serve(t)
+			server := newDNSSECServer(t, tt.state)
+
+			provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+			provider.SetNameservers([]string{nameserver})
+
+			err := provider.CheckDNSSEC(context.Background(), "www.example.com")
+
+			var mismatch *DNSSECMismatchError
+			if tt.mismatch {
+				require.True(t, errors.As(err, &mismatch))
+				assert.Equal(t, "example.com", mismatch.Zone)
+				assert.Contains(t, err.Error(), formatDS([]*dns.DS{otherZone.parentDS[0].(*dns.DS)}))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDigicloudProvider_PreCheck(t *testing.T) {
+	zone := newSignedZone(t, "example.com")
+	rogue := newSignedZone(t, "example.com").signer
+
+	found := func(string, string) (bool, error) { return true, nil }
+	notFound := func(string, string) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name     string
+		signer   crypto.Signer
+		check    dns01.PreCheckFunc
+		found    bool
+		mismatch bool
+	}{
+		{
+			name:   "record not propagated yet",
+			signer: zone.signer,
+			check:  notFound,
+		},
+		{
+			name:   "validly signed TXT answer",
+			signer: zone.signer,
+			check:  found,
+			found:  true,
+		},
+		{
+			name:     "TXT answer signed by an unknown key",
+			signer:   rogue,
+			check:    found,
+			mismatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone.setTXT(t, "_acme-challenge.www.example.com.", "value", tt.signer)
+			nameserver := zone.serve(t)
+			server := newDNSSECServer(t, DomainDNSSEC{DNSSEC: true, DS: dsRdata(zone.parentDS[0])})
+
+			provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+			provider.SetNameservers([]string{nameserver})
+
+			ok, err := provider.PreCheck("www.example.com", "_acme-challenge.www.example.com.", "value", tt.check)
+
+			var mismatch *DNSSECMismatchError
+			if tt.mismatch {
+				assert.True(t, errors.As(err, &mismatch))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.found, ok)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package dnsprovider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+// ProviderSet dispatches dns-01 challenges to the provider responsible for each
+// domain, so that one order can span zones in different Digicloud accounts
+type ProviderSet struct {
+	providers map[string]*DigicloudProvider
+}
+
+// NewProviderSet creates an empty provider set
+func NewProviderSet() *ProviderSet {
+	return &ProviderSet{providers: map[string]*DigicloudProvider{}}
+}
+
+// Add registers provider for the challenge of domain. A "*." prefix on domain is
+// ignored since a wildcard shares its challenge record with the base domain.
+func (s *ProviderSet) Add(domain string, provider *DigicloudProvider) {
+	s.providers[challengeKey(domain)] = provider
+}
+
+// For returns the provider registered for the challenge of domain
+func (s *ProviderSet) For(domain string) (*DigicloudProvider, error) {
+	provider, ok := s.providers[challengeKey(domain)]
+	if !ok {
+		return nil, fmt.Errorf("no DNS provider configured for %s", domain)
+	}
+	return provider, nil
+}
+
+// SetContext sets the context of the challenge calls of all providers.
+// See DigicloudProvider.SetContext.
+func (s *ProviderSet) SetContext(ctx context.Context) {
+	for _, provider := range s.providers {
+		provider.SetContext(ctx)
+	}
+}
+
+// Present creates the TXT record for domain with its provider
+func (s *ProviderSet) Present(domain, token, keyAuth string) error {
+	provider, err := s.For(domain)
+	if err != nil {
+		return err
+	}
+	return provider.Present(domain, token, keyAuth)
+}
+
+// CleanUp removes the TXT record for domain with its provider
+func (s *ProviderSet) CleanUp(domain, token, keyAuth string) error {
+	provider, err := s.For(domain)
+	if err != nil {
+		return err
+	}
+	return provider.CleanUp(domain, token, keyAuth)
+}
+
+// Timeout returns the longest propagation timeout and polling interval of all providers
+func (s *ProviderSet) Timeout() (timeout, interval time.Duration) {
+	for _, provider := range s.providers {
+		t, i := provider.Timeout()
+		timeout = max(timeout, t)
+		interval = max(interval, i)
+	}
+	return timeout, interval
+}
+
+// PreCheck runs the pre-check of the provider responsible for domain.
+// It implements dns01.WrapPreCheckFunc.
+func (s *ProviderSet) PreCheck(domain, fqdn, value string, check dns01.PreCheckFunc) (bool, error) {
+	provider, err := s.For(domain)
+	if err != nil {
+		return false, err
+	}
+	return provider.PreCheck(domain, fqdn, value, check)
+}
+
+// challengeKey returns the domain a challenge record is written for
+func challengeKey(domain string) string {
+	return dnsname.Key(domain)
+}
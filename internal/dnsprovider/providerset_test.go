@@ -0,0 +1,41 @@
+package dnsprovider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderSet(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.URL.Path+" "+r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	retail := NewDigicloudProvider(server.URL, "retail-token", "retail", 300)
+	media := NewDigicloudProvider(server.URL, "media-token", "media", 300)
+	media.SetPropagationTimeout(10*time.Minute, 5*time.Second)
+
+	providers := NewProviderSet()
+	providers.Add("*.retail.example", retail)
+	providers.Add("www.media.example", media)
+
+	require.NoError(t, providers.Present("retail.example", "token", "key-auth"))
+	require.NoError(t, providers.Present("www.media.example", "token", "key-auth"))
+	assert.Equal(t, []string{
+		"/v1/edge/domains/retail.example/records Bearer retail-token",
+		"/v1/edge/domains/media.example/records Bearer media-token",
+	}, tokens)
+
+	assert.Error(t, providers.Present("www.example.com", "token", "key-auth"))
+
+	timeout, interval := providers.Timeout()
+	assert.Equal(t, 10*time.Minute, timeout)
+	assert.Equal(t, 10*time.Second, interval)
+}
@@ -0,0 +1,56 @@
+package dnsprovider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// redacted replaces secrets in logs and errors
+	redacted = "[REDACTED]"
+
+	// maxErrorBody caps how much of an API error response is included in an error
+	maxErrorBody = 1024
+)
+
+var (
+	// bearerPattern matches bearer credentials echoed in a response body
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s",}]+`)
+
+	// secretFieldPattern matches JSON string fields that may hold a credential or the
+	// content of a record
+	secretFieldPattern = regexp.MustCompile(`(?i)("[a-z_-]*(?:token|secret|password|authorization|api[_-]?key|content)[a-z_-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// apiError returns the error for an API response with an unexpected status. The
+// response body is truncated and its secrets are redacted.
+func (p *DigicloudProvider) apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, redactBody(string(body), p.apiToken))
+}
+
+// redactBody replaces the secrets, bearer credentials and secret JSON fields in body
+func redactBody(body string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			body = strings.ReplaceAll(body, secret, redacted)
+		}
+	}
+	body = bearerPattern.ReplaceAllString(body, "${1}"+redacted)
+	return secretFieldPattern.ReplaceAllString(body, `${1}"`+redacted+`"`)
+}
+
+// redactHeaders returns header with the values of credential headers redacted
+func redactHeaders(header http.Header) http.Header {
+	header = header.Clone()
+	for name := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+			header[name] = []string{redacted}
+		}
+	}
+	return header
+}
@@ -0,0 +1,71 @@
+package dnsprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := map[string]struct {
+		body string
+		want string
+	}{
+		"plain error": {
+			body: `{"detail": "domain not found"}`,
+			want: `{"detail": "domain not found"}`,
+		},
+		"echoed token": {
+			body: `invalid credentials test-token`,
+			want: `invalid credentials [REDACTED]`,
+		},
+		"bearer credentials": {
+			body: `{"detail": "rejected Bearer abc.def"}`,
+			want: `{"detail": "rejected Bearer [REDACTED]"}`,
+		},
+		"secret fields": {
+			body: `{"api_token": "abc", "content": "challenge \"value\"", "name": "_acme-challenge"}`,
+			want: `{"api_token": "[REDACTED]", "content": "[REDACTED]", "name": "_acme-challenge"}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactBody(tt.body, "test-token"))
+		})
+	}
+}
+
+func TestDigicloudProvider_APIErrorRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, `{"detail": "invalid", "authorization": %q, "content": "challenge-value"}`, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	var logs []string
+	logger := funcr.New(func(prefix, args string) { logs = append(logs, args) }, funcr.Options{Verbosity: 2})
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	provider.SetLogger(logger.WithValues("issuer", "default/test-issuer"))
+	err := provider.DeleteTXTRecord(context.Background(), "example.com", "_acme-challenge.example.com.", "challenge-value")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API request failed with status 400")
+	assert.NotContains(t, err.Error(), "test-token")
+	assert.NotContains(t, err.Error(), "challenge-value")
+
+	require.NotEmpty(t, logs)
+	output := strings.Join(logs, "\n")
+	assert.Contains(t, output, `"issuer"="default/test-issuer"`)
+	assert.Contains(t, output, `"endpoint"="/v1/edge/domains/{domain}/records"`)
+	assert.Contains(t, output, `"Authorization"=["[REDACTED]"]`)
+	assert.NotContains(t, output, "test-token")
+}
@@ -0,0 +1,152 @@
+package dnsprovider
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+	"github.com/vamirreza/digicloud-issuer/internal/tracing"
+)
+
+const (
+	// maxRateLimitRetries is how often a rate limited request is retried
+	maxRateLimitRetries = 2
+
+	// defaultRetryAfter is how long to wait before retrying a rate limited request
+	// whose response has no Retry-After header
+	defaultRetryAfter = time.Second
+
+	// maxRetryAfter caps the wait before retrying a rate limited request
+	maxRetryAfter = 10 * time.Second
+)
+
+// lastSuccess is the time in Unix nanoseconds of the last Digicloud API request
+// answered with a 2xx status
+var lastSuccess atomic.Int64
+
+// LastSuccessfulRequest returns when a Digicloud API request was last answered with
+// a 2xx status by any provider, or the zero time if none was yet
+func LastSuccessfulRequest() time.Time {
+	nanos := lastSuccess.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// instrumentedTransport records metrics and a span for Digicloud API requests and
+// retries requests rejected by rate limiting
+type instrumentedTransport struct {
+	next   http.RoundTripper
+	logger logr.Logger
+}
+
+func newInstrumentedTransport(next http.RoundTripper) *instrumentedTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &instrumentedTransport{next: next, logger: logr.Discard()}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	endpoint := endpointLabel(req.URL.Path)
+
+	ctx, span := tracing.Start(req.Context(), "Digicloud API "+req.Method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLTemplate(endpoint),
+		))
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		tracing.End(span, err)
+	}()
+	req = req.WithContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		resp, err = t.next.RoundTrip(req)
+		duration := time.Since(start)
+		logger := t.logger.V(2).WithValues("method", req.Method, "endpoint", endpoint, "headers", redactHeaders(req.Header), "duration", duration)
+		if err != nil {
+			logger.Info("Digicloud API request failed", "error", err)
+			metrics.APIRequestDuration.WithLabelValues(endpoint, req.Method, "error").Observe(duration.Seconds())
+			metrics.APIRequests.WithLabelValues(endpoint, req.Method, "error").Inc()
+			return nil, err
+		}
+		metrics.APIRequestDuration.WithLabelValues(endpoint, req.Method, statusClass(resp.StatusCode)).Observe(duration.Seconds())
+		metrics.APIRequests.WithLabelValues(endpoint, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+		logger.Info("Digicloud API request", "status", resp.StatusCode)
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			lastSuccess.Store(time.Now().UnixNano())
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			return resp, nil
+		}
+		metrics.APIRateLimited.WithLabelValues(endpoint, req.Method).Inc()
+		if attempt == maxRateLimitRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		wait := retryAfter(resp)
+		span.AddEvent("rate limited", trace.WithAttributes(attribute.String("retry_after", wait.String())))
+		resp.Body.Close()
+		resp = nil
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		metrics.APIRetries.WithLabelValues(endpoint, req.Method).Inc()
+	}
+}
+
+// retryAfter returns how long the Retry-After header of resp asks to wait, in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return defaultRetryAfter
+	}
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx"
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// endpointLabel replaces the domain and record IDs in an API path with placeholders,
+// so that the path can be used as a metric label
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "domains":
+			segments[i] = "{domain}"
+		case "records":
+			segments[i] = "{record}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,183 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vamirreza/digicloud-issuer/internal/metrics"
+)
+
+func TestInstrumentedTransport_RateLimited(t *testing.T) {
+	const endpoint = "/v1/edge/domains/{domain}/records"
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	requests := func(status string) float64 {
+		return testutil.ToFloat64(metrics.APIRequests.WithLabelValues(endpoint, http.MethodPost, status))
+	}
+	accepted, rateLimited := requests("202"), requests("429")
+	retries := testutil.ToFloat64(metrics.APIRetries.WithLabelValues(endpoint, http.MethodPost))
+	durations := func(class string) uint64 {
+		return histogramCount(t, metrics.APIRequestDuration.WithLabelValues(endpoint, http.MethodPost, class))
+	}
+	succeeded, failed := durations("2xx"), durations("4xx")
+
+	start := time.Now()
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	err := provider.createTXTRecord(context.Background(), "example.com", DNSTXTRecord{Name: "_acme-challenge", Type: "TXT", Content: "value"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, accepted+1, requests("202"))
+	assert.Equal(t, rateLimited+1, requests("429"))
+	assert.Equal(t, retries+1, testutil.ToFloat64(metrics.APIRetries.WithLabelValues(endpoint, http.MethodPost)))
+	assert.Equal(t, succeeded+1, durations("2xx"))
+	assert.Equal(t, failed+1, durations("4xx"))
+	assert.False(t, LastSuccessfulRequest().Before(start))
+}
+
+func TestInstrumentedTransport_RateLimitedContextDone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// The request is abandoned while waiting for Retry-After
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	start := time.Now()
+	_, err := provider.ListDomains(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestInstrumentedTransport_RateLimitedRetriesExhausted(t *testing.T) {
+	const endpoint = "/v1/edge/domains"
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	retries := testutil.ToFloat64(metrics.APIRetries.WithLabelValues(endpoint, http.MethodGet))
+
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	_, err := provider.ListDomains(context.Background())
+
+	// The last 429 response is returned to the caller once the retries are used up
+	assert.ErrorContains(t, err, "429")
+	assert.Equal(t, maxRateLimitRetries+1, attempts)
+	assert.Equal(t, retries+maxRateLimitRetries, testutil.ToFloat64(metrics.APIRetries.WithLabelValues(endpoint, http.MethodGet)))
+}
+
+func TestInstrumentedTransport_RateLimitedClientTimeout(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	// The client timeout covers the waits between retries, not only each attempt
+	provider := NewDigicloudProvider(server.URL, "test-token", "default", 300)
+	provider.client.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err := provider.ListDomains(context.Background())
+
+	var netErr net.Error
+	if assert.True(t, errors.As(err, &netErr), "%v", err) {
+		assert.True(t, netErr.Timeout())
+	}
+	assert.Equal(t, 1, attempts)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestInstrumentedTransport_RetryBudget(t *testing.T) {
+	// All retries of a rate limited request fit into the timeout of the API client
+	provider := NewDigicloudProvider("", "test-token", "default", 300)
+	assert.Less(t, maxRateLimitRetries*maxRetryAfter, provider.client.Timeout)
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              defaultRetryAfter,
+		"0":                             0,
+		"3":                             3 * time.Second,
+		"-1":                            defaultRetryAfter,
+		"60":                            maxRetryAfter,
+		"Wed, 21 Oct 2026 07:28:00 GMT": defaultRetryAfter,
+	}
+
+	for header, want := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if header != "" {
+			resp.Header.Set("Retry-After", header)
+		}
+		assert.Equal(t, want, retryAfter(resp), header)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := map[int]string{
+		http.StatusOK:                  "2xx",
+		http.StatusNoContent:           "2xx",
+		http.StatusNotFound:            "4xx",
+		http.StatusTooManyRequests:     "4xx",
+		http.StatusInternalServerError: "5xx",
+	}
+
+	for code, want := range tests {
+		assert.Equal(t, want, statusClass(code), code)
+	}
+}
+
+// histogramCount returns how many observations a histogram has
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := observer.(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestEndpointLabel(t *testing.T) {
+	tests := map[string]string{
+		"/v1/edge/domains":                               "/v1/edge/domains",
+		"/v1/edge/domains/example.com/records":           "/v1/edge/domains/{domain}/records",
+		"/v1/edge/domains/example.com/records/rec-1":     "/v1/edge/domains/{domain}/records/{record}",
+		"/v1/edge/domains/example.com/verify-ns-records": "/v1/edge/domains/{domain}/verify-ns-records",
+	}
+
+	for path, want := range tests {
+		assert.Equal(t, want, endpointLabel(path), path)
+	}
+}
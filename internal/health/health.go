@@ -0,0 +1,202 @@
+// Package health implements the readiness and liveness checks of the manager.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+const (
+	// DefaultAPIGracePeriod is a grace period suited to requiring Digicloud API
+	// reachability for readiness. The issuer controllers check the API of every ready
+	// issuer every five minutes.
+	DefaultAPIGracePeriod = 15 * time.Minute
+
+	// DefaultStallTimeout is how long a worker may be busy with one item before it
+	// is considered wedged
+	DefaultStallTimeout = 30 * time.Minute
+
+	// cacheSyncTimeout bounds how long the readiness check waits for informers to sync
+	cacheSyncTimeout = time.Second
+)
+
+// CacheSyncer reports whether the informers of a cache have synced
+type CacheSyncer interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+// Readiness reports the manager ready once its informers have synced and, if a grace
+// period is set and any issuer exists, a Digicloud API call succeeded within it
+type Readiness struct {
+	cache       CacheSyncer
+	reader      client.Reader
+	lastSuccess func() time.Time
+	gracePeriod time.Duration
+
+	mu      sync.Mutex
+	elected <-chan struct{}
+	started time.Time
+}
+
+// NewReadiness creates a readiness check of the informers of cache, listing issuers
+// with reader. lastSuccess returns when a Digicloud API call last succeeded. The
+// API is not required to be reachable for gracePeriod after start up, and not at all
+// if gracePeriod is zero: an unreachable API is reported in the issuer status and
+// metrics, and restarting or unreadying the manager does not help.
+func NewReadiness(cache CacheSyncer, reader client.Reader, lastSuccess func() time.Time, gracePeriod time.Duration) *Readiness {
+	return &Readiness{
+		cache:       cache,
+		reader:      reader,
+		lastSuccess: lastSuccess,
+		gracePeriod: gracePeriod,
+		started:     time.Now(),
+	}
+}
+
+// SetElected sets the channel closed once the manager is elected leader. Until then
+// the manager is a standby replica that calls no API, and is ready without API calls.
+func (r *Readiness) SetElected(elected <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.elected = elected
+}
+
+// Check implements healthz.Checker
+func (r *Readiness) Check(req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), cacheSyncTimeout)
+	defer cancel()
+	if !r.cache.WaitForCacheSync(ctx) {
+		return errors.New("informer caches have not synced")
+	}
+	if r.gracePeriod <= 0 {
+		return nil
+	}
+
+	started, leading := r.leadingSince()
+	if !leading || time.Since(started) < r.gracePeriod {
+		return nil
+	}
+	last := r.lastSuccess()
+	if !last.IsZero() && time.Since(last) < r.gracePeriod {
+		return nil
+	}
+
+	// Without issuers the Digicloud API is never called
+	configured, err := r.issuersConfigured(req.Context())
+	if err != nil {
+		return err
+	}
+	if !configured {
+		return nil
+	}
+	if last.IsZero() {
+		return fmt.Errorf("no Digicloud API call succeeded since start up %s ago", time.Since(started).Round(time.Second))
+	}
+	return fmt.Errorf("last successful Digicloud API call was %s ago", time.Since(last).Round(time.Second))
+}
+
+// leadingSince reports whether the manager runs its controllers and since when. The
+// grace period of a standby replica starts once it is elected.
+func (r *Readiness) leadingSince() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.elected == nil {
+		return r.started, true
+	}
+
+	select {
+	case <-r.elected:
+		r.started = time.Now()
+		r.elected = nil
+		return r.started, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// issuersConfigured reports whether any DigicloudIssuer or DigicloudClusterIssuer exists
+func (r *Readiness) issuersConfigured(ctx context.Context) (bool, error) {
+	var issuers digicloudv1alpha1.DigicloudIssuerList
+	if err := r.reader.List(ctx, &issuers, client.Limit(1)); err != nil {
+		return false, fmt.Errorf("failed to list issuers: %w", err)
+	}
+	if len(issuers.Items) > 0 {
+		return true, nil
+	}
+
+	var clusterIssuers digicloudv1alpha1.DigicloudClusterIssuerList
+	if err := r.reader.List(ctx, &clusterIssuers, client.Limit(1)); err != nil {
+		return false, fmt.Errorf("failed to list cluster issuers: %w", err)
+	}
+	return len(clusterIssuers.Items) > 0, nil
+}
+
+// Watchdog tracks the items workers are busy with and reports the manager not alive
+// once a worker has been busy with one item for longer than the stall timeout, so
+// that a wedged worker pool is restarted. A nil Watchdog tracks nothing.
+type Watchdog struct {
+	stallTimeout time.Duration
+
+	mu     sync.Mutex
+	next   uint64
+	active map[uint64]time.Time
+}
+
+// NewWatchdog creates a watchdog considering workers busy for longer than
+// stallTimeout wedged
+func NewWatchdog(stallTimeout time.Duration) *Watchdog {
+	if stallTimeout <= 0 {
+		stallTimeout = DefaultStallTimeout
+	}
+	return &Watchdog{stallTimeout: stallTimeout, active: map[uint64]time.Time{}}
+}
+
+// Track records that a worker started working on an item. The returned function
+// must be called once it is done.
+func (w *Watchdog) Track() func() {
+	if w == nil {
+		return func() {}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.next
+	w.next++
+	w.active[id] = time.Now()
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.active, id)
+	}
+}
+
+// Check implements healthz.Checker
+func (w *Watchdog) Check(_ *http.Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var stalled int
+	var oldest time.Time
+	for _, started := range w.active {
+		if time.Since(started) > w.stallTimeout {
+			stalled++
+			if oldest.IsZero() || started.Before(oldest) {
+				oldest = started
+			}
+		}
+	}
+	if stalled > 0 {
+		return fmt.Errorf("%d workers busy for longer than %s, the oldest for %s",
+			stalled, w.stallTimeout, time.Since(oldest).Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+type fakeCache bool
+
+func (c fakeCache) WaitForCacheSync(_ context.Context) bool {
+	return bool(c)
+}
+
+func TestReadiness_Check(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, digicloudv1alpha1.AddToScheme(scheme))
+	issuer := &digicloudv1alpha1.DigicloudClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer"}}
+
+	tests := []struct {
+		name        string
+		synced      bool
+		issuers     []client.Object
+		startedAgo  time.Duration
+		lastSuccess time.Duration
+		wantErr     string
+	}{
+		{
+			name:       "cache not synced",
+			startedAgo: time.Minute,
+			wantErr:    "informer caches have not synced",
+		},
+		{
+			name:       "within grace period after start up",
+			synced:     true,
+			issuers:    []client.Object{issuer},
+			startedAgo: time.Minute,
+		},
+		{
+			name:       "no issuers",
+			synced:     true,
+			startedAgo: time.Hour,
+		},
+		{
+			name:       "no successful API call",
+			synced:     true,
+			issuers:    []client.Object{issuer},
+			startedAgo: time.Hour,
+			wantErr:    "no Digicloud API call succeeded since start up",
+		},
+		{
+			name:        "recent successful API call",
+			synced:      true,
+			issuers:     []client.Object{issuer},
+			startedAgo:  time.Hour,
+			lastSuccess: time.Minute,
+		},
+		{
+			name:        "stale successful API call",
+			synced:      true,
+			issuers:     []client.Object{issuer},
+			startedAgo:  time.Hour,
+			lastSuccess: 20 * time.Minute,
+			wantErr:     "last successful Digicloud API call was 20m0s ago",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.issuers...).Build()
+			lastSuccess := func() time.Time {
+				if tt.lastSuccess == 0 {
+					return time.Time{}
+				}
+				return time.Now().Add(-tt.lastSuccess)
+			}
+
+			r := NewReadiness(fakeCache(tt.synced), reader, lastSuccess, DefaultAPIGracePeriod)
+			r.started = time.Now().Add(-tt.startedAgo)
+
+			err := r.Check(&http.Request{})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadiness_Check_APIDisabled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, digicloudv1alpha1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&digicloudv1alpha1.DigicloudClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer"}}).
+		Build()
+
+	// Without a grace period the Digicloud API is not required to be reachable
+	r := NewReadiness(fakeCache(true), reader, func() time.Time { return time.Time{} }, 0)
+	r.started = time.Now().Add(-time.Hour)
+	assert.NoError(t, r.Check(&http.Request{}))
+
+	r = NewReadiness(fakeCache(false), reader, func() time.Time { return time.Time{} }, 0)
+	assert.ErrorContains(t, r.Check(&http.Request{}), "informer caches have not synced")
+}
+
+func TestReadiness_Check_Standby(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, digicloudv1alpha1.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&digicloudv1alpha1.DigicloudClusterIssuer{ObjectMeta: metav1.ObjectMeta{Name: "test-issuer"}}).
+		Build()
+
+	r := NewReadiness(fakeCache(true), reader, func() time.Time { return time.Time{} }, DefaultAPIGracePeriod)
+	r.started = time.Now().Add(-time.Hour)
+	elected := make(chan struct{})
+	r.SetElected(elected)
+
+	// A standby replica calls no API
+	assert.NoError(t, r.Check(&http.Request{}))
+
+	// The grace period restarts once elected
+	close(elected)
+	assert.NoError(t, r.Check(&http.Request{}))
+	r.started = time.Now().Add(-time.Hour)
+	assert.ErrorContains(t, r.Check(&http.Request{}), "no Digicloud API call succeeded")
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	w := NewWatchdog(time.Minute)
+
+	done := w.Track()
+	assert.NoError(t, w.Check(&http.Request{}))
+
+	for id := range w.active {
+		w.active[id] = time.Now().Add(-2 * time.Minute)
+	}
+	assert.ErrorContains(t, w.Check(&http.Request{}), "1 workers busy for longer than 1m0s")
+
+	done()
+	assert.NoError(t, w.Check(&http.Request{}))
+
+	// A nil watchdog tracks nothing
+	var nilWatchdog *Watchdog
+	nilWatchdog.Track()()
+}
@@ -0,0 +1,382 @@
+// Package kubectl implements the commands of the kubectl-digicloud plugin. Issuers
+// are read from the cluster and Digicloud is accessed with the accounts and code the
+// controller uses.
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// Plugin runs the plugin commands against a cluster
+type Plugin struct {
+	// Client reads and patches issuers and reads their secrets
+	Client client.Client
+
+	// ClusterResourceNamespace is the namespace secrets referenced by cluster issuers
+	// are read from, as configured for the manager
+	ClusterResourceNamespace string
+
+	// ClusterID is the ID of the cluster, see controllers.ClusterID. Only challenge
+	// records noted with it are considered owned by the cluster's issuers.
+	ClusterID string
+
+	// Out receives the output of the commands
+	Out io.Writer
+}
+
+// Issuers lists the issuers in namespace, or in all namespaces if it is empty, and
+// all cluster issuers, with their status and the zones of their Digicloud accounts
+func (p *Plugin) Issuers(ctx context.Context, namespace string) error {
+	issuers, err := p.listIssuers(ctx, "", namespace)
+	if err != nil {
+		return err
+	}
+
+	tw := p.table("KIND", "NAMESPACE", "NAME", "READY", "MESSAGE", "DIGICLOUD NAMESPACE", "ZONES", "IN-FLIGHT", "API CHECKED")
+	for _, issuer := range issuers {
+		status := issuerStatus(issuer)
+		ready, message := "Unknown", ""
+		for _, condition := range status.Conditions {
+			if condition.Type == cmapi.IssuerConditionReady {
+				ready, message = string(condition.Status), condition.Message
+			}
+		}
+		zones := make([]string, 0, len(status.Zones))
+		for _, zone := range status.Zones {
+			name := zone.Name
+			if zone.NSVerification != "" && zone.NSVerification != "verified" {
+				name += " (" + zone.NSVerification + ")"
+			}
+			zones = append(zones, name)
+		}
+		checked := "<never>"
+		if status.LastAPICheckTime != nil {
+			checked = duration.HumanDuration(time.Since(status.LastAPICheckTime.Time)) + " ago"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", issuer.GetObjectKind().GroupVersionKind().Kind,
+			orNone(issuer.GetNamespace()), issuer.GetName(), ready, message, orNone(status.DigicloudNamespace),
+			orNone(strings.Join(zones, ",")), status.InFlightChallenges, checked)
+	}
+	return tw.Flush()
+}
+
+// ownedRecord is a challenge record in Digicloud created by an issuer
+type ownedRecord struct {
+	issuer   string
+	account  controllers.Account
+	zone     string
+	fqdn     string
+	value    string
+	inFlight bool
+}
+
+// Records lists the challenge records in the zones of the issuer ref, or of all
+// issuers in namespace and all cluster issuers if ref is empty. Records that no
+// issuer in the cluster tracks as an in-flight challenge are stale.
+func (p *Plugin) Records(ctx context.Context, ref, namespace string) error {
+	records, err := p.ownedRecords(ctx, ref, namespace)
+
+	tw := p.table("ISSUER", "ACCOUNT", "ZONE", "RECORD", "VALUE", "STATE")
+	for _, record := range records {
+		state := "stale"
+		if record.inFlight {
+			state = "in-flight"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", record.issuer, record.account, record.zone, record.fqdn, record.value, state)
+	}
+	return errors.Join(tw.Flush(), err)
+}
+
+// Cleanup deletes the stale challenge records Records lists. With dryRun, the
+// records are only listed.
+func (p *Plugin) Cleanup(ctx context.Context, ref, namespace string, dryRun bool) error {
+	records, err := p.ownedRecords(ctx, ref, namespace)
+	errs := []error{err}
+
+	var deleted int
+	for _, record := range records {
+		if record.inFlight {
+			continue
+		}
+		if dryRun {
+			fmt.Fprintf(p.Out, "Would delete %s %q from zone %s\n", record.fqdn, record.value, record.zone)
+			continue
+		}
+		if err := record.account.Provider.DeleteTXTRecord(ctx, record.zone, record.fqdn, record.value); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s from zone %s: %w", record.fqdn, record.zone, err))
+			continue
+		}
+		deleted++
+		fmt.Fprintf(p.Out, "Deleted %s %q from zone %s\n", record.fqdn, record.value, record.zone)
+	}
+	if !dryRun {
+		fmt.Fprintf(p.Out, "%d stale records deleted\n", deleted)
+	}
+	return errors.Join(errs...)
+}
+
+// Revalidate makes the controller re-validate the issuer ref and check the Digicloud
+// API again by setting controllers.RevalidateAnnotation to the current time
+func (p *Plugin) Revalidate(ctx context.Context, ref string) error {
+	issuer, key := controllers.IssuerForRef(ref)
+	if err := p.Client.Get(ctx, key, issuer); err != nil {
+		return fmt.Errorf("failed to get issuer %s: %w", ref, err)
+	}
+
+	patch := client.MergeFrom(issuer.DeepCopyObject().(client.Object))
+	annotations := issuer.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[controllers.RevalidateAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+	issuer.SetAnnotations(annotations)
+	if err := p.Client.Patch(ctx, issuer, patch); err != nil {
+		return fmt.Errorf("failed to annotate issuer %s: %w", ref, err)
+	}
+
+	fmt.Fprintf(p.Out, "Issuer %s will be re-validated\n", ref)
+	return nil
+}
+
+// Which shows, for each issuer in namespace and each cluster issuer, whether it would
+// issue a certificate for dnsName in a CertificateRequest with labels, and the
+// Digicloud account and zone its challenge would be solved with
+func (p *Plugin) Which(ctx context.Context, dnsName, namespace string, labels map[string]string) error {
+	issuers, err := p.listIssuers(ctx, "", namespace)
+	if err != nil {
+		return err
+	}
+
+	tw := p.table("ISSUER", "ALLOWED", "ACCOUNT", "API TOKEN SECRET", "DIGICLOUD NAMESPACE", "CHALLENGE RECORD", "ZONE")
+	for _, issuer := range issuers {
+		s := p.signer(issuer)
+		allowed, err := s.DomainAllowed(dnsName)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\terror: %v\t\t\t\t\t\n", issuerName(issuer), err)
+			continue
+		}
+
+		provisioner := issuer.GetProvisioner()
+		index := s.SelectSolver(dnsName, labels)
+		secretRef := provisioner.APITokenSecretRef
+		if index >= 0 {
+			secretRef = provisioner.Solvers[index].APITokenSecretRef
+		}
+		secret := "<none>"
+		if secretRef.Name != "" {
+			secret = secretRef.Name + "/" + secretRef.Key
+		}
+
+		digicloudNamespace, record, zone := "<unknown>", "<unknown>", "<unknown>"
+		accounts, _ := s.Accounts(ctx, issuer)
+		for _, account := range accounts {
+			if account.Solver != index {
+				continue
+			}
+			digicloudNamespace = account.Provider.Namespace()
+			if target, err := account.Provider.ResolveChallenge(ctx, dnsName); err != nil {
+				record = "error: " + err.Error()
+			} else {
+				record, zone = target.EffectiveFQDN, target.Zone
+				if !hasZone(issuerStatus(issuer).Zones, zone, digicloudNamespace) {
+					zone += " (not found)"
+				}
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\t%s\t%s\t%s\n", issuerName(issuer), allowed,
+			controllers.Account{Solver: index}, secret, digicloudNamespace, record, zone)
+	}
+	return tw.Flush()
+}
+
+// ownedRecords returns the challenge records in the zones of the issuers selected by
+// ref and namespace, marking those tracked by any issuer in the cluster as in flight
+func (p *Plugin) ownedRecords(ctx context.Context, ref, namespace string) ([]ownedRecord, error) {
+	issuers, err := p.listIssuers(ctx, ref, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// In-flight challenges of issuers outside the selection must not be reported stale
+	all, err := p.listIssuers(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	inFlight := map[string]bool{}
+	for _, issuer := range all {
+		for _, record := range issuer.GetChallengeRecords() {
+			inFlight[challengeKey(record.FQDN, record.Value)] = true
+		}
+	}
+
+	var records []ownedRecord
+	var errs []error
+	seen := map[string]bool{}
+	for _, issuer := range issuers {
+		accounts, err := p.signer(issuer).Accounts(ctx, issuer)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", issuerName(issuer), err))
+		}
+		for _, account := range accounts {
+			domains, err := account.Provider.ListDomains(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", issuerName(issuer), account, err))
+				continue
+			}
+			for _, domain := range domains {
+				txt, err := account.Provider.ListTXTRecords(ctx, domain.Name)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s %s: zone %s: %w", issuerName(issuer), account, domain.Name, err))
+					continue
+				}
+				for _, record := range txt {
+					// Issuers sharing an account see the same records
+					key := account.Provider.Namespace() + "/" + domain.Name + "/" + record.ID
+					// Other clusters may present challenges with the same account
+					if record.Note != dnsprovider.RecordNoteFor(p.ClusterID) || seen[key] {
+						continue
+					}
+					seen[key] = true
+
+					fqdn, err := dnsname.Join(record.Name, domain.Name)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%s %s: zone %s: %w", issuerName(issuer), account, domain.Name, err))
+						continue
+					}
+					records = append(records, ownedRecord{
+						issuer:   issuerName(issuer),
+						account:  account,
+						zone:     domain.Name,
+						fqdn:     fqdn,
+						value:    record.Content,
+						inFlight: inFlight[challengeKey(fqdn, record.Content)],
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].zone != records[j].zone {
+			return records[i].zone < records[j].zone
+		}
+		return records[i].fqdn < records[j].fqdn
+	})
+	return records, errors.Join(errs...)
+}
+
+// listIssuers returns the issuer ref, or if ref is empty the issuers in namespace, or
+// in all namespaces if namespace is empty, followed by all cluster issuers
+func (p *Plugin) listIssuers(ctx context.Context, ref, namespace string) ([]controllers.IssuerObject, error) {
+	if ref != "" {
+		issuer, key := controllers.IssuerForRef(ref)
+		if err := p.Client.Get(ctx, key, issuer); err != nil {
+			return nil, fmt.Errorf("failed to get issuer %s: %w", ref, err)
+		}
+		setKind(issuer)
+		return []controllers.IssuerObject{issuer}, nil
+	}
+
+	var issuerList digicloudv1alpha1.DigicloudIssuerList
+	if err := p.Client.List(ctx, &issuerList, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list issuers: %w", err)
+	}
+	var clusterIssuerList digicloudv1alpha1.DigicloudClusterIssuerList
+	if err := p.Client.List(ctx, &clusterIssuerList); err != nil {
+		return nil, fmt.Errorf("failed to list cluster issuers: %w", err)
+	}
+
+	issuers := make([]controllers.IssuerObject, 0, len(issuerList.Items)+len(clusterIssuerList.Items))
+	for i := range issuerList.Items {
+		issuers = append(issuers, &issuerList.Items[i])
+	}
+	for i := range clusterIssuerList.Items {
+		issuers = append(issuers, &clusterIssuerList.Items[i])
+	}
+	for _, issuer := range issuers {
+		setKind(issuer)
+	}
+	return issuers, nil
+}
+
+// signer returns the signer of issuer, which reads its accounts like the controller
+func (p *Plugin) signer(issuer controllers.IssuerObject) *controllers.DigicloudSigner {
+	return controllers.NewDigicloudSigner(p.Client, *issuer.GetProvisioner(), p.ClusterResourceNamespace)
+}
+
+// table returns a tab writer on Out with the header row written
+func (p *Plugin) table(header ...string) *tabwriter.Writer {
+	tw := tabwriter.NewWriter(p.Out, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	return tw
+}
+
+// setKind sets the kind of issuer, which typed clients leave empty
+func setKind(issuer controllers.IssuerObject) {
+	kind := "DigicloudIssuer"
+	if _, ok := issuer.(*digicloudv1alpha1.DigicloudClusterIssuer); ok {
+		kind = "DigicloudClusterIssuer"
+	}
+	issuer.GetObjectKind().SetGroupVersionKind(digicloudv1alpha1.GroupVersion.WithKind(kind))
+}
+
+// issuerName returns the reference of issuer as accepted by controllers.IssuerForRef
+func issuerName(issuer controllers.IssuerObject) string {
+	if issuer.GetNamespace() == "" {
+		return issuer.GetName()
+	}
+	return issuer.GetNamespace() + "/" + issuer.GetName()
+}
+
+// issuerStatus returns the status of a DigicloudIssuer or DigicloudClusterIssuer
+func issuerStatus(issuer controllers.IssuerObject) digicloudv1alpha1.DigicloudIssuerStatus {
+	switch issuer := issuer.(type) {
+	case *digicloudv1alpha1.DigicloudIssuer:
+		return issuer.Status
+	case *digicloudv1alpha1.DigicloudClusterIssuer:
+		return digicloudv1alpha1.DigicloudIssuerStatus(issuer.Status)
+	}
+	return digicloudv1alpha1.DigicloudIssuerStatus{}
+}
+
+// hasZone reports whether zones holds zone in digicloudNamespace
+func hasZone(zones []digicloudv1alpha1.ZoneStatus, zone, digicloudNamespace string) bool {
+	for _, z := range zones {
+		if z.Name == zone && (z.Namespace == "" || z.Namespace == digicloudNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// challengeKey identifies a challenge record by its FQDN and value
+func challengeKey(fqdn, value string) string {
+	return strings.ToLower(dns01.ToFqdn(fqdn)) + " " + value
+}
+
+// orNone returns value, or <none> if it is empty
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,174 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+)
+
+// testClusterID is the ID of the cluster the test plugin runs against
+const testClusterID = "cluster-a"
+
+// recordsAPI is a Digicloud API whose namespace retail holds the zone example.com,
+// with two challenge records of the issuer, one of an issuer in another cluster, one
+// created before records were noted with a cluster and one record of someone else
+type recordsAPI struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	deleted []string
+}
+
+func newRecordsAPI(t *testing.T) *recordsAPI {
+	t.Helper()
+
+	api := &recordsAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Digicloud-Namespace") != "retail":
+			_, _ = w.Write([]byte(`[]`))
+		case r.URL.Path == "/v1/edge/domains":
+			_, _ = w.Write([]byte(`[{"id": "example.com", "name": "example.com"}]`))
+		case r.URL.Path == "/v1/edge/domains/example.com/records":
+			_ = json.NewEncoder(w).Encode(dnsprovider.DNSRecordListResponse{Records: []dnsprovider.DNSTXTRecordDetails{
+				{ID: "rec-1", Name: "_acme-challenge", Type: "TXT", Content: "in-flight", Note: dnsprovider.RecordNoteFor(testClusterID)},
+				{ID: "rec-2", Name: "_acme-challenge.www", Type: "TXT", Content: "stale", Note: dnsprovider.RecordNoteFor(testClusterID)},
+				{ID: "rec-3", Name: "_acme-challenge.mail", Type: "TXT", Content: "manual"},
+				{ID: "rec-4", Name: "www", Type: "A", Content: "192.0.2.1", Note: dnsprovider.RecordNoteFor(testClusterID)},
+				{ID: "rec-5", Name: "_acme-challenge.api", Type: "TXT", Content: "other-cluster", Note: dnsprovider.RecordNoteFor("cluster-b")},
+				{ID: "rec-6", Name: "_acme-challenge.api", Type: "TXT", Content: "unknown-cluster", Note: dnsprovider.RecordNote},
+			}})
+		case r.Method == http.MethodDelete:
+			api.mu.Lock()
+			api.deleted = append(api.deleted, r.URL.Path)
+			api.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+func newTestPlugin(t *testing.T, apiBaseURL string) (*Plugin, *bytes.Buffer) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, digicloudv1alpha1.AddToScheme(scheme))
+
+	issuer := &digicloudv1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "issuer", Namespace: "default"},
+		Spec: digicloudv1alpha1.DigicloudIssuerSpec{
+			Provisioner: digicloudv1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:        apiBaseURL,
+				APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "token"},
+				AllowedDomains:    []string{"*.example.com", "*.media.example.com"},
+				Solvers: []digicloudv1alpha1.DigicloudSolver{{
+					APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{Name: "media-credentials", Key: "token"},
+					Selector:          &digicloudv1alpha1.SolverSelector{DNSZones: []string{"media.example.com"}},
+				}},
+			},
+		},
+		Status: digicloudv1alpha1.DigicloudIssuerStatus{
+			Zones: []digicloudv1alpha1.ZoneStatus{{Name: "example.com", ID: "example.com", Namespace: "retail"}},
+		},
+	}
+	// The challenge of another issuer sharing the account is in flight
+	other := &digicloudv1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "other"},
+		Status: digicloudv1alpha1.DigicloudClusterIssuerStatus{
+			ChallengeRecords: []digicloudv1alpha1.ChallengeRecord{
+				{Request: "request-1", FQDN: "_acme-challenge.example.com.", Zone: "example.com", Value: "in-flight"},
+			},
+		},
+	}
+	secrets := []*corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("token"), "namespace": []byte("retail")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "media-credentials", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("token"), "namespace": []byte("media")},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(issuer, other, secrets[0], secrets[1]).Build()
+	var out bytes.Buffer
+	return &Plugin{Client: c, ClusterID: testClusterID, Out: &out}, &out
+}
+
+func TestPlugin_Records(t *testing.T) {
+	api := newRecordsAPI(t)
+	plugin, out := newTestPlugin(t, api.URL)
+
+	require.NoError(t, plugin.Records(context.Background(), "default/issuer", ""))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3, out.String())
+	assert.Regexp(t, `^default/issuer\s+issuer\s+example.com\s+_acme-challenge.example.com.\s+in-flight\s+in-flight$`, lines[1])
+	assert.Regexp(t, `^default/issuer\s+issuer\s+example.com\s+_acme-challenge.www.example.com.\s+stale\s+stale$`, lines[2])
+}
+
+func TestPlugin_Cleanup(t *testing.T) {
+	api := newRecordsAPI(t)
+	plugin, out := newTestPlugin(t, api.URL)
+
+	require.NoError(t, plugin.Cleanup(context.Background(), "default/issuer", "", true))
+	assert.Contains(t, out.String(), `Would delete _acme-challenge.www.example.com. "stale" from zone example.com`)
+	assert.Empty(t, api.deleted)
+
+	out.Reset()
+	require.NoError(t, plugin.Cleanup(context.Background(), "default/issuer", "", false))
+	assert.Equal(t, []string{"/v1/edge/domains/example.com/records/rec-2"}, api.deleted)
+	assert.Contains(t, out.String(), "1 stale records deleted")
+}
+
+func TestPlugin_Revalidate(t *testing.T) {
+	plugin, _ := newTestPlugin(t, "")
+
+	require.NoError(t, plugin.Revalidate(context.Background(), "default/issuer"))
+
+	var issuer digicloudv1alpha1.DigicloudIssuer
+	require.NoError(t, plugin.Client.Get(context.Background(), types.NamespacedName{Name: "issuer", Namespace: "default"}, &issuer))
+	assert.NotEmpty(t, issuer.Annotations[controllers.RevalidateAnnotation])
+
+	assert.Error(t, plugin.Revalidate(context.Background(), "default/missing"))
+}
+
+func TestPlugin_Which(t *testing.T) {
+	plugin, out := newTestPlugin(t, "")
+
+	require.NoError(t, plugin.Which(context.Background(), "www.example.com", "default", nil))
+	assert.Regexp(t, `default/issuer\s+true\s+issuer\s+digicloud-credentials/token\s+retail\s+_acme-challenge.www.example.com.\s+example.com\n`, out.String())
+	assert.Regexp(t, `other\s+true\s+issuer\s+<none>`, out.String())
+
+	out.Reset()
+	require.NoError(t, plugin.Which(context.Background(), "cdn.media.example.com", "default", nil))
+	assert.Regexp(t, `default/issuer\s+true\s+solver 0\s+media-credentials/token\s+media\s+_acme-challenge.cdn.media.example.com.\s+example.com \(not found\)`, out.String())
+
+	out.Reset()
+	require.NoError(t, plugin.Which(context.Background(), "example.org", "default", nil))
+	assert.Regexp(t, `default/issuer\s+false`, out.String())
+}
@@ -0,0 +1,127 @@
+// Package metrics defines the Prometheus collectors of the issuer. They are
+// registered with controller-runtime's registry and served on the manager's
+// metrics endpoint.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "digicloud_issuer"
+
+var (
+	// APIRequests counts Digicloud API requests by endpoint, method and response
+	// status. Requests that fail without a response have status "error".
+	APIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "Digicloud API requests by endpoint, method and response status.",
+	}, []string{"endpoint", "method", "status"})
+
+	// APIRequestDuration observes the latency of Digicloud API requests by endpoint,
+	// method and status class, such as "2xx". Requests that fail without a response
+	// have status class "error".
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of Digicloud API requests by endpoint, method and response status class.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "status_class"})
+
+	// APIRetries counts Digicloud API requests retried after being rate limited
+	APIRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_retries_total",
+		Help:      "Digicloud API requests retried by endpoint and method.",
+	}, []string{"endpoint", "method"})
+
+	// APIRateLimited counts Digicloud API responses with status 429
+	APIRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_rate_limited_total",
+		Help:      "Digicloud API requests rejected by rate limiting, by endpoint and method.",
+	}, []string{"endpoint", "method"})
+
+	// ChallengeOperations counts presented and cleaned up challenge records by result
+	ChallengeOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "challenge_operations_total",
+		Help:      "Challenge TXT record operations by operation (present, cleanup) and result (success, error).",
+	}, []string{"operation", "result"})
+
+	// PropagationDuration observes the time from presenting a challenge record until
+	// it is found on the authoritative nameservers, or until propagation timed out
+	PropagationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "propagation_duration_seconds",
+		Help:      "Time from presenting a challenge TXT record until it propagated, by result (confirmed, timeout).",
+		Buckets:   []float64{1, 5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"result"})
+
+	// ACMEOrders counts certificate orders by result
+	ACMEOrders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "acme_orders_total",
+		Help:      "ACME certificate orders by result (issued, failed).",
+	}, []string{"result"})
+
+	// IssuerReady is 1 for issuers whose Ready condition is True and 0 otherwise
+	IssuerReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "issuer_ready",
+		Help:      "Whether an issuer is ready, by kind, namespace and name.",
+	}, []string{"kind", "namespace", "name"})
+)
+
+// Results of the operations counted by ChallengeOperations, PropagationDuration and
+// ACMEOrders
+const (
+	ResultSuccess   = "success"
+	ResultError     = "error"
+	ResultConfirmed = "confirmed"
+	ResultTimeout   = "timeout"
+	ResultIssued    = "issued"
+	ResultFailed    = "failed"
+)
+
+// Operations counted by ChallengeOperations
+const (
+	OperationPresent = "present"
+	OperationCleanUp = "cleanup"
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		APIRequests,
+		APIRequestDuration,
+		APIRetries,
+		APIRateLimited,
+		ChallengeOperations,
+		PropagationDuration,
+		ACMEOrders,
+		IssuerReady,
+	)
+}
+
+// Result returns ResultSuccess if err is nil and ResultError otherwise
+func Result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+// SetIssuerReady records whether the issuer of kind in namespace is ready
+func SetIssuerReady(kind, namespace, name string, ready bool) {
+	value := 0.0
+	if ready {
+		value = 1
+	}
+	IssuerReady.WithLabelValues(kind, namespace, name).Set(value)
+}
+
+// DeleteIssuerReady removes the readiness of a deleted issuer
+func DeleteIssuerReady(kind, namespace, name string) {
+	IssuerReady.DeleteLabelValues(kind, namespace, name)
+}
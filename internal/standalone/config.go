@@ -0,0 +1,60 @@
+package standalone
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+// Config configures standalone issuance. Its fields mirror DigicloudIssuerProvisioner,
+// with the data of the referenced secrets given inline.
+//
+//	apiTokenSecretRef:
+//	  name: digicloud
+//	  key: token
+//	acme:
+//	  email: admin@example.com
+//	  termsOfServiceAgreed: true
+//	secrets:
+//	  digicloud:
+//	    token: ${DIGICLOUD_API_TOKEN}
+//	    namespace: my-namespace
+type Config struct {
+	digicloudv1alpha1.DigicloudIssuerProvisioner `json:",inline"`
+
+	// Secrets holds the data of the secrets referenced by apiTokenSecretRef and the
+	// solvers, keyed by secret name
+	Secrets map[string]map[string]string `json:"secrets,omitempty"`
+
+	// AccountKeyFile is the PEM file holding the ACME account key. A new key is
+	// generated and written to it if it does not exist. Relative paths are resolved
+	// against the directory of the config file. Defaults to account.key.
+	AccountKeyFile string `json:"accountKeyFile,omitempty"`
+}
+
+// LoadConfig reads the config at path. References to environment variables in the
+// form $VAR or ${VAR} are expanded, so that tokens need not be stored in the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var config Config
+	if err := yaml.UnmarshalStrict([]byte(os.ExpandEnv(string(data))), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if config.AccountKeyFile == "" {
+		config.AccountKeyFile = "account.key"
+	}
+	if !filepath.IsAbs(config.AccountKeyFile) {
+		config.AccountKeyFile = filepath.Join(filepath.Dir(path), config.AccountKeyFile)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,27 @@
+package standalone
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// writerRecorder implements record.EventRecorder by writing events as lines to w
+type writerRecorder struct {
+	w io.Writer
+}
+
+func (r *writerRecorder) Event(_ runtime.Object, eventtype, reason, message string) {
+	if r.w != nil {
+		fmt.Fprintf(r.w, "%-8s %-22s %s\n", eventtype, reason, message)
+	}
+}
+
+func (r *writerRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *writerRecorder) AnnotatedEventf(object runtime.Object, _ map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Eventf(object, eventtype, reason, messageFmt, args...)
+}
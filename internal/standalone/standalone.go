@@ -0,0 +1,301 @@
+// Package standalone issues certificates outside Kubernetes with the same signer the
+// controller uses. The issuer, its secrets and the certificate request live in an
+// in-memory object store for the duration of one issuance.
+package standalone
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/cert-manager/issuer-lib/controllers/signer"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+)
+
+const (
+	// namespace is the namespace of the in-memory issuer and its secrets
+	namespace = "standalone"
+
+	// issuerName is the name of the in-memory issuer
+	issuerName = "standalone"
+)
+
+// Key types of the certificate private key
+const (
+	KeyTypeECDSA = "ecdsa"
+	KeyTypeRSA   = "rsa"
+)
+
+// Request describes a certificate to obtain
+type Request struct {
+	// DNSNames are the names of the certificate, which may include wildcards
+	DNSNames []string
+
+	// KeyType is the type of the private key, KeyTypeECDSA (P-256) or KeyTypeRSA
+	// (2048 bits). Defaults to KeyTypeECDSA.
+	KeyType string
+}
+
+// Certificate is an issued certificate with its private key, all PEM encoded
+type Certificate struct {
+	// ChainPEM is the certificate followed by the intermediates
+	ChainPEM []byte
+
+	// CAPEM is the certificate of the issuing CA
+	CAPEM []byte
+
+	// KeyPEM is the private key
+	KeyPEM []byte
+}
+
+// Obtain generates a private key and obtains a certificate for req as the signer of a
+// DigicloudIssuer configured with config would. Challenge and order events are
+// written to events.
+func Obtain(ctx context.Context, config *Config, req Request, events io.Writer) (*Certificate, error) {
+	if len(req.DNSNames) == 0 {
+		return nil, errors.New("at least one DNS name is required")
+	}
+
+	key, keyPEM, err := generateKey(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: req.DNSNames}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	objects, err := newObjects(config)
+	if err != nil {
+		return nil, err
+	}
+	issuer := &digicloudv1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: issuerName, Namespace: namespace},
+		Spec:       digicloudv1alpha1.DigicloudIssuerSpec{Provisioner: config.DigicloudIssuerProvisioner},
+	}
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: issuerName, Namespace: namespace, UID: uuid.NewUUID()},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+			IssuerRef: cmmeta.ObjectReference{Name: issuerName, Kind: "DigicloudIssuer", Group: digicloudv1alpha1.GroupVersion.Group},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, cmapi.AddToScheme, digicloudv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			return nil, err
+		}
+	}
+	store := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append(objects, issuer, cr)...).
+		WithStatusSubresource(issuer).
+		Build()
+
+	s := controllers.NewDigicloudSigner(store, config.DigicloudIssuerProvisioner, namespace)
+	s.SetEventRecorder(&writerRecorder{w: events})
+	bundle, signErr := s.Sign(ctx, signer.CertificateRequestObjectFromCertificateRequest(cr), issuer)
+
+	// The signer generates the account key on first use
+	if err := saveAccountKey(ctx, store, config); err != nil {
+		return nil, err
+	}
+	if signErr != nil {
+		return nil, signErr
+	}
+
+	return &Certificate{ChainPEM: bundle.ChainPEM, CAPEM: bundle.CAPEM, KeyPEM: keyPEM}, nil
+}
+
+// newObjects returns the secrets of config, including the ACME account key if its
+// file exists
+func newObjects(config *Config) ([]client.Object, error) {
+	var objects []client.Object
+	for name, data := range config.Secrets {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string][]byte{},
+		}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		objects = append(objects, secret)
+	}
+
+	keyPEM, err := os.ReadFile(config.AccountKeyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return objects, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACME account key: %w", err)
+	}
+	if _, err := acme.ParseAccountKey(keyPEM); err != nil {
+		return nil, fmt.Errorf("%s: %w", config.AccountKeyFile, err)
+	}
+	name, key := accountKeyRef(config)
+	return append(objects, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string][]byte{key: keyPEM},
+	}), nil
+}
+
+// saveAccountKey writes the ACME account key generated by the signer to the account
+// key file of config, if the file does not exist yet
+func saveAccountKey(ctx context.Context, store client.Reader, config *Config) error {
+	if _, err := os.Stat(config.AccountKeyFile); err == nil {
+		return nil
+	}
+
+	name, key := accountKeyRef(config)
+	var secret corev1.Secret
+	if err := store.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &secret); err != nil {
+		// No key was generated, e.g. because signing failed before
+		return client.IgnoreNotFound(err)
+	}
+	return writeFile(config.AccountKeyFile, secret.Data[key], 0o600)
+}
+
+// accountKeyRef returns the secret and key the signer reads the ACME account key from
+func accountKeyRef(config *Config) (string, string) {
+	if config.ACME != nil && config.ACME.PrivateKeySecretRef != nil {
+		return config.ACME.PrivateKeySecretRef.Name, config.ACME.PrivateKeySecretRef.Key
+	}
+	return issuerName + "-acme-account-key", corev1.TLSPrivateKeyKey
+}
+
+// generateKey generates a private key of keyType and returns it PEM encoded
+func generateKey(keyType string) (crypto.Signer, []byte, error) {
+	var key crypto.Signer
+	var err error
+	switch keyType {
+	case "", KeyTypeECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeRSA:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %q, must be %s or %s", keyType, KeyTypeECDSA, KeyTypeRSA)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Files written by WriteCertificate
+const (
+	CertFile      = "cert.pem"
+	ChainFile     = "chain.pem"
+	FullChainFile = "fullchain.pem"
+	KeyFile       = "privkey.pem"
+)
+
+// WriteCertificate writes the certificate, its intermediates, the full chain and the
+// private key of cert to dir. Each file is replaced atomically.
+func WriteCertificate(dir string, cert *Certificate) error {
+	leaf, rest := pem.Decode(cert.ChainPEM)
+	if leaf == nil {
+		return errors.New("issued certificate chain contains no certificate")
+	}
+	chainPEM := rest
+	if len(chainPEM) == 0 {
+		chainPEM = cert.CAPEM
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{KeyFile, cert.KeyPEM, 0o600},
+		{CertFile, pem.EncodeToMemory(leaf), 0o644},
+		{ChainFile, chainPEM, 0o644},
+		{FullChainFile, cert.ChainPEM, 0o644},
+	}
+	for _, file := range files {
+		if err := writeFile(filepath.Join(dir, file.name), file.data, file.perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenewalDue reports whether the certificate in dir expires within renewBefore, and
+// returns its DNS names. A missing certificate is due.
+func RenewalDue(dir string, renewBefore time.Duration) (bool, []string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CertFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false, nil, fmt.Errorf("%s contains no PEM encoded certificate", filepath.Join(dir, CertFile))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	return time.Until(cert.NotAfter) < renewBefore, cert.DNSNames, nil
+}
+
+// writeFile replaces the file at path with data by renaming a temporary file
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package standalone
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+apiTokenSecretRef:
+  name: digicloud
+  key: token
+ttl: 120
+acme:
+  email: admin@example.com
+secrets:
+  digicloud:
+    token: ${TEST_DIGICLOUD_TOKEN}
+    namespace: my-namespace
+`), 0o600))
+	t.Setenv("TEST_DIGICLOUD_TOKEN", "secret-token")
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "digicloud", config.APITokenSecretRef.Name)
+	require.NotNil(t, config.TTL)
+	assert.Equal(t, 120, *config.TTL)
+	require.NotNil(t, config.ACME)
+	assert.Equal(t, "admin@example.com", config.ACME.Email)
+	assert.Equal(t, "secret-token", config.Secrets["digicloud"]["token"])
+	assert.Equal(t, filepath.Join(dir, "account.key"), config.AccountKeyFile)
+}
+
+func TestLoadConfig_UnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("apiTokenSecretRf: {}\n"), 0o600))
+
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, "apiTokenSecretRf")
+}
+
+func TestGenerateKey(t *testing.T) {
+	for _, keyType := range []string{"", KeyTypeECDSA, KeyTypeRSA} {
+		key, keyPEM, err := generateKey(keyType)
+		require.NoError(t, err, keyType)
+
+		block, _ := pem.Decode(keyPEM)
+		require.NotNil(t, block, keyType)
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		require.NoError(t, err, keyType)
+		// Parsed RSA keys may encode their precomputed values differently
+		equaler, ok := key.(interface{ Equal(crypto.PrivateKey) bool })
+		require.True(t, ok, keyType)
+		assert.True(t, equaler.Equal(parsed), keyType)
+	}
+
+	_, _, err := generateKey("dsa")
+	assert.ErrorContains(t, err, "unsupported key type")
+}
+
+func TestWriteCertificate_RenewalDue(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "certs")
+
+	due, dnsNames, err := RenewalDue(dir, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, due, "a missing certificate is due")
+	assert.Empty(t, dnsNames)
+
+	leafPEM := selfSignedPEM(t, []string{"example.com", "*.example.com"}, 48*time.Hour)
+	caPEM := selfSignedPEM(t, nil, 48*time.Hour)
+	cert := &Certificate{
+		ChainPEM: append(append([]byte{}, leafPEM...), caPEM...),
+		CAPEM:    caPEM,
+		KeyPEM:   []byte("key"),
+	}
+	require.NoError(t, WriteCertificate(dir, cert))
+
+	for name, want := range map[string][]byte{
+		CertFile:      leafPEM,
+		ChainFile:     caPEM,
+		FullChainFile: cert.ChainPEM,
+		KeyFile:       cert.KeyPEM,
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	info, err := os.Stat(filepath.Join(dir, KeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	due, dnsNames, err = RenewalDue(dir, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, due)
+	assert.Equal(t, []string{"example.com", "*.example.com"}, dnsNames)
+
+	due, _, err = RenewalDue(dir, 72*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, due)
+}
+
+func selfSignedPEM(t *testing.T, dnsNames []string, validity time.Duration) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
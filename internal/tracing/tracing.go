@@ -0,0 +1,120 @@
+// Package tracing configures OpenTelemetry tracing of the issuer. Spans are started
+// with the global tracer provider, which is a no-op unless Setup configures an OTLP
+// exporter, and carry the UID of the CertificateRequest they were started for so
+// that the work done for one request can be found across traces.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vamirreza/digicloud-issuer/internal/version"
+)
+
+// TracerName is the name of the tracer spans are started with
+const TracerName = "github.com/vamirreza/digicloud-issuer"
+
+// RequestUIDKey is the span attribute holding the UID of the CertificateRequest a
+// span was started for
+const RequestUIDKey = attribute.Key("certificaterequest.uid")
+
+// Options configures the export of traces
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Tracing is disabled if empty.
+	Endpoint string
+
+	// Insecure disables TLS for the connection to the collector
+	Insecure bool
+
+	// SampleRatio is the fraction of traces sampled, between 0 and 1. The sampling
+	// decision of a parent span is respected.
+	SampleRatio float64
+
+	// ServiceName is the service name reported in the trace resource
+	ServiceName string
+}
+
+// Setup installs a tracer provider exporting spans to the OTLP collector in opts as
+// the global tracer provider. The returned function flushes and stops the exporter.
+// Nothing is installed if opts has no endpoint.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(version.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+type requestUIDKey struct{}
+
+// WithRequestUID returns a copy of ctx in which spans are started for the
+// CertificateRequest with uid
+func WithRequestUID(ctx context.Context, uid types.UID) context.Context {
+	if uid == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestUIDKey{}, uid)
+}
+
+// RequestUID returns the UID of the CertificateRequest spans in ctx are started for
+func RequestUID(ctx context.Context) types.UID {
+	uid, _ := ctx.Value(requestUIDKey{}).(types.UID)
+	return uid
+}
+
+// Start starts a span with the global tracer, adding the CertificateRequest UID
+// of ctx to its attributes
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if uid := RequestUID(ctx); uid != "" {
+		opts = append(opts, trace.WithAttributes(RequestUIDKey.String(string(uid))))
+	}
+	return otel.Tracer(TracerName).Start(ctx, name, opts...)
+}
+
+// End records err, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
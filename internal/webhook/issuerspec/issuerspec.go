@@ -0,0 +1,98 @@
+// Package issuerspec holds the defaults and validation rules the admission webhooks
+// of every issuer API version share, so that the versions cannot drift apart.
+package issuerspec
+
+import (
+	"net/url"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsname"
+)
+
+const (
+	// DefaultAPIBaseURL, DefaultTTL, DefaultPropagationTimeout and DefaultPollingInterval
+	// are set by the defaulting webhooks on issuers that leave them unset
+	DefaultAPIBaseURL         = "https://api.digicloud.ir"
+	DefaultTTL                = 300
+	DefaultPropagationTimeout = 5 * time.Minute
+	DefaultPollingInterval    = 10 * time.Second
+
+	// MinTTL and MaxTTL are the record TTLs the Digicloud API accepts
+	MinTTL = 60
+	MaxTTL = 86400
+)
+
+// SkipUpdateValidation reports whether an update of issuer is admitted without
+// validating its spec: while the issuer is being deleted, or when the update leaves
+// its defaulted spec unchanged. Issuers created with a spec the webhooks reject can
+// then still have their finalizer added and removed.
+func SkipUpdateValidation(issuer metav1.Object, oldSpec, newSpec any) bool {
+	return !issuer.GetDeletionTimestamp().IsZero() || equality.Semantic.DeepEqual(oldSpec, newSpec)
+}
+
+// ValidateAllowedDomains checks that every allowedDomains entry compiles
+func ValidateAllowedDomains(allowedDomains []string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, entry := range allowedDomains {
+		if _, err := dnsname.CompilePattern(entry); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), entry, err.Error()))
+		}
+	}
+	return errs
+}
+
+// ValidateURL checks that value is an absolute http or https URL
+func ValidateURL(value string, path *field.Path) field.ErrorList {
+	u, err := url.Parse(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return field.ErrorList{field.Invalid(path, value, "must be an http or https URL")}
+	}
+	if u.Host == "" {
+		return field.ErrorList{field.Invalid(path, value, "must include a host")}
+	}
+	return nil
+}
+
+// ValidateSecretRef checks that a secret reference names both a secret and a key
+func ValidateSecretRef(name, key string, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if name == "" {
+		errs = append(errs, field.Required(path.Child("name"), "secret name must not be empty"))
+	}
+	if key == "" {
+		errs = append(errs, field.Required(path.Child("key"), "secret key must not be empty"))
+	}
+	return errs
+}
+
+// ValidateTTL checks that a record TTL is accepted by the Digicloud API
+func ValidateTTL(ttl *int, path *field.Path) field.ErrorList {
+	if ttl != nil && (*ttl < MinTTL || *ttl > MaxTTL) {
+		return field.ErrorList{field.Invalid(path, *ttl, "must be between 60 and 86400 seconds")}
+	}
+	return nil
+}
+
+// ValidatePropagation checks that the propagation timeout and polling interval are
+// positive and that polling happens more often than the timeout
+func ValidatePropagation(timeout, pollingInterval *metav1.Duration, timeoutPath, pollingIntervalPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if timeout != nil && timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(timeoutPath, timeout.Duration.String(), "must be positive"))
+	}
+	if pollingInterval != nil && pollingInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(pollingIntervalPath, pollingInterval.Duration.String(), "must be positive"))
+	}
+	if timeout != nil && pollingInterval != nil && pollingInterval.Duration >= timeout.Duration {
+		errs = append(errs, field.Invalid(pollingIntervalPath, pollingInterval.Duration.String(),
+			"must be less than "+timeoutPath.String()+" "+timeout.Duration.String()))
+	}
+	return errs
+}
@@ -0,0 +1,62 @@
+package issuerspec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateURL(t *testing.T) {
+	path := field.NewPath("apiBaseUrl")
+	assert.Empty(t, ValidateURL("https://api.digicloud.ir", path))
+	assert.Empty(t, ValidateURL("http://localhost:8080", path))
+	assert.Len(t, ValidateURL("api.digicloud.ir", path), 1)
+	assert.Len(t, ValidateURL("ftp://api.digicloud.ir", path), 1)
+	assert.Len(t, ValidateURL("https://", path), 1)
+}
+
+func TestValidateSecretRef(t *testing.T) {
+	path := field.NewPath("apiTokenSecretRef")
+	assert.Empty(t, ValidateSecretRef("digicloud-credentials", "token", path))
+
+	errs := ValidateSecretRef("", "", path)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "apiTokenSecretRef.name", errs[0].Field)
+	assert.Equal(t, "apiTokenSecretRef.key", errs[1].Field)
+}
+
+func TestValidateTTL(t *testing.T) {
+	ttl := func(ttl int) *int { return &ttl }
+	path := field.NewPath("ttl")
+	assert.Empty(t, ValidateTTL(nil, path))
+	assert.Empty(t, ValidateTTL(ttl(MinTTL), path))
+	assert.Empty(t, ValidateTTL(ttl(MaxTTL), path))
+	assert.Len(t, ValidateTTL(ttl(MinTTL-1), path), 1)
+	assert.Len(t, ValidateTTL(ttl(MaxTTL+1), path), 1)
+}
+
+func TestValidatePropagation(t *testing.T) {
+	duration := func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+	timeoutPath, pollingIntervalPath := field.NewPath("timeout"), field.NewPath("pollingInterval")
+
+	assert.Empty(t, ValidatePropagation(nil, nil, timeoutPath, pollingIntervalPath))
+	assert.Empty(t, ValidatePropagation(duration(time.Minute), duration(time.Second), timeoutPath, pollingIntervalPath))
+
+	errs := ValidatePropagation(duration(time.Minute), duration(time.Minute), timeoutPath, pollingIntervalPath)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Detail, "must be less than timeout 1m0s")
+
+	assert.Len(t, ValidatePropagation(duration(0), nil, timeoutPath, pollingIntervalPath), 1)
+}
+
+func TestSkipUpdateValidation(t *testing.T) {
+	issuer := &metav1.ObjectMeta{Name: "digicloud"}
+	assert.True(t, SkipUpdateValidation(issuer, []string{"a"}, []string{"a"}))
+	assert.False(t, SkipUpdateValidation(issuer, []string{"a"}, []string{"b"}))
+
+	issuer.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	assert.True(t, SkipUpdateValidation(issuer, []string{"a"}, []string{"b"}))
+}
@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/webhook/issuerspec"
+)
+
+var digicloudclusterissuerlog = logf.Log.WithName("digicloudclusterissuer-resource")
+
+// SetupDigicloudClusterIssuerWebhookWithManager registers the webhooks for DigicloudClusterIssuer in the manager
+func SetupDigicloudClusterIssuerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&digicloudv1alpha1.DigicloudClusterIssuer{}).
+		WithDefaulter(&DigicloudClusterIssuerCustomDefaulter{}).
+		WithValidator(&DigicloudClusterIssuerCustomValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-digicloud-issuer-vamirreza-github-io-v1alpha1-digicloudclusterissuer,mutating=true,failurePolicy=fail,sideEffects=None,groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers,verbs=create;update,versions=v1alpha1,name=mdigicloudclusterissuer-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DigicloudClusterIssuerCustomDefaulter sets default values on DigicloudClusterIssuer resources
+type DigicloudClusterIssuerCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &DigicloudClusterIssuerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *DigicloudClusterIssuerCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	issuer, ok := obj.(*digicloudv1alpha1.DigicloudClusterIssuer)
+	if !ok {
+		return fmt.Errorf("expected a DigicloudClusterIssuer object but got %T", obj)
+	}
+	digicloudclusterissuerlog.V(1).Info("Defaulting", "name", issuer.GetName())
+
+	defaultProvisioner(&issuer.Spec.Provisioner)
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-digicloud-issuer-vamirreza-github-io-v1alpha1-digicloudclusterissuer,mutating=false,failurePolicy=fail,sideEffects=None,groups=digicloud.issuer.vamirreza.github.io,resources=digicloudclusterissuers,verbs=create;update,versions=v1alpha1,name=vdigicloudclusterissuer-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// DigicloudClusterIssuerCustomValidator validates DigicloudClusterIssuer resources on create and update
+type DigicloudClusterIssuerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &DigicloudClusterIssuerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *DigicloudClusterIssuerCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	issuer, ok := obj.(*digicloudv1alpha1.DigicloudClusterIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudClusterIssuer object but got %T", obj)
+	}
+	return nil, validateDigicloudClusterIssuer(issuer)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *DigicloudClusterIssuerCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	issuer, ok := newObj.(*digicloudv1alpha1.DigicloudClusterIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudClusterIssuer object but got %T", newObj)
+	}
+	oldIssuer, ok := oldObj.(*digicloudv1alpha1.DigicloudClusterIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudClusterIssuer object but got %T", oldObj)
+	}
+
+	oldSpec := oldIssuer.Spec.DeepCopy()
+	defaultProvisioner(&oldSpec.Provisioner)
+	if issuerspec.SkipUpdateValidation(issuer, *oldSpec, issuer.Spec) {
+		return nil, nil
+	}
+	return nil, validateDigicloudClusterIssuer(issuer)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *DigicloudClusterIssuerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateDigicloudClusterIssuer(issuer *digicloudv1alpha1.DigicloudClusterIssuer) error {
+	specPath := field.NewPath("spec")
+	errs := validateProvisioner(&issuer.Spec.Provisioner, specPath.Child("provisioner"))
+
+	if issuer.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(issuer.Spec.NamespaceSelector); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("namespaceSelector"), issuer.Spec.NamespaceSelector, err.Error()))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(digicloudv1alpha1.GroupVersion.WithKind("DigicloudClusterIssuer").GroupKind(), issuer.Name, errs)
+}
@@ -0,0 +1,85 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/webhook/issuerspec"
+)
+
+// defaultProvisioner sets the defaults of unset provisioner fields
+func defaultProvisioner(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+	if p.APIBaseURL == "" {
+		p.APIBaseURL = issuerspec.DefaultAPIBaseURL
+	}
+	if p.TTL == nil {
+		ttl := issuerspec.DefaultTTL
+		p.TTL = &ttl
+	}
+	if p.PropagationTimeout == nil {
+		p.PropagationTimeout = &metav1.Duration{Duration: issuerspec.DefaultPropagationTimeout}
+	}
+	if p.PollingInterval == nil {
+		p.PollingInterval = &metav1.Duration{Duration: issuerspec.DefaultPollingInterval}
+	}
+	if p.CNAMEStrategy == "" {
+		p.CNAMEStrategy = digicloudv1alpha1.NoneStrategy
+	}
+	if p.ACME != nil && p.ACME.Server == "" {
+		p.ACME.Server = acme.DefaultServer
+	}
+	for i := range p.Solvers {
+		if p.Solvers[i].APIBaseURL == "" {
+			p.Solvers[i].APIBaseURL = p.APIBaseURL
+		}
+	}
+}
+
+// validateProvisioner validates a provisioner spec at path
+func validateProvisioner(p *digicloudv1alpha1.DigicloudIssuerProvisioner, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, issuerspec.ValidateURL(p.APIBaseURL, path.Child("apiBaseUrl"))...)
+
+	// The provisioner's own token is only optional when solvers supply the credentials
+	if p.APITokenSecretRef != (digicloudv1alpha1.SecretKeySelector{}) || len(p.Solvers) == 0 {
+		errs = append(errs, issuerspec.ValidateSecretRef(p.APITokenSecretRef.Name, p.APITokenSecretRef.Key, path.Child("apiTokenSecretRef"))...)
+	}
+
+	errs = append(errs, issuerspec.ValidateTTL(p.TTL, path.Child("ttl"))...)
+
+	errs = append(errs, issuerspec.ValidatePropagation(p.PropagationTimeout, p.PollingInterval,
+		path.Child("propagationTimeout"), path.Child("pollingInterval"))...)
+
+	switch p.CNAMEStrategy {
+	case "", digicloudv1alpha1.NoneStrategy, digicloudv1alpha1.FollowStrategy:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("cnameStrategy"), p.CNAMEStrategy,
+			[]string{string(digicloudv1alpha1.NoneStrategy), string(digicloudv1alpha1.FollowStrategy)}))
+	}
+
+	if p.ACME != nil {
+		acmePath := path.Child("acme")
+		if p.ACME.Server != "" {
+			errs = append(errs, issuerspec.ValidateURL(p.ACME.Server, acmePath.Child("server"))...)
+		}
+		if p.ACME.PrivateKeySecretRef != nil {
+			errs = append(errs, issuerspec.ValidateSecretRef(p.ACME.PrivateKeySecretRef.Name, p.ACME.PrivateKeySecretRef.Key, acmePath.Child("privateKeySecretRef"))...)
+		}
+	}
+
+	errs = append(errs, issuerspec.ValidateAllowedDomains(p.AllowedDomains, path.Child("allowedDomains"))...)
+
+	for i, solver := range p.Solvers {
+		solverPath := path.Child("solvers").Index(i)
+		if solver.APIBaseURL != "" {
+			errs = append(errs, issuerspec.ValidateURL(solver.APIBaseURL, solverPath.Child("apiBaseUrl"))...)
+		}
+		errs = append(errs, issuerspec.ValidateSecretRef(solver.APITokenSecretRef.Name, solver.APITokenSecretRef.Key, solverPath.Child("apiTokenSecretRef"))...)
+		errs = append(errs, issuerspec.ValidateTTL(solver.TTL, solverPath.Child("ttl"))...)
+	}
+
+	return errs
+}
@@ -0,0 +1,303 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	digicloudv1alpha1 "github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+func validProvisioner() digicloudv1alpha1.DigicloudIssuerProvisioner {
+	return digicloudv1alpha1.DigicloudIssuerProvisioner{
+		APIBaseURL: "https://api.digicloud.ir",
+		APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{
+			Name: "digicloud-credentials",
+			Key:  "api-token",
+		},
+	}
+}
+
+func TestDigicloudIssuerCustomDefaulter_Default(t *testing.T) {
+	issuer := &digicloudv1alpha1.DigicloudIssuer{
+		Spec: digicloudv1alpha1.DigicloudIssuerSpec{
+			Provisioner: digicloudv1alpha1.DigicloudIssuerProvisioner{
+				APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{Name: "digicloud-credentials", Key: "api-token"},
+				ACME:              &digicloudv1alpha1.DigicloudIssuerACME{},
+				Solvers: []digicloudv1alpha1.DigicloudSolver{
+					{APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{Name: "other", Key: "api-token"}},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, (&DigicloudIssuerCustomDefaulter{}).Default(context.Background(), issuer))
+
+	p := issuer.Spec.Provisioner
+	assert.Equal(t, "https://api.digicloud.ir", p.APIBaseURL)
+	require.NotNil(t, p.TTL)
+	assert.Equal(t, 300, *p.TTL)
+	assert.Equal(t, 5*time.Minute, p.PropagationTimeout.Duration)
+	assert.Equal(t, 10*time.Second, p.PollingInterval.Duration)
+	assert.Equal(t, digicloudv1alpha1.NoneStrategy, p.CNAMEStrategy)
+	assert.Equal(t, "https://acme-v02.api.letsencrypt.org/directory", p.ACME.Server)
+	assert.Equal(t, "https://api.digicloud.ir", p.Solvers[0].APIBaseURL)
+}
+
+func TestDigicloudIssuerCustomDefaulter_KeepsValues(t *testing.T) {
+	ttl := 120
+	issuer := &digicloudv1alpha1.DigicloudIssuer{
+		Spec: digicloudv1alpha1.DigicloudIssuerSpec{
+			Provisioner: digicloudv1alpha1.DigicloudIssuerProvisioner{
+				APIBaseURL:         "https://dns.example.com",
+				TTL:                &ttl,
+				PropagationTimeout: &metav1.Duration{Duration: time.Minute},
+				PollingInterval:    &metav1.Duration{Duration: 5 * time.Second},
+				CNAMEStrategy:      digicloudv1alpha1.FollowStrategy,
+			},
+		},
+	}
+
+	require.NoError(t, (&DigicloudIssuerCustomDefaulter{}).Default(context.Background(), issuer))
+
+	p := issuer.Spec.Provisioner
+	assert.Equal(t, "https://dns.example.com", p.APIBaseURL)
+	assert.Equal(t, 120, *p.TTL)
+	assert.Equal(t, time.Minute, p.PropagationTimeout.Duration)
+	assert.Equal(t, 5*time.Second, p.PollingInterval.Duration)
+	assert.Equal(t, digicloudv1alpha1.FollowStrategy, p.CNAMEStrategy)
+	assert.Nil(t, p.ACME)
+}
+
+func TestValidateProvisioner(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name     string
+		mutate   func(p *digicloudv1alpha1.DigicloudIssuerProvisioner)
+		expected []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {},
+		},
+		{
+			name: "malformed API URL",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.APIBaseURL = "api.digicloud.ir"
+			},
+			expected: []string{"spec.provisioner.apiBaseUrl"},
+		},
+		{
+			name: "API URL without host",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.APIBaseURL = "https://"
+			},
+			expected: []string{"spec.provisioner.apiBaseUrl"},
+		},
+		{
+			name: "TTL too low",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.TTL = intPtr(30)
+			},
+			expected: []string{"spec.provisioner.ttl"},
+		},
+		{
+			name: "TTL too high",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.TTL = intPtr(86401)
+			},
+			expected: []string{"spec.provisioner.ttl"},
+		},
+		{
+			name: "polling interval not less than propagation timeout",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.PropagationTimeout = &metav1.Duration{Duration: 10 * time.Second}
+				p.PollingInterval = &metav1.Duration{Duration: 10 * time.Second}
+			},
+			expected: []string{"spec.provisioner.pollingInterval"},
+		},
+		{
+			name: "negative propagation timeout",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.PropagationTimeout = &metav1.Duration{Duration: -time.Second}
+			},
+			expected: []string{"spec.provisioner.propagationTimeout"},
+		},
+		{
+			name: "empty secret ref",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.APITokenSecretRef = digicloudv1alpha1.SecretKeySelector{}
+			},
+			expected: []string{"spec.provisioner.apiTokenSecretRef.name", "spec.provisioner.apiTokenSecretRef.key"},
+		},
+		{
+			name: "empty secret key",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.APITokenSecretRef.Key = ""
+			},
+			expected: []string{"spec.provisioner.apiTokenSecretRef.key"},
+		},
+		{
+			name: "solvers supply the credentials",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.APITokenSecretRef = digicloudv1alpha1.SecretKeySelector{}
+				p.Solvers = []digicloudv1alpha1.DigicloudSolver{
+					{APITokenSecretRef: digicloudv1alpha1.SecretKeySelector{Name: "other", Key: "api-token"}},
+				}
+			},
+		},
+		{
+			name: "invalid solver",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.Solvers = []digicloudv1alpha1.DigicloudSolver{
+					{APIBaseURL: "ftp://dns.example.com", TTL: intPtr(10)},
+				}
+			},
+			expected: []string{
+				"spec.provisioner.solvers[0].apiBaseUrl",
+				"spec.provisioner.solvers[0].apiTokenSecretRef.name",
+				"spec.provisioner.solvers[0].apiTokenSecretRef.key",
+				"spec.provisioner.solvers[0].ttl",
+			},
+		},
+		{
+			name: "invalid ACME settings",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.ACME = &digicloudv1alpha1.DigicloudIssuerACME{
+					Server:              "not a url",
+					PrivateKeySecretRef: &digicloudv1alpha1.SecretKeySelector{Name: "acme-key"},
+				}
+			},
+			expected: []string{"spec.provisioner.acme.server", "spec.provisioner.acme.privateKeySecretRef.key"},
+		},
+		{
+			name: "valid allowed domains",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.AllowedDomains = []string{"example.com", "**.example.com", `/(www|api)\.example\.org/`}
+			},
+		},
+		{
+			name: "invalid allowed domains",
+			mutate: func(p *digicloudv1alpha1.DigicloudIssuerProvisioner) {
+				p.AllowedDomains = []string{"example.com", "/(example/", ""}
+			},
+			expected: []string{"spec.provisioner.allowedDomains[1]", "spec.provisioner.allowedDomains[2]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := &digicloudv1alpha1.DigicloudIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud", Namespace: "default"},
+				Spec:       digicloudv1alpha1.DigicloudIssuerSpec{Provisioner: validProvisioner()},
+			}
+			tt.mutate(&issuer.Spec.Provisioner)
+
+			_, err := (&DigicloudIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.True(t, apierrors.IsInvalid(err))
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestDigicloudClusterIssuerCustomValidator_NamespaceSelector(t *testing.T) {
+	issuer := &digicloudv1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud"},
+		Spec: digicloudv1alpha1.DigicloudClusterIssuerSpec{
+			Provisioner: validProvisioner(),
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Equals", Values: []string{"platform"}},
+				},
+			},
+		},
+	}
+
+	_, err := (&DigicloudClusterIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "spec.namespaceSelector")
+
+	issuer.Spec.NamespaceSelector.MatchExpressions[0].Operator = metav1.LabelSelectorOpIn
+	_, err = (&DigicloudClusterIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+	assert.NoError(t, err)
+}
+
+func TestDigicloudIssuerCustomValidator_ValidateUpdate(t *testing.T) {
+	// An issuer created before the webhooks were installed, with a TTL they reject
+	provisioner := validProvisioner()
+	ttl := 30
+	provisioner.TTL = &ttl
+	issuer := &digicloudv1alpha1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud", Namespace: "default"},
+		Spec:       digicloudv1alpha1.DigicloudIssuerSpec{Provisioner: provisioner},
+	}
+	validator := &DigicloudIssuerCustomValidator{}
+
+	// Metadata-only updates, such as adding the finalizer, are admitted
+	withFinalizer := issuer.DeepCopy()
+	withFinalizer.Finalizers = []string{"digicloud.issuer.vamirreza.github.io/finalizer"}
+	require.NoError(t, (&DigicloudIssuerCustomDefaulter{}).Default(context.Background(), withFinalizer))
+	_, err := validator.ValidateUpdate(context.Background(), issuer, withFinalizer)
+	assert.NoError(t, err)
+
+	// Spec changes are validated
+	changed := withFinalizer.DeepCopy()
+	changed.Spec.Provisioner.APIBaseURL = "https://api.example.com"
+	_, err = validator.ValidateUpdate(context.Background(), withFinalizer, changed)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "spec.provisioner.ttl")
+
+	// Updates of an issuer being deleted, such as removing the finalizer, are admitted
+	deleting := changed.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted := deleting.DeepCopy()
+	deleted.Finalizers = nil
+	_, err = validator.ValidateUpdate(context.Background(), deleting, deleted)
+	assert.NoError(t, err)
+}
+
+func TestDigicloudClusterIssuerCustomValidator_ValidateUpdate(t *testing.T) {
+	provisioner := validProvisioner()
+	ttl := 30
+	provisioner.TTL = &ttl
+	issuer := &digicloudv1alpha1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud"},
+		Spec:       digicloudv1alpha1.DigicloudClusterIssuerSpec{Provisioner: provisioner},
+	}
+	validator := &DigicloudClusterIssuerCustomValidator{}
+
+	withFinalizer := issuer.DeepCopy()
+	withFinalizer.Finalizers = []string{"digicloud.issuer.vamirreza.github.io/finalizer"}
+	require.NoError(t, (&DigicloudClusterIssuerCustomDefaulter{}).Default(context.Background(), withFinalizer))
+	_, err := validator.ValidateUpdate(context.Background(), issuer, withFinalizer)
+	assert.NoError(t, err)
+
+	changed := withFinalizer.DeepCopy()
+	changed.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}}
+	_, err = validator.ValidateUpdate(context.Background(), withFinalizer, changed)
+	assert.True(t, apierrors.IsInvalid(err))
+
+	deleting := changed.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted := deleting.DeepCopy()
+	deleted.Finalizers = nil
+	_, err = validator.ValidateUpdate(context.Background(), deleting, deleted)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	digicloudv1beta1 "github.com/vamirreza/digicloud-issuer/api/v1beta1"
+	"github.com/vamirreza/digicloud-issuer/internal/webhook/issuerspec"
+)
+
+var digicloudissuerlog = logf.Log.WithName("digicloudissuer-resource")
+
+// SetupDigicloudIssuerWebhookWithManager registers the webhooks for DigicloudIssuer in the manager
+func SetupDigicloudIssuerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&digicloudv1beta1.DigicloudIssuer{}).
+		WithDefaulter(&DigicloudIssuerCustomDefaulter{}).
+		WithValidator(&DigicloudIssuerCustomValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-digicloud-issuer-vamirreza-github-io-v1beta1-digicloudissuer,mutating=true,failurePolicy=fail,sideEffects=None,groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers,verbs=create;update,versions=v1beta1,name=mdigicloudissuer-v1beta1.kb.io,admissionReviewVersions=v1
+
+// DigicloudIssuerCustomDefaulter sets default values on DigicloudIssuer resources
+type DigicloudIssuerCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &DigicloudIssuerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *DigicloudIssuerCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	issuer, ok := obj.(*digicloudv1beta1.DigicloudIssuer)
+	if !ok {
+		return fmt.Errorf("expected a DigicloudIssuer object but got %T", obj)
+	}
+	digicloudissuerlog.V(1).Info("Defaulting", "name", issuer.GetName(), "namespace", issuer.GetNamespace())
+
+	defaultSpec(&issuer.Spec)
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-digicloud-issuer-vamirreza-github-io-v1beta1-digicloudissuer,mutating=false,failurePolicy=fail,sideEffects=None,groups=digicloud.issuer.vamirreza.github.io,resources=digicloudissuers,verbs=create;update,versions=v1beta1,name=vdigicloudissuer-v1beta1.kb.io,admissionReviewVersions=v1
+
+// DigicloudIssuerCustomValidator validates DigicloudIssuer resources on create and update
+type DigicloudIssuerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &DigicloudIssuerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *DigicloudIssuerCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	issuer, ok := obj.(*digicloudv1beta1.DigicloudIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudIssuer object but got %T", obj)
+	}
+	return nil, validateDigicloudIssuer(issuer)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *DigicloudIssuerCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	issuer, ok := newObj.(*digicloudv1beta1.DigicloudIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudIssuer object but got %T", newObj)
+	}
+	oldIssuer, ok := oldObj.(*digicloudv1beta1.DigicloudIssuer)
+	if !ok {
+		return nil, fmt.Errorf("expected a DigicloudIssuer object but got %T", oldObj)
+	}
+
+	oldSpec := oldIssuer.Spec.DeepCopy()
+	defaultSpec(oldSpec)
+	if issuerspec.SkipUpdateValidation(issuer, *oldSpec, issuer.Spec) {
+		return nil, nil
+	}
+	return nil, validateDigicloudIssuer(issuer)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *DigicloudIssuerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateDigicloudIssuer(issuer *digicloudv1beta1.DigicloudIssuer) error {
+	errs := validateSpec(&issuer.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(digicloudv1beta1.GroupVersion.WithKind("DigicloudIssuer").GroupKind(), issuer.Name, errs)
+}
@@ -0,0 +1,101 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	digicloudv1beta1 "github.com/vamirreza/digicloud-issuer/api/v1beta1"
+	"github.com/vamirreza/digicloud-issuer/internal/acme"
+	"github.com/vamirreza/digicloud-issuer/internal/webhook/issuerspec"
+)
+
+// defaultSpec sets the defaults of unset spec fields, as the v1alpha1 webhooks do
+// for the provisioner
+func defaultSpec(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+	apiBaseURL := issuerspec.DefaultAPIBaseURL
+	if account := spec.Digicloud; account != nil {
+		if account.APIBaseURL == "" {
+			account.APIBaseURL = issuerspec.DefaultAPIBaseURL
+		}
+		if account.TTL == nil {
+			ttl := issuerspec.DefaultTTL
+			account.TTL = &ttl
+		}
+		apiBaseURL = account.APIBaseURL
+	}
+	if spec.Propagation == nil {
+		spec.Propagation = &digicloudv1beta1.PropagationCheck{}
+	}
+	if spec.Propagation.Timeout == nil {
+		spec.Propagation.Timeout = &metav1.Duration{Duration: issuerspec.DefaultPropagationTimeout}
+	}
+	if spec.Propagation.PollingInterval == nil {
+		spec.Propagation.PollingInterval = &metav1.Duration{Duration: issuerspec.DefaultPollingInterval}
+	}
+	if spec.CNAMEStrategy == "" {
+		spec.CNAMEStrategy = digicloudv1beta1.NoneStrategy
+	}
+	if spec.ACME != nil && spec.ACME.Server == "" {
+		spec.ACME.Server = acme.DefaultServer
+	}
+	for i := range spec.Solvers {
+		if spec.Solvers[i].Digicloud.APIBaseURL == "" {
+			spec.Solvers[i].Digicloud.APIBaseURL = apiBaseURL
+		}
+	}
+}
+
+// validateSpec validates an issuer spec at path
+func validateSpec(spec *digicloudv1beta1.DigicloudIssuerSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	// The issuer's own account is only optional when solvers supply the credentials
+	switch {
+	case spec.Digicloud != nil:
+		errs = append(errs, validateAccount(spec.Digicloud, path.Child("digicloud"))...)
+	case len(spec.Solvers) == 0:
+		errs = append(errs, field.Required(path.Child("digicloud"), "required unless solvers are configured"))
+	}
+
+	if p := spec.Propagation; p != nil {
+		propagationPath := path.Child("propagation")
+		errs = append(errs, issuerspec.ValidatePropagation(p.Timeout, p.PollingInterval,
+			propagationPath.Child("timeout"), propagationPath.Child("pollingInterval"))...)
+	}
+
+	switch spec.CNAMEStrategy {
+	case "", digicloudv1beta1.NoneStrategy, digicloudv1beta1.FollowStrategy:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("cnameStrategy"), spec.CNAMEStrategy,
+			[]string{string(digicloudv1beta1.NoneStrategy), string(digicloudv1beta1.FollowStrategy)}))
+	}
+
+	if spec.ACME != nil {
+		acmePath := path.Child("acme")
+		if spec.ACME.Server != "" {
+			errs = append(errs, issuerspec.ValidateURL(spec.ACME.Server, acmePath.Child("server"))...)
+		}
+		if spec.ACME.PrivateKeySecretRef != nil {
+			errs = append(errs, issuerspec.ValidateSecretRef(spec.ACME.PrivateKeySecretRef.Name, spec.ACME.PrivateKeySecretRef.Key, acmePath.Child("privateKeySecretRef"))...)
+		}
+	}
+
+	errs = append(errs, issuerspec.ValidateAllowedDomains(spec.AllowedDomains, path.Child("allowedDomains"))...)
+
+	for i := range spec.Solvers {
+		errs = append(errs, validateAccount(&spec.Solvers[i].Digicloud, path.Child("solvers").Index(i).Child("digicloud"))...)
+	}
+
+	return errs
+}
+
+// validateAccount validates a Digicloud account at path
+func validateAccount(account *digicloudv1beta1.DigicloudAccount, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if account.APIBaseURL != "" {
+		errs = append(errs, issuerspec.ValidateURL(account.APIBaseURL, path.Child("apiBaseUrl"))...)
+	}
+	errs = append(errs, issuerspec.ValidateSecretRef(account.APITokenSecretRef.Name, account.APITokenSecretRef.Key, path.Child("apiTokenSecretRef"))...)
+	errs = append(errs, issuerspec.ValidateTTL(account.TTL, path.Child("ttl"))...)
+	return errs
+}
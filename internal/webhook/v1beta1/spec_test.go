@@ -0,0 +1,301 @@
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	digicloudv1beta1 "github.com/vamirreza/digicloud-issuer/api/v1beta1"
+)
+
+func validSpec() digicloudv1beta1.DigicloudIssuerSpec {
+	return digicloudv1beta1.DigicloudIssuerSpec{
+		Digicloud: &digicloudv1beta1.DigicloudAccount{
+			APIBaseURL:        "https://api.digicloud.ir",
+			APITokenSecretRef: digicloudv1beta1.SecretKeySelector{Name: "digicloud-credentials", Key: "api-token"},
+		},
+	}
+}
+
+func TestDigicloudIssuerCustomDefaulter_Default(t *testing.T) {
+	issuer := &digicloudv1beta1.DigicloudIssuer{
+		Spec: digicloudv1beta1.DigicloudIssuerSpec{
+			Digicloud: &digicloudv1beta1.DigicloudAccount{
+				APITokenSecretRef: digicloudv1beta1.SecretKeySelector{Name: "digicloud-credentials", Key: "api-token"},
+			},
+			ACME: &digicloudv1beta1.ACMEIssuer{},
+			Solvers: []digicloudv1beta1.DigicloudSolver{{
+				Digicloud: digicloudv1beta1.DigicloudAccount{
+					APITokenSecretRef: digicloudv1beta1.SecretKeySelector{Name: "other", Key: "api-token"},
+				},
+			}},
+		},
+	}
+
+	require.NoError(t, (&DigicloudIssuerCustomDefaulter{}).Default(context.Background(), issuer))
+
+	spec := issuer.Spec
+	assert.Equal(t, "https://api.digicloud.ir", spec.Digicloud.APIBaseURL)
+	require.NotNil(t, spec.Digicloud.TTL)
+	assert.Equal(t, 300, *spec.Digicloud.TTL)
+	require.NotNil(t, spec.Propagation)
+	assert.Equal(t, 5*time.Minute, spec.Propagation.Timeout.Duration)
+	assert.Equal(t, 10*time.Second, spec.Propagation.PollingInterval.Duration)
+	assert.Equal(t, digicloudv1beta1.NoneStrategy, spec.CNAMEStrategy)
+	assert.Equal(t, "https://acme-v02.api.letsencrypt.org/directory", spec.ACME.Server)
+	assert.Equal(t, "https://api.digicloud.ir", spec.Solvers[0].Digicloud.APIBaseURL)
+	assert.Nil(t, spec.Solvers[0].Digicloud.TTL)
+}
+
+func TestDigicloudClusterIssuerCustomDefaulter_SolversOnly(t *testing.T) {
+	issuer := &digicloudv1beta1.DigicloudClusterIssuer{
+		Spec: digicloudv1beta1.DigicloudClusterIssuerSpec{
+			DigicloudIssuerSpec: digicloudv1beta1.DigicloudIssuerSpec{
+				Solvers: []digicloudv1beta1.DigicloudSolver{{
+					Digicloud: digicloudv1beta1.DigicloudAccount{
+						APIBaseURL:        "https://dns.example.com",
+						APITokenSecretRef: digicloudv1beta1.SecretKeySelector{Name: "other", Key: "api-token"},
+					},
+				}},
+			},
+		},
+	}
+
+	require.NoError(t, (&DigicloudClusterIssuerCustomDefaulter{}).Default(context.Background(), issuer))
+
+	// No account of the issuer's own is made up, which would fail validation
+	assert.Nil(t, issuer.Spec.Digicloud)
+	assert.Equal(t, "https://dns.example.com", issuer.Spec.Solvers[0].Digicloud.APIBaseURL)
+	_, err := (&DigicloudClusterIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+	assert.NoError(t, err)
+}
+
+func TestValidateSpec(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	tests := []struct {
+		name     string
+		mutate   func(spec *digicloudv1beta1.DigicloudIssuerSpec)
+		expected []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {},
+		},
+		{
+			name: "malformed API URL",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Digicloud.APIBaseURL = "api.digicloud.ir"
+			},
+			expected: []string{"spec.digicloud.apiBaseUrl"},
+		},
+		{
+			name: "TTL too low",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Digicloud.TTL = intPtr(30)
+			},
+			expected: []string{"spec.digicloud.ttl"},
+		},
+		{
+			name: "empty secret ref",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Digicloud.APITokenSecretRef = digicloudv1beta1.SecretKeySelector{}
+			},
+			expected: []string{"spec.digicloud.apiTokenSecretRef.name", "spec.digicloud.apiTokenSecretRef.key"},
+		},
+		{
+			name: "no account",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Digicloud = nil
+			},
+			expected: []string{"spec.digicloud"},
+		},
+		{
+			name: "solvers supply the credentials",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Digicloud = nil
+				spec.Solvers = []digicloudv1beta1.DigicloudSolver{{
+					Digicloud: digicloudv1beta1.DigicloudAccount{
+						APITokenSecretRef: digicloudv1beta1.SecretKeySelector{Name: "other", Key: "api-token"},
+					},
+				}}
+			},
+		},
+		{
+			name: "invalid solver",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Solvers = []digicloudv1beta1.DigicloudSolver{{
+					Digicloud: digicloudv1beta1.DigicloudAccount{APIBaseURL: "ftp://dns.example.com", TTL: intPtr(10)},
+				}}
+			},
+			expected: []string{
+				"spec.solvers[0].digicloud.apiBaseUrl",
+				"spec.solvers[0].digicloud.apiTokenSecretRef.name",
+				"spec.solvers[0].digicloud.apiTokenSecretRef.key",
+				"spec.solvers[0].digicloud.ttl",
+			},
+		},
+		{
+			name: "polling interval not less than propagation timeout",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Propagation = &digicloudv1beta1.PropagationCheck{
+					Timeout:         &metav1.Duration{Duration: 10 * time.Second},
+					PollingInterval: &metav1.Duration{Duration: 10 * time.Second},
+				}
+			},
+			expected: []string{"spec.propagation.pollingInterval"},
+		},
+		{
+			name: "negative propagation timeout",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.Propagation = &digicloudv1beta1.PropagationCheck{Timeout: &metav1.Duration{Duration: -time.Second}}
+			},
+			expected: []string{"spec.propagation.timeout"},
+		},
+		{
+			name: "unknown CNAME strategy",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.CNAMEStrategy = "Always"
+			},
+			expected: []string{"spec.cnameStrategy"},
+		},
+		{
+			name: "invalid ACME settings",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.ACME = &digicloudv1beta1.ACMEIssuer{
+					Server:              "not a url",
+					PrivateKeySecretRef: &digicloudv1beta1.SecretKeySelector{Name: "acme-key"},
+				}
+			},
+			expected: []string{"spec.acme.server", "spec.acme.privateKeySecretRef.key"},
+		},
+		{
+			name: "valid allowed domains",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.AllowedDomains = []string{"example.com", "**.example.com", `/(www|api)\.example\.org/`}
+			},
+		},
+		{
+			name: "invalid allowed domains",
+			mutate: func(spec *digicloudv1beta1.DigicloudIssuerSpec) {
+				spec.AllowedDomains = []string{"example.com", "/(example/", ""}
+			},
+			expected: []string{"spec.allowedDomains[1]", "spec.allowedDomains[2]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := &digicloudv1beta1.DigicloudIssuer{
+				ObjectMeta: metav1.ObjectMeta{Name: "digicloud", Namespace: "default"},
+				Spec:       validSpec(),
+			}
+			tt.mutate(&issuer.Spec)
+
+			_, err := (&DigicloudIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.True(t, apierrors.IsInvalid(err))
+			var fields []string
+			for _, cause := range err.(*apierrors.StatusError).ErrStatus.Details.Causes {
+				fields = append(fields, cause.Field)
+			}
+			assert.Equal(t, tt.expected, fields)
+		})
+	}
+}
+
+func TestDigicloudClusterIssuerCustomValidator_NamespaceSelector(t *testing.T) {
+	issuer := &digicloudv1beta1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud"},
+		Spec: digicloudv1beta1.DigicloudClusterIssuerSpec{
+			DigicloudIssuerSpec: validSpec(),
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Equals", Values: []string{"platform"}},
+				},
+			},
+		},
+	}
+
+	_, err := (&DigicloudClusterIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "spec.namespaceSelector")
+
+	issuer.Spec.NamespaceSelector.MatchExpressions[0].Operator = metav1.LabelSelectorOpIn
+	_, err = (&DigicloudClusterIssuerCustomValidator{}).ValidateCreate(context.Background(), issuer)
+	assert.NoError(t, err)
+}
+
+func TestDigicloudIssuerCustomValidator_ValidateUpdate(t *testing.T) {
+	// An issuer created before the webhooks were installed, with a TTL they reject
+	spec := validSpec()
+	ttl := 30
+	spec.Digicloud.TTL = &ttl
+	issuer := &digicloudv1beta1.DigicloudIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud", Namespace: "default"},
+		Spec:       spec,
+	}
+	validator := &DigicloudIssuerCustomValidator{}
+
+	// Metadata-only updates, such as adding the finalizer, are admitted
+	withFinalizer := issuer.DeepCopy()
+	withFinalizer.Finalizers = []string{"digicloud.issuer.vamirreza.github.io/finalizer"}
+	require.NoError(t, (&DigicloudIssuerCustomDefaulter{}).Default(context.Background(), withFinalizer))
+	_, err := validator.ValidateUpdate(context.Background(), issuer, withFinalizer)
+	assert.NoError(t, err)
+
+	// Spec changes are validated
+	changed := withFinalizer.DeepCopy()
+	changed.Spec.Digicloud.Namespace = "media"
+	_, err = validator.ValidateUpdate(context.Background(), withFinalizer, changed)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "spec.digicloud.ttl")
+
+	// Updates of an issuer being deleted, such as removing the finalizer, are admitted
+	deleting := changed.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted := deleting.DeepCopy()
+	deleted.Finalizers = nil
+	_, err = validator.ValidateUpdate(context.Background(), deleting, deleted)
+	assert.NoError(t, err)
+}
+
+func TestDigicloudClusterIssuerCustomValidator_ValidateUpdate(t *testing.T) {
+	spec := validSpec()
+	ttl := 30
+	spec.Digicloud.TTL = &ttl
+	issuer := &digicloudv1beta1.DigicloudClusterIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "digicloud"},
+		Spec:       digicloudv1beta1.DigicloudClusterIssuerSpec{DigicloudIssuerSpec: spec},
+	}
+	validator := &DigicloudClusterIssuerCustomValidator{}
+
+	withFinalizer := issuer.DeepCopy()
+	withFinalizer.Finalizers = []string{"digicloud.issuer.vamirreza.github.io/finalizer"}
+	require.NoError(t, (&DigicloudClusterIssuerCustomDefaulter{}).Default(context.Background(), withFinalizer))
+	_, err := validator.ValidateUpdate(context.Background(), issuer, withFinalizer)
+	assert.NoError(t, err)
+
+	changed := withFinalizer.DeepCopy()
+	changed.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}}
+	_, err = validator.ValidateUpdate(context.Background(), withFinalizer, changed)
+	assert.True(t, apierrors.IsInvalid(err))
+
+	deleting := changed.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted := deleting.DeepCopy()
+	deleted.Finalizers = nil
+	_, err = validator.ValidateUpdate(context.Background(), deleting, deleted)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,90 @@
+// Package conformance runs cert-manager's DNS01 solver conformance fixture against
+// DigicloudProvider, backed by the fake Digicloud API and its nameserver.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	whapi "github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	acmetest "github.com/cert-manager/cert-manager/test/acme"
+	"k8s.io/client-go/rest"
+
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+)
+
+// solverConfig is the solver configuration of a challenge request, as a DNS01
+// webhook solver would receive it from an issuer
+type solverConfig struct {
+	APIBaseURL string `json:"apiBaseUrl"`
+	APIToken   string `json:"apiToken"`
+	Namespace  string `json:"namespace"`
+}
+
+// solver adapts DigicloudProvider to the webhook.Solver interface the fixture
+// drives, presenting and cleaning up the resolved FQDN of each challenge request
+type solver struct{}
+
+func (solver) Name() string {
+	return "digicloud"
+}
+
+func (solver) Present(ch *whapi.ChallengeRequest) error {
+	provider, err := newProvider(ch)
+	if err != nil {
+		return err
+	}
+	return provider.PresentTXTRecord(context.Background(), ch.ResolvedZone, ch.ResolvedFQDN, ch.Key)
+}
+
+func (solver) CleanUp(ch *whapi.ChallengeRequest) error {
+	provider, err := newProvider(ch)
+	if err != nil {
+		return err
+	}
+	return provider.DeleteTXTRecord(context.Background(), ch.ResolvedZone, ch.ResolvedFQDN, ch.Key)
+}
+
+func (solver) Initialize(*rest.Config, <-chan struct{}) error {
+	return nil
+}
+
+// newProvider returns a provider configured from the solver configuration of ch
+func newProvider(ch *whapi.ChallengeRequest) (*dnsprovider.DigicloudProvider, error) {
+	var config solverConfig
+	if ch.Config == nil {
+		return nil, fmt.Errorf("challenge request has no solver configuration")
+	}
+	if err := json.Unmarshal(ch.Config.Raw, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode solver configuration: %w", err)
+	}
+	return dnsprovider.NewDigicloudProvider(config.APIBaseURL, config.APIToken, config.Namespace, 60), nil
+}
+
+func TestConformance(t *testing.T) {
+	api := digicloudtest.NewServer(t)
+	api.AddZone("conformance", "example.com")
+	nameserver := api.ServeDNS(t)
+
+	// The nameservers of the zone are not reachable, records are checked at the
+	// fake nameserver, which is authoritative for the zone
+	fixture := acmetest.NewFixture(solver{},
+		acmetest.SetResolvedZone("example.com."),
+		acmetest.SetResolvedFQDN("_acme-challenge.conformance.example.com."),
+		acmetest.SetDNSServer(nameserver),
+		acmetest.SetUseAuthoritative(false),
+		acmetest.SetStrict(true),
+		acmetest.SetPollInterval(100*time.Millisecond),
+		acmetest.SetPropagationLimit(10*time.Second),
+		acmetest.SetConfig(solverConfig{
+			APIBaseURL: api.URL,
+			APIToken:   digicloudtest.DefaultToken,
+			Namespace:  "conformance",
+		}),
+	)
+	fixture.RunConformance(t)
+}
@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// issueTimeout bounds one issuance, from the CertificateRequest to the Secret
+const issueTimeout = 2 * time.Minute
+
+// issue takes crt through the steps cert-manager's certificates and approver
+// controllers would, which cannot run against envtest: it generates a private key
+// and a CSR, creates an approved CertificateRequest owned by crt, and once the
+// request is signed stores the certificate in the Secret of crt and marks crt
+// Ready. It returns the signed request.
+func issue(crt *cmapi.Certificate) *cmapi.CertificateRequest {
+	GinkgoHelper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: crt.Spec.CommonName},
+		DNSNames: crt.Spec.DNSNames,
+	}, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      crt.Name + "-1",
+			Namespace: crt.Namespace,
+			Annotations: map[string]string{
+				cmapi.CertificateNameKey:                      crt.Name,
+				cmapi.CertificateRequestRevisionAnnotationKey: "1",
+			},
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			Request:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+			IssuerRef: crt.Spec.IssuerRef,
+			Usages:    []cmapi.KeyUsage{cmapi.UsageDigitalSignature, cmapi.UsageServerAuth},
+		},
+	}
+	Expect(controllerutil.SetControllerReference(crt, cr, k8sClient.Scheme())).To(Succeed())
+	Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+
+	By("approving the CertificateRequest")
+	apiutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionApproved, cmmeta.ConditionTrue,
+		"cert-manager.io", "Certificate request has been approved by cert-manager.io")
+	Expect(k8sClient.Status().Update(ctx, cr)).To(Succeed())
+
+	By("waiting for the CertificateRequest to be signed")
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cr), cr)).To(Succeed())
+		g.Expect(cr.Status.FailureTime).To(BeNil(), "request failed: %s", readyMessage(cr))
+		g.Expect(apiutil.CertificateRequestReadyReason(cr)).To(Equal(cmapi.CertificateRequestReasonIssued), readyMessage(cr))
+	}, issueTimeout, time.Second).Should(Succeed())
+
+	By("storing the certificate in the Secret")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        crt.Spec.SecretName,
+			Namespace:   crt.Namespace,
+			Annotations: map[string]string{cmapi.CertificateNameKey: crt.Name},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cr.Status.Certificate,
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+			cmmeta.TLSCAKey:         cr.Status.CA,
+		},
+	}
+	Expect(controllerutil.SetOwnerReference(crt, secret, k8sClient.Scheme())).To(Succeed())
+	Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+	revision := 1
+	crt.Status.Revision = &revision
+	apiutil.SetCertificateCondition(crt, crt.Generation, cmapi.CertificateConditionReady, cmmeta.ConditionTrue,
+		"Ready", "Certificate is up to date and has not expired")
+	Expect(k8sClient.Status().Update(ctx, crt)).To(Succeed())
+
+	return cr
+}
+
+// readyMessage returns the message of the Ready condition of cr
+func readyMessage(cr *cmapi.CertificateRequest) string {
+	if condition := apiutil.GetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionReady); condition != nil {
+		return condition.Message
+	}
+	return ""
+}
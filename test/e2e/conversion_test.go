@@ -0,0 +1,83 @@
+package e2e
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+)
+
+var _ = Describe("Conversion", func() {
+	It("Should sign with the Digicloud namespace of a v1beta1 issuer", func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		// The secret names a Digicloud namespace without zones, so that the issuer
+		// only becomes ready and signs if spec.digicloud.namespace is honoured
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "digicloud-credentials", Namespace: ns.Name},
+			Data: map[string][]byte{
+				"token":     []byte(digicloudtest.DefaultToken),
+				"namespace": []byte("unused"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		issuer := &v1beta1.DigicloudIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "e2e", Namespace: ns.Name},
+			Spec: v1beta1.DigicloudIssuerSpec{
+				Digicloud: &v1beta1.DigicloudAccount{
+					APIBaseURL:        api.URL,
+					APITokenSecretRef: v1beta1.SecretKeySelector{Name: secret.Name, Key: "token"},
+					Namespace:         digicloudNamespace,
+				},
+				ACME: &v1beta1.ACMEIssuer{Server: acme.DirectoryURL, Email: "e2e@example.com", TermsOfServiceAgreed: true},
+				Propagation: &v1beta1.PropagationCheck{
+					Timeout:         &metav1.Duration{Duration: 30 * time.Second},
+					PollingInterval: &metav1.Duration{Duration: 500 * time.Millisecond},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, issuer)).To(Succeed())
+
+		By("reading the issuer as v1alpha1")
+		spoke := &v1alpha1.DigicloudIssuer{}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), spoke)).To(Succeed())
+			g.Expect(spoke.Spec.Provisioner.Namespace).To(Equal(digicloudNamespace))
+			g.Expect(spoke.GetConditions()).To(ContainElement(And(
+				HaveField("Type", cmapi.IssuerConditionReady),
+				HaveField("Status", cmmeta.ConditionTrue),
+			)))
+		}).Should(Succeed())
+
+		crt := &cmapi.Certificate{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-", Namespace: ns.Name},
+			Spec: cmapi.CertificateSpec{
+				DNSNames:   []string{"conversion.example.com"},
+				SecretName: "e2e-tls",
+				IssuerRef: cmmeta.ObjectReference{
+					Name:  issuer.Name,
+					Kind:  "DigicloudIssuer",
+					Group: v1alpha1.GroupVersion.Group,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, crt)).To(Succeed())
+		issue(crt)
+
+		tls := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: crt.Spec.SecretName}, tls)).To(Succeed())
+		expectCertificate(tls, crt.Spec.DNSNames)
+	})
+})
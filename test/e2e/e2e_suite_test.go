@@ -0,0 +1,229 @@
+package e2e
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+	webhookv1alpha1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1alpha1"
+	webhookv1beta1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1beta1"
+)
+
+const (
+	// clusterResourceNamespace is the namespace secrets referenced by cluster issuers
+	// are read from
+	clusterResourceNamespace = "digicloud-issuer"
+
+	// digicloudNamespace is the Digicloud namespace holding zone
+	digicloudNamespace = "e2e"
+
+	// zone is the Digicloud zone certificates are issued for
+	zone = "example.com"
+)
+
+var cfg *rest.Config
+var k8sClient client.Client
+var testEnv *envtest.Environment
+var ctx context.Context
+var cancel context.CancelFunc
+
+// api is the fake Digicloud API challenge records are written to, and nameserver
+// the address of the nameserver serving its zones
+var api *digicloudtest.Server
+var nameserver string
+
+// acme is the ACME server certificates are ordered from
+var acme *acmeServer
+
+func TestE2E(t *testing.T) {
+	api = digicloudtest.NewServer(t)
+	nameserver = api.ServeDNS(t)
+	api.AddZone(digicloudNamespace, zone)
+	acme = startPebble(t, nameserver)
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "E2E Test Suite")
+}
+
+var _ = BeforeSuite(func() {
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	ctx, cancel = context.WithCancel(context.TODO())
+
+	crds, err := certManagerCRDs()
+	Expect(err).NotTo(HaveOccurred())
+
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		CRDs:                  crds,
+		ErrorIfCRDPathMissing: true,
+		// The scheme holds both API versions so that envtest serves conversion
+		// from the manager's webhook server
+		Scheme: scheme.Scheme,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
+	}
+
+	err = v1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = v1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = cmapi.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	cfg, err = testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg).NotTo(BeNil())
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(k8sClient).NotTo(BeNil())
+
+	err = k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: clusterResourceNamespace}})
+	Expect(err).NotTo(HaveOccurred())
+
+	// Start the manager with the controllers cmd/main.go runs. The nameservers
+	// of the zone are not reachable, so that challenge propagation is only checked
+	// with the fake nameserver Pebble validates against.
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme.Scheme,
+		Metrics: server.Options{
+			BindAddress: "0",
+		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    testEnv.WebhookInstallOptions.LocalServingHost,
+			Port:    testEnv.WebhookInstallOptions.LocalServingPort,
+			CertDir: testEnv.WebhookInstallOptions.LocalServingCertDir,
+		}),
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controllers.IssuerReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ForObject: &v1alpha1.DigicloudIssuer{},
+		Recorder:  mgr.GetEventRecorderFor("digicloud-issuer"),
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controllers.IssuerReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		ForObject:                &v1alpha1.DigicloudClusterIssuer{},
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controllers.SignerController{
+		Client:                   mgr.GetClient(),
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+		Nameservers:              []string{nameserver},
+		RecursiveNameserversOnly: true,
+	}).SetupWithManager(ctx, mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&controllers.CertificateRequestReconciler{
+		Client:                   mgr.GetClient(),
+		Recorder:                 mgr.GetEventRecorderFor("digicloud-issuer"),
+		ClusterResourceNamespace: clusterResourceNamespace,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1alpha1.SetupDigicloudIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1alpha1.SetupDigicloudClusterIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1beta1.SetupDigicloudIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1beta1.SetupDigicloudClusterIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	go func() {
+		defer GinkgoRecover()
+		err = mgr.Start(ctx)
+		Expect(err).ToNot(HaveOccurred(), "failed to run manager")
+	}()
+
+	// Wait for the webhook server to accept connections before running any specs
+	webhookOpts := testEnv.WebhookInstallOptions
+	dialer := &net.Dialer{Timeout: time.Second}
+	addr := net.JoinHostPort(webhookOpts.LocalServingHost, strconv.Itoa(webhookOpts.LocalServingPort))
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true}) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
+})
+
+var _ = AfterSuite(func() {
+	cancel()
+	By("tearing down the test environment")
+	err := testEnv.Stop()
+	Expect(err).NotTo(HaveOccurred())
+})
+
+// certManagerCRDs returns the Certificate and CertificateRequest CRDs shipped with
+// the cert-manager module. Their labels and annotations are Helm templates, which
+// are dropped.
+func certManagerCRDs() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/cert-manager/cert-manager").Output()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(strings.TrimSpace(string(out)), "deploy", "crds")
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, name := range []string{"crd-certificates.yaml", "crd-certificaterequests.yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal(data, crd); err != nil {
+			return nil, err
+		}
+		crd.Labels, crd.Annotations = nil, nil
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
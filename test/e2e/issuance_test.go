@@ -0,0 +1,203 @@
+package e2e
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiutil "github.com/cert-manager/cert-manager/pkg/api/util"
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	"github.com/vamirreza/digicloud-issuer/internal/dnsprovider/digicloudtest"
+)
+
+var _ = Describe("Issuance", func() {
+	for _, kind := range []string{"DigicloudIssuer", "DigicloudClusterIssuer"} {
+		Context("with a "+kind, func() {
+			var (
+				namespace string
+				issuer    controllers.IssuerObject
+			)
+
+			BeforeEach(func() {
+				ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-"}}
+				Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+				namespace = ns.Name
+				issuer = createIssuer(kind, namespace)
+			})
+
+			DescribeTable("Should issue a certificate",
+				func(dnsNames ...string) {
+					requests := len(api.Requests())
+
+					crt := &cmapi.Certificate{
+						ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-", Namespace: namespace},
+						Spec: cmapi.CertificateSpec{
+							DNSNames:   dnsNames,
+							SecretName: "e2e-tls",
+							IssuerRef: cmmeta.ObjectReference{
+								Name:  issuer.GetName(),
+								Kind:  kind,
+								Group: v1alpha1.GroupVersion.Group,
+							},
+						},
+					}
+					Expect(k8sClient.Create(ctx, crt)).To(Succeed())
+					cr := issue(crt)
+
+					By("checking the certificate in the Secret")
+					secret := &corev1.Secret{}
+					Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: crt.Spec.SecretName}, secret)).To(Succeed())
+					expectCertificate(secret, dnsNames)
+
+					By("checking that a challenge record was presented for every name")
+					var created int
+					for _, request := range api.Requests()[requests:] {
+						if request.Method == http.MethodPost && strings.HasSuffix(request.Path, "/records") {
+							created++
+						}
+					}
+					Expect(created).To(Equal(len(dnsNames)))
+
+					By("checking that the challenge records were cleaned up")
+					Expect(challengeRecords()).To(BeEmpty())
+					Eventually(func(g Gomega) {
+						g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cr), cr)).To(Succeed())
+						g.Expect(controllerutil.ContainsFinalizer(cr, controllers.RequestFinalizer)).To(BeFalse())
+						g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), issuer)).To(Succeed())
+						g.Expect(issuer.GetChallengeRecords()).To(BeEmpty())
+					}).Should(Succeed())
+				},
+				Entry("for a single name", "www.example.com"),
+				Entry("for a wildcard name", "*.example.com"),
+				Entry("for a wildcard name and its parent", "*.example.com", "example.com"),
+				Entry("for multiple names", "example.com", "www.example.com", "api.example.com"),
+			)
+
+			It("Should not sign a denied CertificateRequest", func() {
+				cr := &cmapi.CertificateRequest{
+					ObjectMeta: metav1.ObjectMeta{GenerateName: "e2e-", Namespace: namespace},
+					Spec: cmapi.CertificateRequestSpec{
+						Request: []byte("unused"),
+						IssuerRef: cmmeta.ObjectReference{
+							Name:  issuer.GetName(),
+							Kind:  kind,
+							Group: v1alpha1.GroupVersion.Group,
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+				apiutil.SetCertificateRequestCondition(cr, cmapi.CertificateRequestConditionDenied, cmmeta.ConditionTrue,
+					"e2e", "Denied by the e2e suite")
+				Expect(k8sClient.Status().Update(ctx, cr)).To(Succeed())
+
+				Eventually(func(g Gomega) {
+					g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cr), cr)).To(Succeed())
+					g.Expect(apiutil.CertificateRequestReadyReason(cr)).To(Equal(cmapi.CertificateRequestReasonDenied))
+					g.Expect(cr.Status.FailureTime).NotTo(BeNil())
+				}).Should(Succeed())
+				Expect(cr.Status.Certificate).To(BeEmpty())
+			})
+		})
+	}
+})
+
+// createIssuer creates an issuer of kind that orders certificates from the local
+// ACME server and presents challenges through the fake Digicloud API, and waits for
+// it to become ready. Cluster issuers are named after namespace.
+func createIssuer(kind, namespace string) controllers.IssuerObject {
+	GinkgoHelper()
+
+	provisioner := v1alpha1.DigicloudIssuerProvisioner{
+		APIBaseURL:         api.URL,
+		APITokenSecretRef:  v1alpha1.SecretKeySelector{Name: "digicloud-credentials-" + namespace, Key: "token"},
+		ACME:               &v1alpha1.DigicloudIssuerACME{Server: acme.DirectoryURL, Email: "e2e@example.com", TermsOfServiceAgreed: true},
+		PropagationTimeout: &metav1.Duration{Duration: 30 * time.Second},
+		PollingInterval:    &metav1.Duration{Duration: 500 * time.Millisecond},
+	}
+
+	var issuer controllers.IssuerObject
+	secretNamespace := namespace
+	if kind == "DigicloudClusterIssuer" {
+		issuer = &v1alpha1.DigicloudClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+			Spec:       v1alpha1.DigicloudClusterIssuerSpec{Provisioner: provisioner},
+		}
+		secretNamespace = clusterResourceNamespace
+	} else {
+		issuer = &v1alpha1.DigicloudIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "e2e", Namespace: namespace},
+			Spec:       v1alpha1.DigicloudIssuerSpec{Provisioner: provisioner},
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: provisioner.APITokenSecretRef.Name, Namespace: secretNamespace},
+		Data: map[string][]byte{
+			"token":     []byte(digicloudtest.DefaultToken),
+			"namespace": []byte(digicloudNamespace),
+		},
+	}
+	Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+	Expect(k8sClient.Create(ctx, issuer)).To(Succeed())
+
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), issuer)).To(Succeed())
+		g.Expect(issuer.GetConditions()).To(ContainElement(And(
+			HaveField("Type", cmapi.IssuerConditionReady),
+			HaveField("Status", cmmeta.ConditionTrue),
+		)))
+	}).Should(Succeed())
+	return issuer
+}
+
+// expectCertificate checks that the Secret holds a certificate for dnsNames issued
+// by the local ACME server, together with its private key
+func expectCertificate(secret *corev1.Secret, dnsNames []string) {
+	GinkgoHelper()
+
+	Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+	pair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	Expect(err).NotTo(HaveOccurred(), "certificate does not match the private key")
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	Expect(err).NotTo(HaveOccurred())
+	Expect(leaf.DNSNames).To(ConsistOf(dnsNames))
+
+	intermediates := x509.NewCertPool()
+	for _, der := range pair.Certificate[1:] {
+		cert, err := x509.ParseCertificate(der)
+		Expect(err).NotTo(HaveOccurred())
+		intermediates.AddCert(cert)
+	}
+	intermediates.AppendCertsFromPEM(secret.Data[cmmeta.TLSCAKey])
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         acme.Roots,
+		Intermediates: intermediates,
+		DNSName:       strings.Replace(dnsNames[0], "*", "e2e", 1),
+	})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+// challengeRecords returns the challenge records left in zone
+func challengeRecords() []digicloudtest.Record {
+	var records []digicloudtest.Record
+	for _, record := range api.Records(digicloudNamespace, zone) {
+		if strings.HasPrefix(record.Name, "_acme-challenge") {
+			records = append(records, record)
+		}
+	}
+	return records
+}
@@ -0,0 +1,61 @@
+package e2e
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/letsencrypt/pebble/v2/ca"
+	"github.com/letsencrypt/pebble/v2/db"
+	"github.com/letsencrypt/pebble/v2/va"
+	"github.com/letsencrypt/pebble/v2/wfe"
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// acmeServer is a Pebble ACME server running in the test process
+type acmeServer struct {
+	// DirectoryURL is the URL of the ACME directory
+	DirectoryURL string
+
+	// Roots holds the root certificate Pebble issues certificates under
+	Roots *x509.CertPool
+}
+
+// startPebble starts a Pebble ACME server that validates DNS01 challenges by asking
+// the nameserver at resolver. lego trusts the HTTPS certificate of the server
+// through LEGO_CA_CERTIFICATES. The server is stopped when the test ends.
+func startPebble(t *testing.T, resolver string) *acmeServer {
+	t.Helper()
+
+	// Validate right away, never reject nonces and never reuse authorizations,
+	// so that every order solves its challenges
+	t.Setenv("PEBBLE_VA_NOSLEEP", "1")
+	t.Setenv("PEBBLE_WFE_NONCEREJECT", "0")
+	t.Setenv("PEBBLE_AUTHZREUSE", "0")
+
+	logger := log.New(GinkgoWriter, "pebble ", log.LstdFlags)
+	store := db.NewMemoryStore()
+	authority := ca.New(logger, store, "", "ecdsa", 0, 1, map[string]ca.Profile{
+		"default": {Description: "The default profile"},
+	})
+	validator := va.New(logger, 0, 0, false, resolver, store)
+	frontend := wfe.New(logger, store, validator, authority, []string{"pebble.letsencrypt.org"}, false, false, 1, 1)
+
+	server := httptest.NewTLSServer(frontend.Handler())
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "pebble.crt")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write the HTTPS certificate of Pebble: %v", err)
+	}
+	t.Setenv("LEGO_CA_CERTIFICATES", caFile)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.GetRootCert(0).Cert)
+	return &acmeServer{DirectoryURL: server.URL + wfe.DirectoryPath, Roots: roots}
+}
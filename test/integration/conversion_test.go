@@ -0,0 +1,80 @@
+package integration
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
+)
+
+var _ = Describe("DigicloudIssuer conversion", func() {
+	It("Should serve a v1alpha1 issuer as v1beta1", func() {
+		issuer := &v1alpha1.DigicloudIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "conversion-issuer-",
+				Namespace:    "default",
+			},
+			Spec: v1alpha1.DigicloudIssuerSpec{
+				Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+					APITokenSecretRef: v1alpha1.SecretKeySelector{
+						Name: "api-key-secret",
+						Key:  "api-key",
+					},
+					Solvers: []v1alpha1.DigicloudSolver{{
+						Selector: &v1alpha1.SolverSelector{DNSZones: []string{"example.com"}},
+						APITokenSecretRef: v1alpha1.SecretKeySelector{
+							Name: "example-secret",
+							Key:  "api-key",
+						},
+						Namespace: "example",
+					}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, issuer)).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, issuer)
+
+		converted := &v1beta1.DigicloudIssuer{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), converted)).Should(Succeed())
+		Expect(converted.Spec.Digicloud).NotTo(BeNil())
+		Expect(converted.Spec.Digicloud.APITokenSecretRef.Name).To(Equal("api-key-secret"))
+		Expect(converted.Spec.Digicloud.TTL).To(HaveValue(Equal(300)))
+		Expect(converted.Spec.Solvers).To(HaveLen(1))
+		Expect(converted.Spec.Solvers[0].Digicloud.Namespace).To(Equal("example"))
+	})
+
+	It("Should preserve the Digicloud namespace when read as v1alpha1", func() {
+		issuer := &v1beta1.DigicloudIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "conversion-issuer-",
+				Namespace:    "default",
+			},
+			Spec: v1beta1.DigicloudIssuerSpec{
+				Digicloud: &v1beta1.DigicloudAccount{
+					APITokenSecretRef: v1beta1.SecretKeySelector{
+						Name: "api-key-secret",
+						Key:  "api-key",
+					},
+					Namespace: "media",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, issuer)).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, issuer)
+
+		spoke := &v1alpha1.DigicloudIssuer{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), spoke)).Should(Succeed())
+		Expect(spoke.Spec.Provisioner.Namespace).To(Equal("media"))
+
+		spoke.Spec.Provisioner.AllowedDomains = []string{"*.example.com"}
+		Expect(k8sClient.Update(ctx, spoke)).Should(Succeed())
+
+		updated := &v1beta1.DigicloudIssuer{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), updated)).Should(Succeed())
+		Expect(updated.Spec.Digicloud.Namespace).To(Equal("media"))
+		Expect(updated.Spec.AllowedDomains).To(ConsistOf("*.example.com"))
+	})
+})
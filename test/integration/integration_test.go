@@ -2,7 +2,10 @@ package integration
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -17,12 +20,16 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 
 	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+	"github.com/vamirreza/digicloud-issuer/api/v1beta1"
 	"github.com/vamirreza/digicloud-issuer/internal/controllers"
+	webhookv1alpha1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1alpha1"
+	webhookv1beta1 "github.com/vamirreza/digicloud-issuer/internal/webhook/v1beta1"
 )
 
 var cfg *rest.Config
@@ -45,14 +52,18 @@ var _ = BeforeSuite(func() {
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
+		// The scheme holds both API versions so that envtest serves conversion
+		// from the manager's webhook server
+		Scheme: scheme.Scheme,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
 	}
 
-	var err error
-	cfg, err = testEnv.Start()
+	err := v1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
-	Expect(cfg).NotTo(BeNil())
 
-	err = v1alpha1.AddToScheme(scheme.Scheme)
+	err = v1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
 	err = cmapi.AddToScheme(scheme.Scheme)
@@ -61,6 +72,10 @@ var _ = BeforeSuite(func() {
 	err = cmacme.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	cfg, err = testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg).NotTo(BeNil())
+
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
@@ -71,26 +86,59 @@ var _ = BeforeSuite(func() {
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    testEnv.WebhookInstallOptions.LocalServingHost,
+			Port:    testEnv.WebhookInstallOptions.LocalServingPort,
+			CertDir: testEnv.WebhookInstallOptions.LocalServingCertDir,
+		}),
 	})
 	Expect(err).ToNot(HaveOccurred())
 
-	err = (&controllers.DigicloudIssuerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	err = (&controllers.IssuerReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ForObject: &v1alpha1.DigicloudIssuer{},
+		Recorder:  mgr.GetEventRecorderFor("digicloud-issuer"),
 	}).SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
 
-	err = (&controllers.DigicloudClusterIssuerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	err = (&controllers.IssuerReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		ForObject: &v1alpha1.DigicloudClusterIssuer{},
+		Recorder:  mgr.GetEventRecorderFor("digicloud-issuer"),
 	}).SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = webhookv1alpha1.SetupDigicloudIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1alpha1.SetupDigicloudClusterIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1beta1.SetupDigicloudIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = webhookv1beta1.SetupDigicloudClusterIssuerWebhookWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		defer GinkgoRecover()
 		err = mgr.Start(ctx)
 		Expect(err).ToNot(HaveOccurred(), "failed to run manager")
 	}()
+
+	// Wait for the webhook server to accept connections before running any specs
+	webhookOpts := testEnv.WebhookInstallOptions
+	dialer := &net.Dialer{Timeout: time.Second}
+	addr := net.JoinHostPort(webhookOpts.LocalServingHost, strconv.Itoa(webhookOpts.LocalServingPort))
+	Eventually(func() error {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true}) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}).Should(Succeed())
 })
 
 var _ = AfterSuite(func() {
@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vamirreza/digicloud-issuer/api/v1alpha1"
+)
+
+var _ = Describe("DigicloudIssuer webhooks", func() {
+	var issuer *v1alpha1.DigicloudIssuer
+
+	BeforeEach(func() {
+		issuer = &v1alpha1.DigicloudIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "webhook-issuer-",
+				Namespace:    "default",
+			},
+			Spec: v1alpha1.DigicloudIssuerSpec{
+				Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+					APITokenSecretRef: v1alpha1.SecretKeySelector{
+						Name: "api-key-secret",
+						Key:  "api-key",
+					},
+				},
+			},
+		}
+	})
+
+	It("Should default the provisioner", func() {
+		Expect(k8sClient.Create(ctx, issuer)).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, issuer)
+
+		created := &v1alpha1.DigicloudIssuer{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(issuer), created)).Should(Succeed())
+		Expect(created.Spec.Provisioner.APIBaseURL).To(Equal("https://api.digicloud.ir"))
+		Expect(created.Spec.Provisioner.TTL).To(HaveValue(Equal(300)))
+		Expect(created.Spec.Provisioner.PropagationTimeout.Duration).To(Equal(5 * time.Minute))
+		Expect(created.Spec.Provisioner.PollingInterval.Duration).To(Equal(10 * time.Second))
+	})
+
+	It("Should reject a malformed API URL", func() {
+		issuer.Spec.Provisioner.APIBaseURL = "api.digicloud.ir"
+
+		err := k8sClient.Create(ctx, issuer)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.provisioner.apiBaseUrl"))
+	})
+
+	It("Should reject a polling interval that is not less than the propagation timeout", func() {
+		issuer.Spec.Provisioner.PropagationTimeout = &metav1.Duration{Duration: 30 * time.Second}
+		issuer.Spec.Provisioner.PollingInterval = &metav1.Duration{Duration: time.Minute}
+
+		err := k8sClient.Create(ctx, issuer)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.provisioner.pollingInterval"))
+	})
+
+	It("Should reject an update that empties the secret key", func() {
+		Expect(k8sClient.Create(ctx, issuer)).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, issuer)
+
+		issuer.Spec.Provisioner.APITokenSecretRef.Key = ""
+		err := k8sClient.Update(ctx, issuer)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.provisioner.apiTokenSecretRef.key"))
+	})
+})
+
+var _ = Describe("DigicloudClusterIssuer webhooks", func() {
+	var clusterIssuer *v1alpha1.DigicloudClusterIssuer
+
+	BeforeEach(func() {
+		clusterIssuer = &v1alpha1.DigicloudClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "webhook-cluster-issuer-",
+				Namespace:    "default",
+			},
+			Spec: v1alpha1.DigicloudClusterIssuerSpec{
+				Provisioner: v1alpha1.DigicloudIssuerProvisioner{
+					APITokenSecretRef: v1alpha1.SecretKeySelector{
+						Name: "api-key-secret",
+						Key:  "api-key",
+					},
+				},
+			},
+		}
+	})
+
+	It("Should default the provisioner", func() {
+		Expect(k8sClient.Create(ctx, clusterIssuer)).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, clusterIssuer)
+
+		created := &v1alpha1.DigicloudClusterIssuer{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterIssuer), created)).Should(Succeed())
+		Expect(created.Spec.Provisioner.APIBaseURL).To(Equal("https://api.digicloud.ir"))
+		Expect(created.Spec.Provisioner.TTL).To(HaveValue(Equal(300)))
+	})
+
+	It("Should reject an invalid namespace selector", func() {
+		clusterIssuer.Spec.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "team", Operator: metav1.LabelSelectorOpExists, Values: []string{"platform"}},
+			},
+		}
+
+		err := k8sClient.Create(ctx, clusterIssuer)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+		Expect(err.Error()).To(ContainSubstring("spec.namespaceSelector"))
+	})
+
+	It("Should reject a solver without a secret reference", func() {
+		clusterIssuer.Spec.Provisioner.Solvers = []v1alpha1.DigicloudSolver{
+			{Selector: &v1alpha1.SolverSelector{DNSZones: []string{"example.com"}}},
+		}
+
+		err := k8sClient.Create(ctx, clusterIssuer)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+	})
+})